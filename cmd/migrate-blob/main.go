@@ -0,0 +1,56 @@
+// Command migrate-blob move image bytes stored inline in the database to
+// the configured blob storage, then compute the perceptual hash of stored
+// images missing one.
+package main
+
+import (
+	"context"
+	"flag"
+	"flukis/product/config"
+	"flukis/product/internals/image"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	batch := flag.Int("batch", 100, "number of rows moved per query")
+	flag.Parse()
+	if *batch <= 0 {
+		log.Fatal().Int("batch", *batch).Msg("batch must be positive")
+	}
+
+	// load config
+	if err := godotenv.Load(); err != nil {
+		log.Warn().Err(err).Msg("failed to load file .env")
+	}
+	cfg := config.LoadConfig("config.yml")
+
+	// database
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.DBConfig.ConnString())
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to connect to database")
+	}
+	defer pool.Close()
+
+	// blob storage
+	blobStorage, err := cfg.Storage.Open()
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to open blob storage")
+	}
+	imageBlobs := image.NewBlobStore(blobStorage, cfg.Image.Renditions())
+
+	moved, err := image.MigrateBlobs(ctx, pool, imageBlobs, *batch)
+	if err != nil {
+		log.Fatal().Err(err).Int("moved", moved).Msg("failed to migrate blob")
+	}
+	log.Info().Int("moved", moved).Msg("blob migration done")
+
+	hashed, err := image.BackfillPerceptualHash(ctx, pool, imageBlobs, *batch)
+	if err != nil {
+		log.Fatal().Err(err).Int("hashed", hashed).Msg("failed to compute perceptual hash")
+	}
+	log.Info().Int("hashed", hashed).Msg("perceptual hash backfill done")
+}
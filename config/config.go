@@ -33,21 +33,49 @@ func loadEnvUint(key string, res *uint) {
 	*res = uint(num)
 }
 
+// read float from env file
+func loadEnvFloat(key string, res *float64) {
+	s, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+
+	num, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return
+	}
+	*res = num
+}
+
 type Config struct {
-	Listen   listenConfig `yaml:"listen" json:"listen"`
-	DBConfig pgConfig     `yaml:"db" json:"db"`
+	Listen   listenConfig  `yaml:"listen" json:"listen"`
+	DBConfig pgConfig      `yaml:"db" json:"db"`
+	Storage  storageConfig `yaml:"storage" json:"storage"`
+	Image    imageConfig   `yaml:"image" json:"image"`
+	Search   searchConfig  `yaml:"search" json:"search"`
+
+	Pagination paginationConfig `yaml:"pagination" json:"pagination"`
 }
 
 func defaultConfig() Config {
 	return Config{
 		Listen:   defaultListenConfig(),
 		DBConfig: defaultPgConfig(),
+		Storage:  defaultStorageConfig(),
+		Image:    defaultImageConfig(),
+		Search:   defaultSearchConfig(),
+
+		Pagination: defaultPaginationConfig(),
 	}
 }
 
 func (c *Config) loadFromEnv() {
 	c.Listen.loadFromEnv()
 	c.DBConfig.loadFromEnv()
+	c.Storage.loadFromEnv()
+	c.Image.loadFromEnv()
+	c.Search.loadFromEnv()
+	c.Pagination.loadFromEnv()
 }
 
 func loadConfigFromReader(r io.Reader, c *Config) error {
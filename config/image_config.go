@@ -0,0 +1,98 @@
+package config
+
+import (
+	"flukis/product/utils/helper"
+	"strings"
+)
+
+type imageConfig struct {
+	PreviewWidth    uint `yaml:"preview_width" json:"preview_width"`
+	PreviewHeight   uint `yaml:"preview_height" json:"preview_height"`
+	ThumbnailWidth  uint `yaml:"thumbnail_width" json:"thumbnail_width"`
+	ThumbnailHeight uint `yaml:"thumbnail_height" json:"thumbnail_height"`
+	LargeWidth      uint `yaml:"large_width" json:"large_width"`
+	LargeHeight     uint `yaml:"large_height" json:"large_height"`
+
+	MaxSize        uint    `yaml:"max_size" json:"max_size"`
+	MaxPixels      uint    `yaml:"max_pixels" json:"max_pixels"`
+	Formats        string  `yaml:"formats" json:"formats"`
+	MinAspectRatio float64 `yaml:"min_aspect_ratio" json:"min_aspect_ratio"`
+	MaxAspectRatio float64 `yaml:"max_aspect_ratio" json:"max_aspect_ratio"`
+
+	DuplicateDistance uint `yaml:"duplicate_distance" json:"duplicate_distance"`
+}
+
+// Rules return the validation rules of uploaded image. Formats is a comma
+// separated list of jpeg, png, gif and webp.
+func (i imageConfig) Rules() helper.ImageRules {
+	var formats []string
+	for _, f := range strings.Split(i.Formats, ",") {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			formats = append(formats, f)
+		}
+	}
+	return helper.ImageRules{
+		MaxBytes:       int64(i.MaxSize),
+		MaxPixels:      int64(i.MaxPixels),
+		Formats:        formats,
+		MinAspectRatio: i.MinAspectRatio,
+		MaxAspectRatio: i.MaxAspectRatio,
+	}
+}
+
+// Renditions return the sizes generated for every uploaded image. Preview
+// and thumbnail are cropped to the exact size, large only fit in its box.
+func (i imageConfig) Renditions() []helper.Rendition {
+	return []helper.Rendition{
+		{
+			Name:   helper.RenditionPreview,
+			Width:  int(i.PreviewWidth),
+			Height: int(i.PreviewHeight),
+			Crop:   true,
+		},
+		{
+			Name:   helper.RenditionThumbnail,
+			Width:  int(i.ThumbnailWidth),
+			Height: int(i.ThumbnailHeight),
+			Crop:   true,
+		},
+		{
+			Name:   helper.RenditionLarge,
+			Width:  int(i.LargeWidth),
+			Height: int(i.LargeHeight),
+		},
+	}
+}
+
+func defaultImageConfig() imageConfig {
+	return imageConfig{
+		PreviewWidth:    600,
+		PreviewHeight:   400,
+		ThumbnailWidth:  150,
+		ThumbnailHeight: 100,
+		LargeWidth:      1200,
+		LargeHeight:     800,
+		MaxSize:         10 << 20,
+		MaxPixels:       40_000_000,
+		Formats:         "jpeg,png,gif,webp",
+		MinAspectRatio:  0.2,
+		MaxAspectRatio:  5,
+
+		DuplicateDistance: 6,
+	}
+}
+
+func (i *imageConfig) loadFromEnv() {
+	loadEnvUint("IMAGE_PREVIEW_WIDTH", &i.PreviewWidth)
+	loadEnvUint("IMAGE_PREVIEW_HEIGHT", &i.PreviewHeight)
+	loadEnvUint("IMAGE_THUMBNAIL_WIDTH", &i.ThumbnailWidth)
+	loadEnvUint("IMAGE_THUMBNAIL_HEIGHT", &i.ThumbnailHeight)
+	loadEnvUint("IMAGE_LARGE_WIDTH", &i.LargeWidth)
+	loadEnvUint("IMAGE_LARGE_HEIGHT", &i.LargeHeight)
+	loadEnvUint("IMAGE_MAX_SIZE", &i.MaxSize)
+	loadEnvUint("IMAGE_MAX_PIXELS", &i.MaxPixels)
+	loadEnvString("IMAGE_FORMATS", &i.Formats)
+	loadEnvFloat("IMAGE_MIN_ASPECT_RATIO", &i.MinAspectRatio)
+	loadEnvFloat("IMAGE_MAX_ASPECT_RATIO", &i.MaxAspectRatio)
+	loadEnvUint("IMAGE_DUPLICATE_DISTANCE", &i.DuplicateDistance)
+}
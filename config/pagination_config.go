@@ -0,0 +1,31 @@
+package config
+
+import (
+	"crypto/rand"
+)
+
+type paginationConfig struct {
+	CursorSecret string `yaml:"cursor_secret" json:"cursor_secret"`
+}
+
+// CursorKey return the HMAC key signing listing cursors. Without
+// CursorSecret a random key is made, cursors then stop working on restart
+// and are not shared between instances.
+func (p paginationConfig) CursorKey() []byte {
+	if p.CursorSecret != "" {
+		return []byte(p.CursorSecret)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func defaultPaginationConfig() paginationConfig {
+	return paginationConfig{}
+}
+
+func (p *paginationConfig) loadFromEnv() {
+	loadEnvString("CURSOR_SECRET", &p.CursorSecret)
+}
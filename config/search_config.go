@@ -0,0 +1,79 @@
+package config
+
+import (
+	"flukis/product/domain"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type searchConfig struct {
+	Backend string `yaml:"backend" json:"backend"`
+
+	PriceBuckets    string `yaml:"price_buckets" json:"price_buckets"`
+	FacetValueLimit uint   `yaml:"facet_value_limit" json:"facet_value_limit"`
+
+	SuggestTimeout   uint    `yaml:"suggest_timeout" json:"suggest_timeout"`
+	SuggestThreshold float64 `yaml:"suggest_threshold" json:"suggest_threshold"`
+}
+
+// PriceBounds return the ascending limits between price facet buckets.
+// PriceBuckets is a comma separated list of prices, invalid entry is
+// skipped.
+func (s searchConfig) PriceBounds() []float64 {
+	var bounds []float64
+	for _, raw := range strings.Split(s.PriceBuckets, ",") {
+		num, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			continue
+		}
+		bounds = append(bounds, num)
+	}
+	sort.Float64s(bounds)
+	res := bounds[:0]
+	for i := range bounds {
+		if i == 0 || bounds[i] != bounds[i-1] {
+			res = append(res, bounds[i])
+		}
+	}
+	return res
+}
+
+// FacetScope return the facet settings shared by search and listing, the
+// caller set the query and whether variants are counted.
+func (s searchConfig) FacetScope() domain.FacetScope {
+	return domain.FacetScope{
+		PriceBounds: s.PriceBounds(),
+		ValueLimit:  int(s.FacetValueLimit),
+	}
+}
+
+// SuggestOptions return the typeahead budget, SuggestTimeout is in
+// milliseconds.
+func (s searchConfig) SuggestOptions() domain.SuggestOptions {
+	return domain.SuggestOptions{
+		Timeout:   time.Duration(s.SuggestTimeout) * time.Millisecond,
+		Threshold: s.SuggestThreshold,
+	}
+}
+
+func defaultSearchConfig() searchConfig {
+	return searchConfig{
+		Backend: "postgres",
+
+		PriceBuckets:    "25,50,100,250,500,1000",
+		FacetValueLimit: 20,
+
+		SuggestTimeout:   200,
+		SuggestThreshold: 0.3,
+	}
+}
+
+func (s *searchConfig) loadFromEnv() {
+	loadEnvString("SEARCH_BACKEND", &s.Backend)
+	loadEnvString("SEARCH_PRICE_BUCKETS", &s.PriceBuckets)
+	loadEnvUint("SEARCH_FACET_VALUE_LIMIT", &s.FacetValueLimit)
+	loadEnvUint("SEARCH_SUGGEST_TIMEOUT", &s.SuggestTimeout)
+	loadEnvFloat("SEARCH_SUGGEST_THRESHOLD", &s.SuggestThreshold)
+}
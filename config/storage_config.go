@@ -0,0 +1,47 @@
+package config
+
+import (
+	"flukis/product/utils/storage"
+	"fmt"
+)
+
+type storageConfig struct {
+	Driver string `yaml:"driver" json:"driver"`
+
+	LocalDir string `yaml:"local_dir" json:"local_dir"`
+
+	S3Endpoint  string `yaml:"s3_endpoint" json:"s3_endpoint"`
+	S3Region    string `yaml:"s3_region" json:"s3_region"`
+	S3Bucket    string `yaml:"s3_bucket" json:"s3_bucket"`
+	S3AccessKey string `yaml:"s3_access_key" json:"s3_access_key"`
+	S3SecretKey string `yaml:"s3_secret_key" json:"s3_secret_key"`
+}
+
+// Open return the storage backend selected by driver, "local" or "s3".
+func (s storageConfig) Open() (storage.Storage, error) {
+	switch s.Driver {
+	case "", "local":
+		return storage.NewLocal(s.LocalDir)
+	case "s3":
+		return storage.NewS3(s.S3Endpoint, s.S3Region, s.S3Bucket, s.S3AccessKey, s.S3SecretKey)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", s.Driver)
+}
+
+func defaultStorageConfig() storageConfig {
+	return storageConfig{
+		Driver:   "local",
+		LocalDir: "data/blob",
+		S3Region: "us-east-1",
+	}
+}
+
+func (s *storageConfig) loadFromEnv() {
+	loadEnvString("STORAGE_DRIVER", &s.Driver)
+	loadEnvString("STORAGE_LOCAL_DIR", &s.LocalDir)
+	loadEnvString("S3_ENDPOINT", &s.S3Endpoint)
+	loadEnvString("S3_REGION", &s.S3Region)
+	loadEnvString("S3_BUCKET", &s.S3Bucket)
+	loadEnvString("S3_ACCESS_KEY", &s.S3AccessKey)
+	loadEnvString("S3_SECRET_KEY", &s.S3SecretKey)
+}
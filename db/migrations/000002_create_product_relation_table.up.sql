@@ -0,0 +1,13 @@
+CREATE TABLE Product_Relation (
+    product_relation_id BYTEA PRIMARY KEY,
+    product_id BYTEA REFERENCES Product(product_id),
+    related_product_id BYTEA REFERENCES Product(product_id),
+    relation_type VARCHAR(32) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    bidirectional BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX product_relation_product_idx ON Product_Relation (product_id, relation_type, position);
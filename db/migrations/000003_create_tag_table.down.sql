@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS Variant_Tag;
+DROP TABLE IF EXISTS Product_Tag;
+DROP TABLE IF EXISTS Tag;
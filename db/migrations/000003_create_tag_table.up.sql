@@ -0,0 +1,26 @@
+CREATE TABLE Tag (
+    tag_id BYTEA PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE Product_Tag (
+    product_id BYTEA REFERENCES Product(product_id),
+    tag_id BYTEA REFERENCES Tag(tag_id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    PRIMARY KEY (product_id, tag_id)
+);
+
+CREATE TABLE Variant_Tag (
+    variant_id BYTEA REFERENCES Variant(variant_id),
+    tag_id BYTEA REFERENCES Tag(tag_id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    PRIMARY KEY (variant_id, tag_id)
+);
+
+CREATE INDEX product_tag_tag_idx ON Product_Tag (tag_id);
+CREATE INDEX variant_tag_tag_idx ON Variant_Tag (tag_id);
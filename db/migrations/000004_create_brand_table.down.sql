@@ -0,0 +1,2 @@
+ALTER TABLE Product DROP COLUMN IF EXISTS brand_id;
+DROP TABLE IF EXISTS Brand;
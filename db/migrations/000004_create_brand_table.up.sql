@@ -0,0 +1,12 @@
+CREATE TABLE Brand (
+    brand_id BYTEA PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+ALTER TABLE Product ADD COLUMN brand_id BYTEA REFERENCES Brand(brand_id);
+
+CREATE INDEX product_brand_idx ON Product (brand_id, created_at);
@@ -0,0 +1,17 @@
+ALTER TABLE Variant
+    DROP COLUMN IF EXISTS weight,
+    DROP COLUMN IF EXISTS weight_unit,
+    DROP COLUMN IF EXISTS length,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS dimension_unit,
+    DROP COLUMN IF EXISTS shipping_class;
+
+ALTER TABLE Product
+    DROP COLUMN IF EXISTS weight,
+    DROP COLUMN IF EXISTS weight_unit,
+    DROP COLUMN IF EXISTS length,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS dimension_unit,
+    DROP COLUMN IF EXISTS shipping_class;
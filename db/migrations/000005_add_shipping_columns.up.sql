@@ -0,0 +1,17 @@
+ALTER TABLE Product
+    ADD COLUMN weight DECIMAL(10, 3),
+    ADD COLUMN weight_unit VARCHAR(8) NOT NULL DEFAULT 'g',
+    ADD COLUMN length DECIMAL(10, 2),
+    ADD COLUMN width DECIMAL(10, 2),
+    ADD COLUMN height DECIMAL(10, 2),
+    ADD COLUMN dimension_unit VARCHAR(8) NOT NULL DEFAULT 'cm',
+    ADD COLUMN shipping_class VARCHAR(64);
+
+ALTER TABLE Variant
+    ADD COLUMN weight DECIMAL(10, 3),
+    ADD COLUMN weight_unit VARCHAR(8) NOT NULL DEFAULT 'g',
+    ADD COLUMN length DECIMAL(10, 2),
+    ADD COLUMN width DECIMAL(10, 2),
+    ADD COLUMN height DECIMAL(10, 2),
+    ADD COLUMN dimension_unit VARCHAR(8) NOT NULL DEFAULT 'cm',
+    ADD COLUMN shipping_class VARCHAR(64);
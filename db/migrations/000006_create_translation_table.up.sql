@@ -0,0 +1,12 @@
+CREATE TABLE Translation (
+    translation_id BYTEA PRIMARY KEY,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id BYTEA NOT NULL,
+    locale VARCHAR(35) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    UNIQUE (entity_type, entity_id, locale)
+);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS Variant_Attribute;
+DROP TABLE IF EXISTS Product_Attribute;
+DROP TABLE IF EXISTS Attribute_Value;
+
+ALTER TABLE Attribute
+    DROP COLUMN IF EXISTS data_type,
+    DROP COLUMN IF EXISTS unit,
+    DROP COLUMN IF EXISTS min_value,
+    DROP COLUMN IF EXISTS max_value,
+    DROP COLUMN IF EXISTS max_length;
@@ -0,0 +1,39 @@
+ALTER TABLE Attribute
+    ADD COLUMN data_type VARCHAR(16) NOT NULL DEFAULT 'text',
+    ADD COLUMN unit VARCHAR(32),
+    ADD COLUMN min_value DOUBLE PRECISION,
+    ADD COLUMN max_value DOUBLE PRECISION,
+    ADD COLUMN max_length INT;
+
+CREATE TABLE Attribute_Value (
+    attribute_value_id BYTEA PRIMARY KEY,
+    attribute_id BYTEA REFERENCES Attribute(attribute_id),
+    value VARCHAR(255) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    UNIQUE (attribute_id, value)
+);
+
+CREATE TABLE Product_Attribute (
+    product_id BYTEA REFERENCES Product(product_id),
+    attribute_id BYTEA REFERENCES Attribute(attribute_id),
+    value TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    PRIMARY KEY (product_id, attribute_id)
+);
+
+CREATE TABLE Variant_Attribute (
+    variant_id BYTEA REFERENCES Variant(variant_id),
+    attribute_id BYTEA REFERENCES Attribute(attribute_id),
+    value TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    PRIMARY KEY (variant_id, attribute_id)
+);
+
+CREATE INDEX product_attribute_value_idx ON Product_Attribute (attribute_id, value);
+CREATE INDEX variant_attribute_value_idx ON Variant_Attribute (attribute_id, value);
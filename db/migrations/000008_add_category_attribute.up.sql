@@ -0,0 +1,11 @@
+CREATE TABLE Category_Attribute (
+    category_attribute_id BYTEA PRIMARY KEY,
+    category_id BYTEA REFERENCES Category(category_id),
+    attribute_id BYTEA REFERENCES Attribute(attribute_id),
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    UNIQUE (category_id, attribute_id)
+);
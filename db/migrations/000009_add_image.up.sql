@@ -0,0 +1,15 @@
+CREATE TABLE Image (
+    image_id BYTEA PRIMARY KEY,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id BYTEA NOT NULL,
+    data BYTEA NOT NULL,
+    alt_text VARCHAR(255) NOT NULL DEFAULT '',
+    position INT NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX image_entity_idx ON Image (entity_type, entity_id, position);
+CREATE UNIQUE INDEX image_primary_idx ON Image (entity_type, entity_id) WHERE is_primary AND deleted_at IS NULL;
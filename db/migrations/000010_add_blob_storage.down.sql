@@ -0,0 +1,22 @@
+-- the inline bytes of migrated images are cleared, dropping the keys would
+-- lose them, so refuse while any image live only in blob storage
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM Image WHERE blob_key IS NOT NULL AND data IS NULL)
+        OR EXISTS (SELECT 1 FROM Product WHERE image_key IS NOT NULL AND image_preview IS NULL) THEN
+        RAISE EXCEPTION 'images are stored in blob storage only, move them back inline before rolling back';
+    END IF;
+END
+$$;
+
+ALTER TABLE Image
+    DROP COLUMN IF EXISTS blob_key,
+    DROP COLUMN IF EXISTS blob_size,
+    DROP COLUMN IF EXISTS content_type,
+    DROP COLUMN IF EXISTS hash;
+
+ALTER TABLE Product
+    DROP COLUMN IF EXISTS image_key,
+    DROP COLUMN IF EXISTS image_size,
+    DROP COLUMN IF EXISTS image_content_type,
+    DROP COLUMN IF EXISTS image_hash;
@@ -0,0 +1,12 @@
+ALTER TABLE Product
+    ADD COLUMN image_key VARCHAR(255),
+    ADD COLUMN image_size BIGINT,
+    ADD COLUMN image_content_type VARCHAR(255),
+    ADD COLUMN image_hash VARCHAR(64);
+
+ALTER TABLE Image
+    ALTER COLUMN data DROP NOT NULL,
+    ADD COLUMN blob_key VARCHAR(255),
+    ADD COLUMN blob_size BIGINT,
+    ADD COLUMN content_type VARCHAR(255),
+    ADD COLUMN hash VARCHAR(64);
@@ -0,0 +1,5 @@
+ALTER TABLE Image
+    DROP COLUMN IF EXISTS phash;
+
+ALTER TABLE Product
+    DROP COLUMN IF EXISTS image_phash;
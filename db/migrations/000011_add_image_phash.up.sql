@@ -0,0 +1,5 @@
+ALTER TABLE Product
+    ADD COLUMN image_phash BIGINT;
+
+ALTER TABLE Image
+    ADD COLUMN phash BIGINT;
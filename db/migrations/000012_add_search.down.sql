@@ -0,0 +1,24 @@
+DROP TRIGGER IF EXISTS category_search_update ON Category;
+DROP TRIGGER IF EXISTS product_category_search_update ON Product_Category;
+DROP TRIGGER IF EXISTS variant_search_update ON Variant;
+DROP TRIGGER IF EXISTS product_name_search_update ON Product;
+DROP TRIGGER IF EXISTS product_search_update ON Product;
+
+DROP FUNCTION IF EXISTS category_search_trigger();
+DROP FUNCTION IF EXISTS product_category_search_trigger();
+DROP FUNCTION IF EXISTS variant_search_trigger();
+DROP FUNCTION IF EXISTS product_name_search_trigger();
+DROP FUNCTION IF EXISTS product_search_trigger();
+DROP FUNCTION IF EXISTS refresh_product_search(BYTEA);
+DROP FUNCTION IF EXISTS variant_search_vector(BYTEA, TEXT, TEXT);
+DROP FUNCTION IF EXISTS product_search_vector(BYTEA, TEXT, TEXT);
+DROP FUNCTION IF EXISTS product_category_names(BYTEA);
+
+DROP INDEX IF EXISTS variant_search_idx;
+DROP INDEX IF EXISTS product_search_idx;
+
+ALTER TABLE Variant
+    DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE Product
+    DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,116 @@
+ALTER TABLE Product
+    ADD COLUMN search_vector TSVECTOR;
+
+ALTER TABLE Variant
+    ADD COLUMN search_vector TSVECTOR;
+
+-- names of the live categories of a product
+CREATE FUNCTION product_category_names(BYTEA) RETURNS TEXT AS $$
+    SELECT COALESCE(string_agg(c.name, ' '), '')
+    FROM Product_Category pc
+    JOIN Category c ON c.category_id = pc.category_id
+    WHERE pc.product_id = $1
+        AND pc.deleted_at IS NULL
+        AND c.deleted_at IS NULL
+$$ LANGUAGE SQL STABLE;
+
+-- product: name (A), category names (B), description (C)
+CREATE FUNCTION product_search_vector(BYTEA, TEXT, TEXT) RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('english', COALESCE($2, '')), 'A')
+        || setweight(to_tsvector('english', product_category_names($1)), 'B')
+        || setweight(to_tsvector('english', COALESCE($3, '')), 'C')
+$$ LANGUAGE SQL STABLE;
+
+-- variant: name (A), main product name (B), category names and description (C)
+CREATE FUNCTION variant_search_vector(BYTEA, TEXT, TEXT) RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('english', COALESCE($2, '')), 'A')
+        || setweight(to_tsvector('english', COALESCE((SELECT p.name FROM Product p WHERE p.product_id = $1), '')), 'B')
+        || setweight(to_tsvector('english', product_category_names($1)), 'C')
+        || setweight(to_tsvector('english', COALESCE($3, '')), 'C')
+$$ LANGUAGE SQL STABLE;
+
+-- rebuild the vector of a product and its variants
+CREATE FUNCTION refresh_product_search(BYTEA) RETURNS VOID AS $$
+    UPDATE Product
+    SET search_vector = product_search_vector(product_id, name, description)
+    WHERE product_id = $1;
+    UPDATE Variant
+    SET search_vector = variant_search_vector(main_product_id, name, description)
+    WHERE main_product_id = $1;
+$$ LANGUAGE SQL;
+
+CREATE FUNCTION product_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := product_search_vector(NEW.product_id, NEW.name, NEW.description);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION product_name_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE Variant
+    SET search_vector = variant_search_vector(main_product_id, name, description)
+    WHERE main_product_id = NEW.product_id;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION variant_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := variant_search_vector(NEW.main_product_id, NEW.name, NEW.description);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION product_category_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM refresh_product_search(NEW.product_id);
+        RETURN NULL;
+    END IF;
+    PERFORM refresh_product_search(OLD.product_id);
+    IF TG_OP = 'UPDATE' AND NEW.product_id IS DISTINCT FROM OLD.product_id THEN
+        PERFORM refresh_product_search(NEW.product_id);
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION category_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM refresh_product_search(pc.product_id)
+    FROM Product_Category pc
+    WHERE pc.category_id = NEW.category_id
+        AND pc.deleted_at IS NULL;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_search_update
+    BEFORE INSERT OR UPDATE OF name, description ON Product
+    FOR EACH ROW EXECUTE FUNCTION product_search_trigger();
+
+CREATE TRIGGER product_name_search_update
+    AFTER UPDATE OF name ON Product
+    FOR EACH ROW EXECUTE FUNCTION product_name_search_trigger();
+
+CREATE TRIGGER variant_search_update
+    BEFORE INSERT OR UPDATE OF name, description, main_product_id ON Variant
+    FOR EACH ROW EXECUTE FUNCTION variant_search_trigger();
+
+CREATE TRIGGER product_category_search_update
+    AFTER INSERT OR UPDATE OR DELETE ON Product_Category
+    FOR EACH ROW EXECUTE FUNCTION product_category_search_trigger();
+
+CREATE TRIGGER category_search_update
+    AFTER UPDATE OF name, deleted_at ON Category
+    FOR EACH ROW EXECUTE FUNCTION category_search_trigger();
+
+UPDATE Product
+SET search_vector = product_search_vector(product_id, name, description);
+
+UPDATE Variant
+SET search_vector = variant_search_vector(main_product_id, name, description);
+
+CREATE INDEX product_search_idx ON Product USING GIN (search_vector);
+CREATE INDEX variant_search_idx ON Variant USING GIN (search_vector);
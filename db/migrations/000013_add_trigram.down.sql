@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS variant_attribute_value_trgm_idx;
+DROP INDEX IF EXISTS product_attribute_value_trgm_idx;
+DROP INDEX IF EXISTS category_name_trgm_idx;
+DROP INDEX IF EXISTS product_name_trgm_idx;
+
+DROP EXTENSION IF EXISTS pg_trgm;
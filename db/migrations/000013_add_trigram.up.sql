@@ -0,0 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX product_name_trgm_idx ON Product USING GIN (lower(name) gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX category_name_trgm_idx ON Category USING GIN (lower(name) gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX product_attribute_value_trgm_idx ON Product_Attribute USING GIN (lower(value) gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX variant_attribute_value_trgm_idx ON Variant_Attribute USING GIN (lower(value) gin_trgm_ops) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+DROP FUNCTION IF EXISTS search_query(TEXT);
+DROP VIEW IF EXISTS Search_Synonym_Rewrite;
+DROP TABLE IF EXISTS Search_Rule;
+DROP TABLE IF EXISTS Search_Synonym;
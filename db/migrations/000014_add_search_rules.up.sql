@@ -0,0 +1,44 @@
+CREATE TABLE Search_Synonym (
+    synonym_id BYTEA PRIMARY KEY,
+    terms TEXT[] NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE Search_Rule (
+    rule_id BYTEA PRIMARY KEY,
+    query VARCHAR(255) NOT NULL,
+    product_id BYTEA NOT NULL REFERENCES Product(product_id),
+    action VARCHAR(16) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    UNIQUE (query, product_id)
+);
+
+-- every term of a synonym set is rewritten to the OR of all terms of the set
+CREATE VIEW Search_Synonym_Rewrite AS
+SELECT target, substitute
+FROM (
+    SELECT
+        phraseto_tsquery('english', t.term) AS target,
+        (
+            SELECT string_agg('(' || phraseto_tsquery('english', a.term)::text || ')', ' | ')::tsquery
+            FROM unnest(s.terms) AS a(term)
+            WHERE numnode(phraseto_tsquery('english', a.term)) > 0
+        ) AS substitute
+    FROM Search_Synonym s
+    CROSS JOIN unnest(s.terms) AS t(term)
+    WHERE s.deleted_at IS NULL
+) r
+WHERE numnode(target) > 0 AND substitute IS NOT NULL;
+
+-- search text to tsquery with synonyms expanded
+CREATE FUNCTION search_query(TEXT) RETURNS TSQUERY AS $$
+    SELECT ts_rewrite(
+        websearch_to_tsquery('english', $1),
+        'SELECT target, substitute FROM Search_Synonym_Rewrite'
+    )
+$$ LANGUAGE SQL STABLE;
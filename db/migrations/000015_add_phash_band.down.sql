@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS image_phash_band_idx;
+DROP INDEX IF EXISTS product_phash_band_idx;
+
+DROP FUNCTION IF EXISTS phash_bands(BIGINT);
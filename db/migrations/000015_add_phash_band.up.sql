@@ -0,0 +1,11 @@
+-- the 4 16 bit bands of a perceptual hash, the band number is kept in the
+-- high bits so a value only match the same band of another hash
+CREATE FUNCTION phash_bands(BIGINT) RETURNS INT[] AS $$
+    SELECT ARRAY(
+        SELECT (band << 16) | ((($1 >> (16 * band)) & 65535)::INT)
+        FROM generate_series(0, 3) AS band
+    )
+$$ LANGUAGE SQL IMMUTABLE STRICT;
+
+CREATE INDEX product_phash_band_idx ON Product USING GIN (phash_bands(image_phash)) WHERE deleted_at IS NULL;
+CREATE INDEX image_phash_band_idx ON Image USING GIN (phash_bands(phash)) WHERE deleted_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE Product_Relation DROP CONSTRAINT IF EXISTS product_relation_unique;
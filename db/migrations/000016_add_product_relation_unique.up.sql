@@ -0,0 +1,18 @@
+-- keep one row per relation, the live one first then the latest
+DELETE FROM Product_Relation
+WHERE product_relation_id IN (
+    SELECT product_relation_id
+    FROM (
+        SELECT
+            product_relation_id,
+            ROW_NUMBER() OVER (
+                PARTITION BY product_id, related_product_id, relation_type
+                ORDER BY deleted_at IS NULL DESC, created_at DESC, product_relation_id DESC
+            ) AS rank
+        FROM Product_Relation
+    ) r
+    WHERE r.rank > 1
+);
+
+ALTER TABLE Product_Relation
+    ADD CONSTRAINT product_relation_unique UNIQUE (product_id, related_product_id, relation_type);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS Phash_Band_Mask;
@@ -0,0 +1,12 @@
+-- every 16 bit mask with its number of set bits, the band probes of a
+-- duplicate lookup flip the bands of a hash by the masks of few bits
+CREATE TABLE Phash_Band_Mask (
+    mask INT PRIMARY KEY,
+    bits SMALLINT NOT NULL
+);
+
+INSERT INTO Phash_Band_Mask (mask, bits)
+SELECT mask, length(replace(mask::bit(16)::text, '0', ''))
+FROM generate_series(0, 65535) AS mask;
+
+CREATE INDEX phash_band_mask_bits_idx ON Phash_Band_Mask (bits, mask);
@@ -1,31 +1,242 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/oklog/ulid/v2"
 	"gopkg.in/guregu/null.v4"
 )
 
+type AttributeType string
+
+const (
+	AttributeText    AttributeType = "text"
+	AttributeEnum    AttributeType = "enum"
+	AttributeNumber  AttributeType = "number"
+	AttributeBoolean AttributeType = "boolean"
+)
+
+var (
+	ErrInvalidAttributeType  = errors.New("attribute type must be one of text, enum, number or boolean")
+	ErrInvalidAttribute      = errors.New("invalid attribute definition")
+	ErrInvalidAttributeValue = errors.New("invalid attribute value")
+	ErrAttributeInUse        = errors.New("attribute is used with a value the change does not allow")
+)
+
+func (t AttributeType) Valid() bool {
+	switch t {
+	case AttributeText, AttributeEnum, AttributeNumber, AttributeBoolean:
+		return true
+	}
+	return false
+}
+
 type Attribute struct {
 	AttributeID ulid.ULID
 	Name        string
+	Type        AttributeType
+	Unit        null.String
+	Min         null.Float
+	Max         null.Float
+	MaxLength   null.Int
+	Values      []AttributeValue
 	CreatedAt   time.Time
 	UpdatedAt   null.Time
 	DeletedAt   null.Time
 }
 
+// AttributeValue is one allowed value of enum attribute.
+type AttributeValue struct {
+	AttributeValueID ulid.ULID
+	AttributeID      ulid.ULID
+	Value            string
+	Position         int
+	CreatedAt        time.Time
+	DeletedAt        null.Time
+}
+
+// AssignedAttribute is an attribute with the value set on a product or
+// variant.
+type AssignedAttribute struct {
+	Attribute Attribute
+	Value     string
+}
+
 type AttributesDTO struct {
-	ID   ulid.ULID `json:"id"`
-	Name string    `json:"name"`
+	ID        ulid.ULID     `json:"id"`
+	Name      string        `json:"name"`
+	Type      AttributeType `json:"type,omitempty"`
+	Unit      *string       `json:"unit,omitempty"`
+	Min       *float64      `json:"min,omitempty"`
+	Max       *float64      `json:"max,omitempty"`
+	MaxLength *int64        `json:"max_length,omitempty"`
+	Values    []string      `json:"values,omitempty"`
+	Value     string        `json:"value,omitempty"`
 }
 
-func NewAttribute(name string) (Attribute, error) {
+func NewAttribute(name string, attrType AttributeType, unit null.String, min, max null.Float, maxLength null.Int) (Attribute, error) {
 	id := ulid.Make()
-	return Attribute{
+	attr := Attribute{
 		AttributeID: id,
 		Name:        strings.ToLower(name),
 		CreatedAt:   time.Now(),
+	}
+	if err := attr.SetType(attrType, unit, min, max, maxLength); err != nil {
+		return Attribute{}, err
+	}
+	return attr, nil
+}
+
+// SetType change the data type of attribute, option that do not belong
+// to the type is cleared.
+func (a *Attribute) SetType(attrType AttributeType, unit null.String, min, max null.Float, maxLength null.Int) error {
+	if attrType == "" {
+		attrType = AttributeText
+	}
+	if !attrType.Valid() {
+		return ErrInvalidAttributeType
+	}
+	a.Type = attrType
+	a.Unit = null.String{}
+	a.Min = null.Float{}
+	a.Max = null.Float{}
+	a.MaxLength = null.Int{}
+	switch attrType {
+	case AttributeNumber:
+		if min.Valid && max.Valid && min.Float64 > max.Float64 {
+			return fmt.Errorf("%w: min must not be greater than max", ErrInvalidAttribute)
+		}
+		if unit.Valid && strings.TrimSpace(unit.String) != "" {
+			a.Unit = null.StringFrom(strings.TrimSpace(unit.String))
+		}
+		a.Min = min
+		a.Max = max
+	case AttributeText:
+		if maxLength.Valid && maxLength.Int64 <= 0 {
+			return fmt.Errorf("%w: max length must be greater than zero", ErrInvalidAttribute)
+		}
+		a.MaxLength = maxLength
+	}
+	return nil
+}
+
+// NormalizeAttributeValue trim and lowercase value, so "Red", "red " and
+// "RED" are the same value.
+func NormalizeAttributeValue(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+func NewAttributeValue(attributeId ulid.ULID, value string, position int) (AttributeValue, error) {
+	value = NormalizeAttributeValue(value)
+	if value == "" {
+		return AttributeValue{}, fmt.Errorf("%w: enum value can not be empty", ErrInvalidAttribute)
+	}
+	id := ulid.Make()
+	return AttributeValue{
+		AttributeValueID: id,
+		AttributeID:      attributeId,
+		Value:            value,
+		Position:         position,
+		CreatedAt:        time.Now(),
 	}, nil
 }
+
+// NormalizeValue validate raw against the attribute type and return the
+// canonical value to store.
+func (a Attribute) NormalizeValue(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	switch a.Type {
+	case AttributeEnum:
+		value := NormalizeAttributeValue(raw)
+		for _, allowed := range a.Values {
+			if allowed.Value == value {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %q is not an allowed value of %s", ErrInvalidAttributeValue, raw, a.Name)
+	case AttributeNumber:
+		num, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(num) || math.IsInf(num, 0) {
+			return "", fmt.Errorf("%w: %s must be a number", ErrInvalidAttributeValue, a.Name)
+		}
+		if a.Min.Valid && num < a.Min.Float64 {
+			return "", fmt.Errorf("%w: %s must be at least %v", ErrInvalidAttributeValue, a.Name, a.Min.Float64)
+		}
+		if a.Max.Valid && num > a.Max.Float64 {
+			return "", fmt.Errorf("%w: %s must be at most %v", ErrInvalidAttributeValue, a.Name, a.Max.Float64)
+		}
+		return strconv.FormatFloat(num, 'f', -1, 64), nil
+	case AttributeBoolean:
+		switch strings.ToLower(raw) {
+		case "true", "1", "yes":
+			return "true", nil
+		case "false", "0", "no":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%w: %s must be true or false", ErrInvalidAttributeValue, a.Name)
+	default:
+		if raw == "" {
+			return "", fmt.Errorf("%w: %s can not be empty", ErrInvalidAttributeValue, a.Name)
+		}
+		if a.MaxLength.Valid && int64(utf8.RuneCountInString(raw)) > a.MaxLength.Int64 {
+			return "", fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidAttributeValue, a.Name, a.MaxLength.Int64)
+		}
+		return raw, nil
+	}
+}
+
+// CheckUsedValues validate the value products and variants already have
+// against the attribute, a change of type, bounds or allowed values must
+// keep every used value valid and in its normalized form.
+func (a Attribute) CheckUsedValues(used []string) error {
+	for _, value := range used {
+		normalized, err := a.NormalizeValue(value)
+		if err != nil || normalized != value {
+			return fmt.Errorf("%w: %q", ErrAttributeInUse, value)
+		}
+	}
+	return nil
+}
+
+func NewAttributeDTO(a Attribute) AttributesDTO {
+	values := make([]string, 0, len(a.Values))
+	for _, v := range a.Values {
+		values = append(values, v.Value)
+	}
+	return AttributesDTO{
+		ID:        a.AttributeID,
+		Name:      a.Name,
+		Type:      a.Type,
+		Unit:      a.Unit.Ptr(),
+		Min:       a.Min.Ptr(),
+		Max:       a.Max.Ptr(),
+		MaxLength: a.MaxLength.Ptr(),
+		Values:    values,
+	}
+}
+
+// AttributeInput is an attribute value to set on a product or variant.
+type AttributeInput struct {
+	ID    ulid.ULID `json:"id"`
+	Value string    `json:"value"`
+}
+
+func NewAssignedAttributesDTO(attrs []AssignedAttribute) []AttributesDTO {
+	res := make([]AttributesDTO, 0, len(attrs))
+	for _, a := range attrs {
+		res = append(res, AttributesDTO{
+			ID:    a.Attribute.AttributeID,
+			Name:  a.Attribute.Name,
+			Type:  a.Attribute.Type,
+			Unit:  a.Attribute.Unit.Ptr(),
+			Value: a.Value,
+		})
+	}
+	return res
+}
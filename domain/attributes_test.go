@@ -0,0 +1,115 @@
+package domain_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"testing"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestNormalizeValue(t *testing.T) {
+	size := domain.Attribute{
+		Name: "size",
+		Type: domain.AttributeEnum,
+		Values: []domain.AttributeValue{
+			{Value: "small"},
+			{Value: "large"},
+		},
+	}
+	weight := domain.Attribute{
+		Name: "weight",
+		Type: domain.AttributeNumber,
+		Min:  null.FloatFrom(0),
+		Max:  null.FloatFrom(100),
+	}
+	waterproof := domain.Attribute{
+		Name: "waterproof",
+		Type: domain.AttributeBoolean,
+	}
+	material := domain.Attribute{
+		Name:      "material",
+		Type:      domain.AttributeText,
+		MaxLength: null.IntFrom(5),
+	}
+
+	tests := []struct {
+		name    string
+		attr    domain.Attribute
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "enum normalized", attr: size, raw: " Small ", want: "small"},
+		{name: "enum not allowed", attr: size, raw: "medium", wantErr: true},
+		{name: "number canonical", attr: weight, raw: "12.50", want: "12.5"},
+		{name: "number at bound", attr: weight, raw: "100", want: "100"},
+		{name: "number below min", attr: weight, raw: "-1", wantErr: true},
+		{name: "number above max", attr: weight, raw: "100.1", wantErr: true},
+		{name: "number malformed", attr: weight, raw: "heavy", wantErr: true},
+		{name: "number NaN", attr: domain.Attribute{Type: domain.AttributeNumber}, raw: "NaN", wantErr: true},
+		{name: "number infinite", attr: domain.Attribute{Type: domain.AttributeNumber}, raw: "+Inf", wantErr: true},
+		{name: "boolean yes", attr: waterproof, raw: "Yes", want: "true"},
+		{name: "boolean zero", attr: waterproof, raw: "0", want: "false"},
+		{name: "boolean malformed", attr: waterproof, raw: "maybe", wantErr: true},
+		{name: "text trimmed", attr: material, raw: " wool ", want: "wool"},
+		{name: "text empty", attr: material, raw: "  ", wantErr: true},
+		{name: "text too long in characters", attr: material, raw: "cotton", wantErr: true},
+		{name: "text length counted in runes", attr: material, raw: "ééééé", want: "ééééé"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.attr.NormalizeValue(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidAttributeValue) {
+					t.Fatalf("err = %v, want %v", err, domain.ErrInvalidAttributeValue)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("value = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUsedValues(t *testing.T) {
+	size := domain.Attribute{
+		Name:   "size",
+		Type:   domain.AttributeEnum,
+		Values: []domain.AttributeValue{{Value: "small"}, {Value: "large"}},
+	}
+	tests := []struct {
+		name    string
+		attr    domain.Attribute
+		used    []string
+		wantErr bool
+	}{
+		{name: "nothing used", attr: domain.Attribute{Type: domain.AttributeNumber}},
+		{name: "text to number with numbers", attr: domain.Attribute{Type: domain.AttributeNumber}, used: []string{"1", "2.5"}},
+		{name: "text to number with a word", attr: domain.Attribute{Type: domain.AttributeNumber}, used: []string{"1", "red"}, wantErr: true},
+		{name: "number not in normalized form", attr: domain.Attribute{Type: domain.AttributeNumber}, used: []string{"2.50"}, wantErr: true},
+		{name: "new max below a used value", attr: domain.Attribute{Type: domain.AttributeNumber, Max: null.FloatFrom(10)}, used: []string{"12"}, wantErr: true},
+		{name: "enum value kept", attr: size, used: []string{"small"}},
+		{name: "enum value removed", attr: size, used: []string{"small", "medium"}, wantErr: true},
+		{name: "to boolean", attr: domain.Attribute{Type: domain.AttributeBoolean}, used: []string{"true", "yes"}, wantErr: true},
+		{name: "text shorter max length", attr: domain.Attribute{Type: domain.AttributeText, MaxLength: null.IntFrom(3)}, used: []string{"wool"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.attr.CheckUsedValues(tt.used)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrAttributeInUse) {
+					t.Fatalf("err = %v, want %v", err, domain.ErrAttributeInUse)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// Blob is the reference to a binary object kept in storage. Key is derived
+// from the content hash, so the same content is stored once. PHash is the
+// perceptual hash of an image blob.
+type Blob struct {
+	Key         string
+	Size        int64
+	ContentType string
+	Hash        string
+	PHash       null.Int
+}
+
+func NewBlob(prefix string, data []byte) Blob {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	return Blob{
+		Key:         prefix + "/" + hash[:2] + "/" + hash,
+		Size:        int64(len(data)),
+		ContentType: http.DetectContentType(data),
+		Hash:        hash,
+	}
+}
+
+// Empty report whether the blob reference is not set.
+func (b Blob) Empty() bool {
+	return b.Key == ""
+}
+
+// RenditionKey return the storage key of a resized copy of the blob.
+func (b Blob) RenditionKey(name string) string {
+	return b.Key + "_" + name
+}
+
+// WebPKey return the storage key of the WebP copy of a rendition.
+func (b Blob) WebPKey(name string) string {
+	return b.RenditionKey(name) + "_webp"
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+type Brand struct {
+	BrandID     ulid.ULID
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   null.Time
+	DeletedAt   null.Time
+}
+
+type BrandsDTO struct {
+	ID          ulid.ULID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"desc"`
+}
+
+func NewBrand(name, desc string) (Brand, error) {
+	id := ulid.Make()
+	return Brand{
+		BrandID:     id,
+		Name:        name,
+		Description: desc,
+		CreatedAt:   time.Now(),
+	}, nil
+}
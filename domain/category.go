@@ -17,9 +17,10 @@ type Category struct {
 }
 
 type CategoriesDTO struct {
-	ID          ulid.ULID `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"desc"`
+	ID          ulid.ULID              `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"desc"`
+	Attributes  []CategoryAttributeDTO `json:"attributes,omitempty"`
 }
 
 func NewCategory(name, desc string) (Category, error) {
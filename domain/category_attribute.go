@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+var (
+	ErrMissingAttribute    = errors.New("missing required attribute")
+	ErrAttributeNotAllowed = errors.New("attribute is not allowed in product category")
+)
+
+// CategoryAttribute is one attribute of a category attribute set. A
+// product in the category must have it when Required, otherwise may have
+// it.
+type CategoryAttribute struct {
+	CategoryAttributeID ulid.ULID
+	Category            Category
+	Attribute           Attribute
+	Required            bool
+	Position            int
+	CreatedAt           time.Time
+	UpdatedAt           null.Time
+	DeletedAt           null.Time
+}
+
+type CategoryAttributeDTO struct {
+	AttributesDTO
+	Required bool `json:"required"`
+	Position int  `json:"position"`
+}
+
+// CategoryAttributeInput is one entry of category attribute set
+// management request.
+type CategoryAttributeInput struct {
+	ID       ulid.ULID `json:"id"`
+	Required bool      `json:"required"`
+	Position int       `json:"position"`
+}
+
+func NewCategoryAttribute(categoryId, attributeId ulid.ULID, required bool, position int) (CategoryAttribute, error) {
+	id := ulid.Make()
+	return CategoryAttribute{
+		CategoryAttributeID: id,
+		Category: Category{
+			CategoryID: categoryId,
+		},
+		Attribute: Attribute{
+			AttributeID: attributeId,
+		},
+		Required:  required,
+		Position:  position,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func NewCategoryAttributeDTO(ca CategoryAttribute) CategoryAttributeDTO {
+	return CategoryAttributeDTO{
+		AttributesDTO: NewAttributeDTO(ca.Attribute),
+		Required:      ca.Required,
+		Position:      ca.Position,
+	}
+}
+
+// CheckAttributeSet validate the assigned attribute against the attribute
+// set of every product category. Category without attribute set allow any
+// attribute, so a product without such set is never rejected.
+func CheckAttributeSet(set []CategoryAttribute, assigned []AssignedAttribute) error {
+	if len(set) == 0 {
+		return nil
+	}
+	has := make(map[ulid.ULID]bool, len(assigned))
+	for _, a := range assigned {
+		has[a.Attribute.AttributeID] = true
+	}
+	allowed := make(map[ulid.ULID]bool, len(set))
+	missing := make(map[string]bool)
+	for _, ca := range set {
+		allowed[ca.Attribute.AttributeID] = true
+		if ca.Required && !has[ca.Attribute.AttributeID] {
+			missing[ca.Attribute.Name] = true
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingAttribute, joinNames(missing))
+	}
+	notAllowed := make(map[string]bool)
+	for _, a := range assigned {
+		if !allowed[a.Attribute.AttributeID] {
+			notAllowed[a.Attribute.Name] = true
+		}
+	}
+	if len(notAllowed) > 0 {
+		return fmt.Errorf("%w: %s", ErrAttributeNotAllowed, joinNames(notAllowed))
+	}
+	return nil
+}
+
+func joinNames(names map[string]bool) string {
+	res := make([]string, 0, len(names))
+	for name := range names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return strings.Join(res, ", ")
+}
@@ -0,0 +1,71 @@
+package domain_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestCheckAttributeSet(t *testing.T) {
+	attr := func(id, name string) domain.Attribute {
+		return domain.Attribute{AttributeID: ulid.MustParse(id), Name: name}
+	}
+	color := attr("01J0000000000000000000000A", "color")
+	size := attr("01J0000000000000000000000B", "size")
+	fabric := attr("01J0000000000000000000000C", "fabric")
+	shirt := domain.Category{CategoryID: ulid.MustParse("01J0000000000000000000000D")}
+	sale := domain.Category{CategoryID: ulid.MustParse("01J0000000000000000000000E")}
+
+	shirtSet := []domain.CategoryAttribute{
+		{Category: shirt, Attribute: color, Required: true},
+		{Category: shirt, Attribute: size},
+	}
+	assigned := func(attrs ...domain.Attribute) []domain.AssignedAttribute {
+		res := make([]domain.AssignedAttribute, len(attrs))
+		for i := range attrs {
+			res[i] = domain.AssignedAttribute{Attribute: attrs[i], Value: "x"}
+		}
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		set      []domain.CategoryAttribute
+		assigned []domain.AssignedAttribute
+		wantErr  error
+	}{
+		{name: "no set allow anything", set: nil, assigned: assigned(fabric)},
+		{name: "required only", set: shirtSet, assigned: assigned(color)},
+		{name: "required and optional", set: shirtSet, assigned: assigned(color, size)},
+		{name: "required missing", set: shirtSet, assigned: assigned(size), wantErr: domain.ErrMissingAttribute},
+		{name: "nothing assigned", set: shirtSet, assigned: nil, wantErr: domain.ErrMissingAttribute},
+		{name: "outside the set", set: shirtSet, assigned: assigned(color, fabric), wantErr: domain.ErrAttributeNotAllowed},
+		{
+			name:     "sets of every category are merged",
+			set:      append([]domain.CategoryAttribute{{Category: sale, Attribute: fabric}}, shirtSet...),
+			assigned: assigned(color, fabric),
+		},
+		{
+			name:     "required by any category",
+			set:      append([]domain.CategoryAttribute{{Category: sale, Attribute: fabric, Required: true}}, shirtSet...),
+			assigned: assigned(color),
+			wantErr:  domain.ErrMissingAttribute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := domain.CheckAttributeSet(tt.set, tt.assigned)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+		})
+	}
+}
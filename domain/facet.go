@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+// DefaultFacetValueLimit is how many values of one attribute are counted,
+// most frequent first.
+const DefaultFacetValueLimit = 20
+
+// FacetScope tell what facets are counted over. Query is the search text,
+// empty when counting a listing. Variants are counted next to products
+// only when Variants is set. PriceBounds are the ascending limits between
+// price buckets.
+type FacetScope struct {
+	Query       string
+	Variants    bool
+	PriceBounds []float64
+	ValueLimit  int
+}
+
+type CategoryFacet struct {
+	ID       ulid.ULID `json:"id"`
+	Name     string    `json:"name"`
+	Count    int       `json:"count"`
+	Selected bool      `json:"selected"`
+}
+
+type BrandFacet struct {
+	ID       ulid.ULID `json:"id"`
+	Name     string    `json:"name"`
+	Count    int       `json:"count"`
+	Selected bool      `json:"selected"`
+}
+
+type AttributeValueFacet struct {
+	Value    string `json:"value"`
+	Count    int    `json:"count"`
+	Selected bool   `json:"selected"`
+}
+
+type AttributeFacet struct {
+	ID     ulid.ULID             `json:"id"`
+	Name   string                `json:"name"`
+	Values []AttributeValueFacet `json:"values"`
+}
+
+// PriceFacet count items with price in [Min, Max), unset Min or Max is an
+// open end.
+type PriceFacet struct {
+	Min   null.Float `json:"min"`
+	Max   null.Float `json:"max"`
+	Count int        `json:"count"`
+}
+
+// Facets count the items of each filter value. Every facet is counted
+// against all the other active filters but not its own, so selecting
+// "Red" still show how many "Blue" items there are.
+type Facets struct {
+	Categories []CategoryFacet  `json:"categories"`
+	Brands     []BrandFacet     `json:"brands"`
+	Attributes []AttributeFacet `json:"attributes"`
+	Prices     []PriceFacet     `json:"prices"`
+}
+
+// NewPriceFacet return the bucket of the given width_bucket number, bucket
+// 0 is below the first bound and the last one is above the last bound.
+func NewPriceFacet(bounds []float64, bucket, count int) PriceFacet {
+	res := PriceFacet{
+		Count: count,
+	}
+	if bucket > 0 && bucket <= len(bounds) {
+		res.Min = null.FloatFrom(bounds[bucket-1])
+	}
+	if bucket < len(bounds) {
+		res.Max = null.FloatFrom(bounds[bucket])
+	}
+	return res
+}
+
+// WithoutFacets return filter without its facet filters, price, category,
+// brand and attribute, the ones counted by the facets.
+func (f ListFilter) WithoutFacets() ListFilter {
+	f.MinPrice = null.Float{}
+	f.MaxPrice = null.Float{}
+	f.CategoryIDs = nil
+	f.BrandIDs = nil
+	f.Attributes = nil
+	return f
+}
+
+// MarkSelected flag the facet values that are part of filter.
+func (f *Facets) MarkSelected(filter ListFilter) {
+	for i := range f.Categories {
+		f.Categories[i].Selected = containsID(filter.CategoryIDs, f.Categories[i].ID)
+	}
+	for i := range f.Brands {
+		f.Brands[i].Selected = containsID(filter.BrandIDs, f.Brands[i].ID)
+	}
+	for i := range f.Attributes {
+		for _, attr := range filter.Attributes {
+			if attr.ID != f.Attributes[i].ID {
+				continue
+			}
+			for j := range f.Attributes[i].Values {
+				for _, value := range attr.Values {
+					if value == f.Attributes[i].Values[j].Value {
+						f.Attributes[i].Values[j].Selected = true
+					}
+				}
+			}
+		}
+	}
+}
+
+func containsID(ids []ulid.ULID, id ulid.ULID) bool {
+	for i := range ids {
+		if ids[i] == id {
+			return true
+		}
+	}
+	return false
+}
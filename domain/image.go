@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+var (
+	ErrInvalidImageOwner = errors.New("image owner must be one of product or variant")
+	ErrInvalidImage      = errors.New("invalid image")
+	ErrImageNotFound     = errors.New("image not found")
+	ErrInvalidRendition  = errors.New("unknown image rendition")
+)
+
+const maxAltTextLength = 255
+
+// Image is one photo of a product or variant gallery, ordered by position.
+// Only one image of an owner is primary.
+type Image struct {
+	ImageID    ulid.ULID
+	EntityType EntityType
+	EntityID   ulid.ULID
+	Data       []byte
+	Blob       Blob
+	AltText    string
+	Position   int
+	IsPrimary  bool
+	CreatedAt  time.Time
+	UpdatedAt  null.Time
+	DeletedAt  null.Time
+}
+
+type ImageDTO struct {
+	ID       ulid.ULID `json:"id"`
+	Image    string    `json:"image"`
+	AltText  string    `json:"alt"`
+	Position int       `json:"position"`
+	Primary  bool      `json:"primary"`
+}
+
+// ImageContent is the bytes of one image rendition ready to be served.
+type ImageContent struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+}
+
+// ImageInput is one entry of image reorder request.
+type ImageInput struct {
+	ID       ulid.ULID   `json:"id"`
+	Position int         `json:"position"`
+	AltText  null.String `json:"alt"`
+	Primary  bool        `json:"primary"`
+}
+
+// ValidImageOwner report whether entity of the type can have images.
+func ValidImageOwner(t EntityType) bool {
+	return t == EntityProduct || t == EntityVariant
+}
+
+func NewImage(entityType EntityType, entityId ulid.ULID, data []byte, alt string, position int, primary bool) (Image, error) {
+	if !ValidImageOwner(entityType) {
+		return Image{}, ErrInvalidImageOwner
+	}
+	if len(data) == 0 {
+		return Image{}, fmt.Errorf("%w: image can not be empty", ErrInvalidImage)
+	}
+	alt, err := NormalizeAltText(alt)
+	if err != nil {
+		return Image{}, err
+	}
+	id := ulid.Make()
+	return Image{
+		ImageID:    id,
+		EntityType: entityType,
+		EntityID:   entityId,
+		Data:       data,
+		AltText:    alt,
+		Position:   position,
+		IsPrimary:  primary,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+func NormalizeAltText(alt string) (string, error) {
+	alt = strings.TrimSpace(alt)
+	if len([]rune(alt)) > maxAltTextLength {
+		return "", fmt.Errorf("%w: alt text must be at most %d characters", ErrInvalidImage, maxAltTextLength)
+	}
+	return alt, nil
+}
+
+func NewImageDTO(img Image) ImageDTO {
+	return ImageDTO{
+		ID:       img.ImageID,
+		Image:    GalleryImageURL(img),
+		AltText:  img.AltText,
+		Position: img.Position,
+		Primary:  img.IsPrimary,
+	}
+}
+
+func NewImagesDTO(images []Image) []ImageDTO {
+	res := make([]ImageDTO, 0, len(images))
+	for idx := range images {
+		res = append(res, NewImageDTO(images[idx]))
+	}
+	return res
+}
+
+// PrimaryImage return the primary image of a gallery ordered by position,
+// or the first image when none is flagged.
+func PrimaryImage(images []Image) (Image, bool) {
+	if len(images) == 0 {
+		return Image{}, false
+	}
+	for idx := range images {
+		if images[idx].IsPrimary {
+			return images[idx], true
+		}
+	}
+	return images[0], true
+}
+
+// ProductImageURL return the path serving the image of a product.
+func ProductImageURL(id ulid.ULID) string {
+	return "/product/" + id.String() + "/image"
+}
+
+// VariantImageURL return the path serving the image of a variant.
+func VariantImageURL(id ulid.ULID) string {
+	return "/variant/" + id.String() + "/image"
+}
+
+// GalleryImageURL return the path serving one gallery image.
+func GalleryImageURL(img Image) string {
+	return "/image/" + string(img.EntityType) + "/" + img.EntityID.String() + "/" + img.ImageID.String()
+}
@@ -0,0 +1,534 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+type SortField string
+
+const (
+	SortCreated SortField = "created"
+	SortUpdated SortField = "updated"
+	SortPrice   SortField = "price"
+	SortName    SortField = "name"
+)
+
+var (
+	ErrInvalidSort   = errors.New("sort must be one of created, updated, price or name and order one of asc or desc")
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidPage   = errors.New("invalid page")
+)
+
+func (f SortField) Valid() bool {
+	switch f {
+	case SortCreated, SortUpdated, SortPrice, SortName:
+		return true
+	}
+	return false
+}
+
+// ListSort is the order of a listing, ties are broken by id in the same
+// direction so the order is total.
+type ListSort struct {
+	Field SortField
+	Desc  bool
+}
+
+// NewListSort build sort from "sort" and "order" query values, default to
+// oldest first.
+func NewListSort(field, order string) (ListSort, error) {
+	res := ListSort{
+		Field: SortCreated,
+	}
+	if field != "" {
+		res.Field = SortField(strings.ToLower(field))
+	}
+	if !res.Field.Valid() {
+		return ListSort{}, ErrInvalidSort
+	}
+	switch strings.ToLower(order) {
+	case "", "asc":
+	case "desc":
+		res.Desc = true
+	default:
+		return ListSort{}, ErrInvalidSort
+	}
+	return res, nil
+}
+
+func (s ListSort) String() string {
+	if s.Desc {
+		return string(s.Field) + ":desc"
+	}
+	return string(s.Field) + ":asc"
+}
+
+// SortValue return the value a listed item is ordered by, in its cursor
+// form.
+func (s ListSort) SortValue(name string, price float64, createdAt, updatedAt time.Time) string {
+	switch s.Field {
+	case SortPrice:
+		return strconv.FormatFloat(price, 'f', -1, 64)
+	case SortName:
+		return name
+	case SortUpdated:
+		return updatedAt.Format(time.RFC3339Nano)
+	}
+	return createdAt.Format(time.RFC3339Nano)
+}
+
+// CreatedSort is the order of listings without sort option, oldest first.
+var CreatedSort = ListSort{Field: SortCreated}
+
+// ListCursor point after the last item of a page, or before the first one
+// when Before is set. Sort and Scope, a digest of the filters, are kept so
+// the cursor can not be replayed with another order or filter, ID break the
+// tie of equal sort values.
+type ListCursor struct {
+	Sort   string    `json:"s"`
+	Value  string    `json:"v"`
+	ID     ulid.ULID `json:"id"`
+	Before bool      `json:"b,omitempty"`
+	Scope  string    `json:"f,omitempty"`
+}
+
+// NewCreatedCursor point at an item of a CreatedSort listing.
+func NewCreatedCursor(createdAt time.Time, id ulid.ULID) ListCursor {
+	return ListCursor{
+		Sort:  CreatedSort.String(),
+		Value: createdAt.Format(time.RFC3339Nano),
+		ID:    id,
+	}
+}
+
+// Page is the position of a listing page. HasMore tell whether items
+// follow the page, then NextCursor read them. PrevCursor read the items
+// preceding the page, it is empty on the first page.
+type Page struct {
+	NextCursor string
+	PrevCursor string
+	HasMore    bool
+	// Total is the size of the whole listing when it was asked, an
+	// estimate when TotalEstimated is set.
+	Total          null.Int
+	TotalEstimated bool
+}
+
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 100
+	// MaxPage keep the offset of the last page within an int32
+	MaxPage = math.MaxInt32 / MaxPerPage
+)
+
+// PageRequest is the page a listing is asked for. Cursor mode read Limit
+// items from Cursor. Offset mode, when Number is set, read the Number-th
+// page of Limit items and always count the total. WithTotal ask cursor mode
+// for the total too, large listing only get an estimate.
+type PageRequest struct {
+	Limit     int
+	Cursor    string
+	Number    int
+	WithTotal bool
+}
+
+// NewPageRequest build page request from query values:
+//
+//	limit, cursor      cursor mode, limit is required and from 1 to
+//	                   MaxPerPage
+//	with_total         true to count the listing in cursor mode
+//	page, per_page     offset mode, page start at 1 and is at most
+//	                   MaxPage, per_page default to DefaultPerPage and is
+//	                   at most MaxPerPage
+func NewPageRequest(values url.Values) (PageRequest, error) {
+	var res PageRequest
+	rawPage, rawPerPage := values.Get("page"), values.Get("per_page")
+	if rawPage != "" || rawPerPage != "" {
+		if values.Get("cursor") != "" {
+			return PageRequest{}, fmt.Errorf("%w: page can not be used with cursor", ErrInvalidPage)
+		}
+		res.Number, res.Limit = 1, DefaultPerPage
+		var err error
+		if rawPage != "" {
+			if res.Number, err = strconv.Atoi(rawPage); err != nil || res.Number < 1 || res.Number > MaxPage {
+				return PageRequest{}, fmt.Errorf("%w: page must be a number from 1 to %d", ErrInvalidPage, MaxPage)
+			}
+		}
+		if rawPerPage != "" {
+			if res.Limit, err = strconv.Atoi(rawPerPage); err != nil || res.Limit < 1 || res.Limit > MaxPerPage {
+				return PageRequest{}, fmt.Errorf("%w: per_page must be a number from 1 to %d", ErrInvalidPage, MaxPerPage)
+			}
+		}
+		return res, nil
+	}
+
+	var err error
+	if res.Limit, err = strconv.Atoi(values.Get("limit")); err != nil || res.Limit < 1 || res.Limit > MaxPerPage {
+		return PageRequest{}, fmt.Errorf("%w: limit must be a number from 1 to %d", ErrInvalidPage, MaxPerPage)
+	}
+	res.Cursor = values.Get("cursor")
+	if rawTotal := values.Get("with_total"); rawTotal != "" {
+		if res.WithTotal, err = strconv.ParseBool(rawTotal); err != nil {
+			return PageRequest{}, fmt.Errorf("%w: with_total must be true or false", ErrInvalidPage)
+		}
+	}
+	return res, nil
+}
+
+// OffsetMode tell whether the page is read by number.
+func (r PageRequest) OffsetMode() bool {
+	return r.Number > 0
+}
+
+// Offset is the number of items skipped before the page, 0 in cursor mode.
+func (r PageRequest) Offset() int {
+	if !r.OffsetMode() {
+		return 0
+	}
+	return (r.Number - 1) * r.Limit
+}
+
+// PageMeta is the pagination part of a listing meta. Page, PerPage and
+// TotalPages are only set in offset mode, Total when it was counted.
+type PageMeta struct {
+	Limit          int    `json:"limit"`
+	ThisPage       int    `json:"total_this_page"`
+	Next           string `json:"next_cursor"`
+	Prev           string `json:"prev_cursor"`
+	HasMore        bool   `json:"has_more"`
+	Page           int    `json:"page,omitempty"`
+	PerPage        int    `json:"per_page,omitempty"`
+	Total          *int64 `json:"total,omitempty"`
+	TotalPages     *int64 `json:"total_pages,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+}
+
+func NewPageMeta(req PageRequest, page Page, length int) PageMeta {
+	res := PageMeta{
+		Limit:          req.Limit,
+		ThisPage:       length,
+		Next:           page.NextCursor,
+		Prev:           page.PrevCursor,
+		HasMore:        page.HasMore,
+		TotalEstimated: page.TotalEstimated,
+	}
+	if page.Total.Valid {
+		total := page.Total.Int64
+		res.Total = &total
+	}
+	if req.OffsetMode() {
+		res.Page = req.Number
+		res.PerPage = req.Limit
+		if res.Total != nil {
+			pages := (*res.Total + int64(req.Limit) - 1) / int64(req.Limit)
+			res.TotalPages = &pages
+		}
+	}
+	return res
+}
+
+// Keyset return the row comparison and the order of the query reading the
+// page from cursor c. A page before the cursor is read in reverse and put
+// back in order afterward.
+func (s ListSort) Keyset(c ListCursor) (compare, direction string) {
+	if s.Desc != c.Before {
+		return "<", "DESC"
+	}
+	return ">", "ASC"
+}
+
+// Typed return the cursor value as the type of the sorted column.
+func (c ListCursor) Typed(sort ListSort) (any, error) {
+	if c.Sort != sort.String() {
+		return nil, fmt.Errorf("%w: cursor was made for sort %s", ErrInvalidCursor, c.Sort)
+	}
+	switch sort.Field {
+	case SortPrice:
+		price, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		return price, nil
+	case SortName:
+		return c.Value, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return t, nil
+}
+
+// AttributeFilter match item having the attribute set to one of Values.
+type AttributeFilter struct {
+	ID     ulid.ULID
+	Values []string
+}
+
+// ListFilter narrow product and variant listing, zero value of a field
+// disable it. MainID only apply to variants.
+type ListFilter struct {
+	Tags         TagFilter
+	MinPrice     null.Float
+	MaxPrice     null.Float
+	CategoryIDs  []ulid.ULID
+	BrandIDs     []ulid.ULID
+	Attributes   []AttributeFilter
+	NameContains string
+	CreatedFrom  null.Time
+	CreatedTo    null.Time
+	UpdatedFrom  null.Time
+	UpdatedTo    null.Time
+	MainID       ulid.ULID
+}
+
+// NewListFilter build filter from query values:
+//
+//	tag, tag_match              see NewTagFilter
+//	min_price, max_price        inclusive price range
+//	category                    category ids, comma separated or repeated, any match
+//	brand                       brand ids, comma separated or repeated, any match
+//	attr                        "<attribute id>:<value>", repeated; values of the same
+//	                            attribute are alternatives, different attributes are all required
+//	name                        case insensitive substring of the name
+//	created_from, created_to    inclusive date range, RFC 3339 or YYYY-MM-DD
+//	updated_from, updated_to
+//	main_id                     main product id of variants
+func NewListFilter(values url.Values) (ListFilter, error) {
+	res := ListFilter{
+		Tags:         NewTagFilter(values["tag"], values.Get("tag_match")),
+		NameContains: strings.TrimSpace(values.Get("name")),
+	}
+
+	var err error
+	if res.MinPrice, err = parseFloatFilter(values, "min_price"); err != nil {
+		return ListFilter{}, err
+	}
+	if res.MaxPrice, err = parseFloatFilter(values, "max_price"); err != nil {
+		return ListFilter{}, err
+	}
+	if res.MinPrice.Valid && res.MaxPrice.Valid && res.MinPrice.Float64 > res.MaxPrice.Float64 {
+		return ListFilter{}, fmt.Errorf("%w: min_price is greater than max_price", ErrInvalidFilter)
+	}
+
+	if res.CategoryIDs, err = parseIDsFilter(values, "category"); err != nil {
+		return ListFilter{}, err
+	}
+	if res.BrandIDs, err = parseIDsFilter(values, "brand"); err != nil {
+		return ListFilter{}, err
+	}
+
+	byID := make(map[ulid.ULID]int)
+	for _, value := range values["attr"] {
+		rawId, attrValue, ok := strings.Cut(value, ":")
+		if !ok || strings.TrimSpace(attrValue) == "" {
+			return ListFilter{}, fmt.Errorf("%w: attr must be <attribute id>:<value>, got %q", ErrInvalidFilter, value)
+		}
+		id, err := ulid.Parse(strings.TrimSpace(rawId))
+		if err != nil {
+			return ListFilter{}, fmt.Errorf("%w: attr %q: %v", ErrInvalidFilter, value, err)
+		}
+		idx, ok := byID[id]
+		if !ok {
+			idx = len(res.Attributes)
+			byID[id] = idx
+			res.Attributes = append(res.Attributes, AttributeFilter{ID: id})
+		}
+		res.Attributes[idx].Values = append(res.Attributes[idx].Values, strings.TrimSpace(attrValue))
+	}
+
+	if res.CreatedFrom, err = parseTimeFilter(values, "created_from", false); err != nil {
+		return ListFilter{}, err
+	}
+	if res.CreatedTo, err = parseTimeFilter(values, "created_to", true); err != nil {
+		return ListFilter{}, err
+	}
+	if res.UpdatedFrom, err = parseTimeFilter(values, "updated_from", false); err != nil {
+		return ListFilter{}, err
+	}
+	if res.UpdatedTo, err = parseTimeFilter(values, "updated_to", true); err != nil {
+		return ListFilter{}, err
+	}
+
+	if mainId := values.Get("main_id"); mainId != "" {
+		res.MainID, err = ulid.Parse(mainId)
+		if err != nil {
+			return ListFilter{}, fmt.Errorf("%w: main_id: %v", ErrInvalidFilter, err)
+		}
+	}
+	return res, nil
+}
+
+// AttributePairs flatten the attribute filter into parallel id and value
+// slices, the shape the queries unnest.
+func (f ListFilter) AttributePairs() (ids [][]byte, values []string) {
+	for _, attr := range f.Attributes {
+		for _, value := range attr.Values {
+			id := attr.ID
+			ids = append(ids, id[:])
+			values = append(values, value)
+		}
+	}
+	return ids, values
+}
+
+// CategoryKeys return CategoryIDs as raw bytes, the shape of a bytea array
+// parameter.
+func (f ListFilter) CategoryKeys() [][]byte {
+	return idKeys(f.CategoryIDs)
+}
+
+// BrandKeys return BrandIDs as raw bytes.
+func (f ListFilter) BrandKeys() [][]byte {
+	return idKeys(f.BrandIDs)
+}
+
+func idKeys(ids []ulid.ULID) [][]byte {
+	res := make([][]byte, len(ids))
+	for i := range ids {
+		res[i] = ids[i][:]
+	}
+	return res
+}
+
+// Scope return the filter in a canonical query form, equal filters give
+// the same scope whatever the order of their values.
+func (f ListFilter) Scope() string {
+	values := url.Values{}
+	if len(f.Tags.Tags) > 0 {
+		values["tag"] = sortedStrings(f.Tags.Tags)
+		if f.Tags.MatchAll {
+			values.Set("tag_match", "all")
+		}
+	}
+	if f.MinPrice.Valid {
+		values.Set("min_price", strconv.FormatFloat(f.MinPrice.Float64, 'f', -1, 64))
+	}
+	if f.MaxPrice.Valid {
+		values.Set("max_price", strconv.FormatFloat(f.MaxPrice.Float64, 'f', -1, 64))
+	}
+	if len(f.CategoryIDs) > 0 {
+		values["category"] = sortedIDs(f.CategoryIDs)
+	}
+	if len(f.BrandIDs) > 0 {
+		values["brand"] = sortedIDs(f.BrandIDs)
+	}
+	var attrs []string
+	for _, attr := range f.Attributes {
+		for _, value := range attr.Values {
+			attrs = append(attrs, attr.ID.String()+":"+value)
+		}
+	}
+	if len(attrs) > 0 {
+		values["attr"] = sortedStrings(attrs)
+	}
+	if f.NameContains != "" {
+		values.Set("name", f.NameContains)
+	}
+	for key, t := range map[string]null.Time{
+		"created_from": f.CreatedFrom,
+		"created_to":   f.CreatedTo,
+		"updated_from": f.UpdatedFrom,
+		"updated_to":   f.UpdatedTo,
+	} {
+		if t.Valid {
+			values.Set(key, t.Time.UTC().Format(time.RFC3339Nano))
+		}
+	}
+	if f.MainID != (ulid.ULID{}) {
+		values.Set("main_id", f.MainID.String())
+	}
+	return values.Encode()
+}
+
+func sortedStrings(list []string) []string {
+	res := append([]string(nil), list...)
+	sort.Strings(res)
+	return res
+}
+
+func sortedIDs(ids []ulid.ULID) []string {
+	res := make([]string, len(ids))
+	for i := range ids {
+		res[i] = ids[i].String()
+	}
+	sort.Strings(res)
+	return res
+}
+
+// NamePattern return the ILIKE pattern of NameContains with wildcard
+// escaped, or empty when the filter is unset.
+func (f ListFilter) NamePattern() string {
+	if f.NameContains == "" {
+		return ""
+	}
+	escaped := likeEscaper.Replace(f.NameContains)
+	return "%" + escaped + "%"
+}
+
+// likeEscaper escape the LIKE wildcards of user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// parseIDsFilter read ids given comma separated or as repeated value.
+func parseIDsFilter(values url.Values, key string) ([]ulid.ULID, error) {
+	var ids []ulid.ULID
+	for _, value := range values[key] {
+		for _, raw := range strings.Split(value, ",") {
+			if raw = strings.TrimSpace(raw); raw == "" {
+				continue
+			}
+			id, err := ulid.Parse(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s %q: %v", ErrInvalidFilter, key, raw, err)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func parseFloatFilter(values url.Values, key string) (null.Float, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return null.Float{}, nil
+	}
+	num, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return null.Float{}, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, key, err)
+	}
+	if math.IsNaN(num) || math.IsInf(num, 0) {
+		return null.Float{}, fmt.Errorf("%w: %s must be a finite number", ErrInvalidFilter, key)
+	}
+	return null.FloatFrom(num), nil
+}
+
+// parseTimeFilter accept RFC 3339 or a plain date, plain date as upper
+// bound include the whole day.
+func parseTimeFilter(values url.Values, key string, upper bool) (null.Time, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return null.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return null.TimeFrom(t), nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return null.Time{}, fmt.Errorf("%w: %s must be RFC 3339 or YYYY-MM-DD", ErrInvalidFilter, key)
+	}
+	if upper {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return null.TimeFrom(t), nil
+}
@@ -0,0 +1,210 @@
+package domain_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestNewListFilter(t *testing.T) {
+	attrA := ulid.MustParse("01J0000000000000000000000A")
+	attrB := ulid.MustParse("01J0000000000000000000000B")
+	category := ulid.MustParse("01J0000000000000000000000C")
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr error
+		check   func(t *testing.T, f domain.ListFilter)
+	}{
+		{
+			name:  "empty",
+			query: "",
+			check: func(t *testing.T, f domain.ListFilter) {
+				if f.MinPrice.Valid || f.MaxPrice.Valid || len(f.Tags.Tags) != 0 || len(f.Attributes) != 0 {
+					t.Errorf("filter = %+v, want zero", f)
+				}
+			},
+		},
+		{
+			name:  "tags are normalized and deduplicated",
+			query: "tag=Red,%20blue&tag=red&tag_match=ALL",
+			check: func(t *testing.T, f domain.ListFilter) {
+				if len(f.Tags.Tags) != 2 || f.Tags.Tags[0] != "red" || f.Tags.Tags[1] != "blue" || !f.Tags.MatchAll {
+					t.Errorf("tags = %+v, want [red blue] all", f.Tags)
+				}
+			},
+		},
+		{
+			name:  "price range",
+			query: "min_price=1.5&max_price=10",
+			check: func(t *testing.T, f domain.ListFilter) {
+				if f.MinPrice.Float64 != 1.5 || f.MaxPrice.Float64 != 10 {
+					t.Errorf("price = %v..%v, want 1.5..10", f.MinPrice, f.MaxPrice)
+				}
+			},
+		},
+		{name: "min above max", query: "min_price=10&max_price=1", wantErr: domain.ErrInvalidFilter},
+		{name: "price not a number", query: "min_price=cheap", wantErr: domain.ErrInvalidFilter},
+		{name: "price NaN", query: "min_price=NaN", wantErr: domain.ErrInvalidFilter},
+		{name: "price infinite", query: "max_price=Inf", wantErr: domain.ErrInvalidFilter},
+		{
+			name:  "categories comma separated or repeated",
+			query: "category=" + category.String() + ",%20&category=" + attrA.String(),
+			check: func(t *testing.T, f domain.ListFilter) {
+				if len(f.CategoryIDs) != 2 || f.CategoryIDs[0] != category || f.CategoryIDs[1] != attrA {
+					t.Errorf("categories = %v", f.CategoryIDs)
+				}
+			},
+		},
+		{name: "category not an id", query: "category=shoes", wantErr: domain.ErrInvalidFilter},
+		{
+			name:  "attribute values grouped by attribute",
+			query: "attr=" + attrA.String() + ":red&attr=" + attrB.String() + ":xl&attr=" + attrA.String() + ":%20blue",
+			check: func(t *testing.T, f domain.ListFilter) {
+				if len(f.Attributes) != 2 {
+					t.Fatalf("attributes = %+v, want 2", f.Attributes)
+				}
+				if a := f.Attributes[0]; a.ID != attrA || len(a.Values) != 2 || a.Values[0] != "red" || a.Values[1] != "blue" {
+					t.Errorf("first attribute = %+v, want red and blue", a)
+				}
+				if a := f.Attributes[1]; a.ID != attrB || len(a.Values) != 1 || a.Values[0] != "xl" {
+					t.Errorf("second attribute = %+v, want xl", a)
+				}
+			},
+		},
+		{name: "attribute without value", query: "attr=" + attrA.String() + ":", wantErr: domain.ErrInvalidFilter},
+		{name: "attribute without id", query: "attr=red", wantErr: domain.ErrInvalidFilter},
+		{
+			name:  "plain date upper bound include the whole day",
+			query: "created_from=2024-05-01&created_to=2024-05-01",
+			check: func(t *testing.T, f domain.ListFilter) {
+				from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+				if !f.CreatedFrom.Time.Equal(from) {
+					t.Errorf("created_from = %v, want %v", f.CreatedFrom.Time, from)
+				}
+				if want := from.Add(24*time.Hour - time.Nanosecond); !f.CreatedTo.Time.Equal(want) {
+					t.Errorf("created_to = %v, want %v", f.CreatedTo.Time, want)
+				}
+			},
+		},
+		{name: "date malformed", query: "updated_to=yesterday", wantErr: domain.ErrInvalidFilter},
+		{name: "main id malformed", query: "main_id=1", wantErr: domain.ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := domain.NewListFilter(values)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			tt.check(t, f)
+		})
+	}
+}
+
+func TestListFilterScope(t *testing.T) {
+	attr := ulid.MustParse("01J0000000000000000000000A")
+	a, b := ulid.MustParse("01J0000000000000000000000B"), ulid.MustParse("01J0000000000000000000000C")
+
+	scope := func(query string) string {
+		t.Helper()
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := domain.NewListFilter(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f.Scope()
+	}
+
+	tests := []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{
+			name:  "order of values does not matter",
+			a:     "tag=red,blue&category=" + a.String() + "," + b.String() + "&attr=" + attr.String() + ":x&attr=" + attr.String() + ":y",
+			b:     "attr=" + attr.String() + ":y&attr=" + attr.String() + ":x&category=" + b.String() + "&category=" + a.String() + "&tag=blue&tag=red",
+			equal: true,
+		},
+		{name: "same price written differently", a: "min_price=1.50", b: "min_price=1.5", equal: true},
+		{name: "other price", a: "min_price=1", b: "min_price=2"},
+		{name: "tag match all", a: "tag=red", b: "tag=red&tag_match=all"},
+		{name: "name filter", a: "", b: "name=shoe"},
+		{name: "date filter", a: "created_from=2024-01-01", b: "created_from=2024-01-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scope(tt.a) == scope(tt.b); got != tt.equal {
+				t.Errorf("scope(%q) == scope(%q) is %v, want %v", tt.a, tt.b, got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestNewPageRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    domain.PageRequest
+		wantErr bool
+	}{
+		{name: "cursor mode", query: "limit=10&cursor=abc&with_total=true", want: domain.PageRequest{Limit: 10, Cursor: "abc", WithTotal: true}},
+		{name: "limit required", query: "", wantErr: true},
+		{name: "limit not a number", query: "limit=ten", wantErr: true},
+		{name: "limit zero", query: "limit=0", wantErr: true},
+		{name: "limit negative", query: "limit=-1", wantErr: true},
+		{name: "limit above max", query: "limit=101", wantErr: true},
+		{name: "limit at max", query: "limit=100", want: domain.PageRequest{Limit: 100}},
+		{name: "with_total malformed", query: "limit=1&with_total=maybe", wantErr: true},
+		{name: "offset mode default per page", query: "page=3", want: domain.PageRequest{Limit: domain.DefaultPerPage, Number: 3}},
+		{name: "offset mode first page", query: "per_page=5", want: domain.PageRequest{Limit: 5, Number: 1}},
+		{name: "page zero", query: "page=0", wantErr: true},
+		{name: "page at max", query: "page=21474836&per_page=100", want: domain.PageRequest{Limit: 100, Number: domain.MaxPage}},
+		{name: "page above max", query: "page=21474837", wantErr: true},
+		{name: "page overflowing int", query: "page=9223372036854775807&per_page=100", wantErr: true},
+		{name: "per_page above max", query: "page=1&per_page=101", wantErr: true},
+		{name: "page with cursor", query: "page=1&cursor=abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := domain.NewPageRequest(values)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidPage) {
+					t.Fatalf("err = %v, want %v", err, domain.ErrInvalidPage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("page request = %+v, want %+v", got, tt.want)
+			}
+			if offset := got.Offset(); offset < 0 {
+				t.Errorf("offset = %d, want at least 0", offset)
+			}
+		})
+	}
+}
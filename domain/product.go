@@ -12,6 +12,9 @@ type Product struct {
 	Description  string
 	Price        float64
 	ImagePreview []byte
+	Image        Blob
+	Brand        Brand
+	Shipping     Shipping
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	DeletedAt    time.Time
@@ -21,21 +24,48 @@ type ProductDTO struct {
 	Name        string    `json:"name"`
 	Description string    `json:"desc"`
 	Price       float64   `json:"price"`
-	Image       []byte    `json:"image"`
+	Image       string    `json:"image,omitempty"`
 }
 
+// ProductDetailDTO is a product with its relations. Listing only fill the
+// relations asked with include, VariantSummary is only set on detail.
 type ProductDetailDTO struct {
 	ProductDTO
-	Category  []CategoriesDTO
-	Attribute []AttributesDTO
+	Category       []CategoriesDTO     `json:"Category,omitempty"`
+	Attribute      []AttributesDTO     `json:"Attribute,omitempty"`
+	Variants       []VariantDTO        `json:"variants,omitempty"`
+	Related        []RelatedProductDTO `json:"related,omitempty"`
+	Tags           []string            `json:"tags,omitempty"`
+	Brand          *BrandsDTO          `json:"brand,omitempty"`
+	Shipping       *ShippingDTO        `json:"shipping,omitempty"`
+	Images         []ImageDTO          `json:"images,omitempty"`
+	VariantSummary *VariantSummary     `json:"variant_summary,omitempty"`
 }
 
-func NewProduct(name, desc string, price float64) (Product, error) {
+// HasImage report whether the product has its own preview image, in
+// storage or still inline.
+func (p Product) HasImage() bool {
+	return !p.Image.Empty() || len(p.ImagePreview) > 0
+}
+
+// ImageURL return the path serving the product image, or empty when the
+// product has none.
+func (p Product) ImageURL() string {
+	if !p.HasImage() {
+		return ""
+	}
+	return ProductImageURL(p.ProductID)
+}
+
+func NewProduct(name, desc string, price float64, brandId ulid.ULID) (Product, error) {
 	id := ulid.Make()
 	return Product{
 		ProductID:   id,
 		Name:        name,
 		Description: desc,
 		Price:       price,
+		Brand: Brand{
+			BrandID: brandId,
+		},
 	}, nil
 }
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxImageDistance is the biggest Hamming distance of two 64 bit
+// perceptual hashes.
+const MaxImageDistance = 64
+
+var (
+	ErrInvalidImageDistance  = fmt.Errorf("image distance must be between 0 and %d", MaxImageDistance)
+	ErrInvalidDuplicateLimit = errors.New("limit must be positive")
+)
+
+// DuplicateProduct is a pair of products having images whose perceptual
+// hashes are Distance bits apart.
+type DuplicateProduct struct {
+	Product   Product
+	Duplicate Product
+	Distance  int
+}
+
+type DuplicateProductDTO struct {
+	Product   ProductDTO `json:"product"`
+	Duplicate ProductDTO `json:"duplicate"`
+	Distance  int        `json:"distance"`
+}
+
+func NewDuplicateProductDTO(dup DuplicateProduct) DuplicateProductDTO {
+	return DuplicateProductDTO{
+		Product:   newDuplicateSide(dup.Product),
+		Duplicate: newDuplicateSide(dup.Duplicate),
+		Distance:  dup.Distance,
+	}
+}
+
+// every product of a pair has at least one image, served by its image url
+func newDuplicateSide(prd Product) ProductDTO {
+	return ProductDTO{
+		ID:          prd.ProductID,
+		Name:        prd.Name,
+		Description: prd.Description,
+		Price:       prd.Price,
+		Image:       ProductImageURL(prd.ProductID),
+	}
+}
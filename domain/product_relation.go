@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+type RelationType string
+
+const (
+	RelationRelated     RelationType = "related"
+	RelationAccessory   RelationType = "accessory"
+	RelationUpsell      RelationType = "upsell"
+	RelationReplacement RelationType = "replacement"
+)
+
+var (
+	ErrInvalidRelationType = errors.New("relation type must be one of related, accessory, upsell or replacement")
+	ErrSelfRelation        = errors.New("product can not be related to itself")
+	ErrRelationProduct     = errors.New("product or related product does not exist")
+)
+
+func (t RelationType) Valid() bool {
+	switch t {
+	case RelationRelated, RelationAccessory, RelationUpsell, RelationReplacement:
+		return true
+	}
+	return false
+}
+
+type ProductRelation struct {
+	ProductRelationID ulid.ULID
+	Product           Product
+	RelatedProduct    Product
+	Type              RelationType
+	Position          int
+	Bidirectional     bool
+	CreatedAt         time.Time
+	UpdatedAt         null.Time
+	DeletedAt         null.Time
+}
+
+type RelatedProductDTO struct {
+	ProductDTO
+	Type     RelationType `json:"type"`
+	Position int          `json:"position"`
+}
+
+func NewRelationProduct(pId, rId ulid.ULID, relType RelationType, position int, bidirectional bool) (ProductRelation, error) {
+	if !relType.Valid() {
+		return ProductRelation{}, ErrInvalidRelationType
+	}
+	if pId == rId {
+		return ProductRelation{}, ErrSelfRelation
+	}
+	id := ulid.Make()
+	return ProductRelation{
+		ProductRelationID: id,
+		Product: Product{
+			ProductID: pId,
+		},
+		RelatedProduct: Product{
+			ProductID: rId,
+		},
+		Type:          relType,
+		Position:      position,
+		Bidirectional: bidirectional,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// Reverse build the mirrored relation of a bidirectional relation.
+func (r ProductRelation) Reverse() (ProductRelation, error) {
+	return NewRelationProduct(
+		r.RelatedProduct.ProductID,
+		r.Product.ProductID,
+		r.Type,
+		r.Position,
+		r.Bidirectional,
+	)
+}
@@ -0,0 +1,61 @@
+package domain_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestNewRelationProduct(t *testing.T) {
+	a := ulid.MustParse("01J0000000000000000000000A")
+	b := ulid.MustParse("01J0000000000000000000000B")
+
+	tests := []struct {
+		name    string
+		pId     ulid.ULID
+		rId     ulid.ULID
+		relType domain.RelationType
+		wantErr error
+	}{
+		{name: "related", pId: a, rId: b, relType: domain.RelationRelated},
+		{name: "accessory", pId: a, rId: b, relType: domain.RelationAccessory},
+		{name: "upsell", pId: a, rId: b, relType: domain.RelationUpsell},
+		{name: "replacement", pId: a, rId: b, relType: domain.RelationReplacement},
+		{name: "unknown type", pId: a, rId: b, relType: "similar", wantErr: domain.ErrInvalidRelationType},
+		{name: "empty type", pId: a, rId: b, relType: "", wantErr: domain.ErrInvalidRelationType},
+		{name: "self relation", pId: a, rId: a, relType: domain.RelationRelated, wantErr: domain.ErrSelfRelation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel, err := domain.NewRelationProduct(tt.pId, tt.rId, tt.relType, 3, true)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if rel.Product.ProductID != tt.pId || rel.RelatedProduct.ProductID != tt.rId || rel.Type != tt.relType {
+				t.Errorf("relation = %+v", rel)
+			}
+
+			reverse, err := rel.Reverse()
+			if err != nil {
+				t.Fatalf("reverse err = %v", err)
+			}
+			if reverse.Product.ProductID != tt.rId || reverse.RelatedProduct.ProductID != tt.pId {
+				t.Errorf("reverse link %s to %s, want %s to %s", reverse.Product.ProductID, reverse.RelatedProduct.ProductID, tt.rId, tt.pId)
+			}
+			if reverse.Type != rel.Type || reverse.Position != rel.Position || !reverse.Bidirectional {
+				t.Errorf("reverse = %+v, want the type, position and direction of %+v", reverse, rel)
+			}
+			if reverse.ProductRelationID == rel.ProductRelationID {
+				t.Error("reverse share the relation id")
+			}
+		})
+	}
+}
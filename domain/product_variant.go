@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/oklog/ulid/v2"
@@ -13,25 +15,104 @@ type Variant struct {
 	Name        string
 	Description string
 	Price       float64
+	Shipping    Shipping
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   null.Time
 }
 
 type VariantDTO struct {
-	ID              ulid.ULID `json:"id"`
-	Name            string    `json:"name"`
-	Description     string    `json:"desc"`
-	Price           float64   `json:"price"`
-	Image           []byte    `json:"image"`
-	MainProductID   ulid.ULID `json:"main_id"`
-	MainProductName string    `json:"main_name"`
+	ID              ulid.ULID       `json:"id"`
+	Name            string          `json:"name"`
+	Description     string          `json:"desc"`
+	Price           float64         `json:"price"`
+	Image           string          `json:"image,omitempty"`
+	MainProductID   ulid.ULID       `json:"main_id"`
+	MainProductName string          `json:"main_name"`
+	Tags            []string        `json:"tags,omitempty"`
+	Attributes      []AttributesDTO `json:"attributes,omitempty"`
+	Shipping        *ShippingDTO    `json:"shipping,omitempty"`
+	Images          []ImageDTO      `json:"images,omitempty"`
 }
 
+// VariantDetailDTO is a variant with the categories of its main product.
 type VariantDetailDTO struct {
 	VariantDTO
-	Category  []CategoriesDTO
-	Attribute []AttributesDTO
+	Category  []CategoriesDTO `json:"Category,omitempty"`
+	Attribute []AttributesDTO `json:"Attribute,omitempty"`
+}
+
+// NewVariantDTO return the fields of v read by listing, its main product
+// name may be translated afterward.
+func NewVariantDTO(v Variant) VariantDTO {
+	return VariantDTO{
+		ID:              v.VariantID,
+		Name:            v.Name,
+		Description:     v.Description,
+		Price:           v.Price,
+		MainProductID:   v.MainProduct.ProductID,
+		MainProductName: v.MainProduct.Name,
+	}
+}
+
+// VariantSummary describe the variants of a product. Prices are null when
+// the product has no variant. Axes are the attributes the variants set,
+// with every value they take.
+type VariantSummary struct {
+	Count    int           `json:"count"`
+	MinPrice null.Float    `json:"min_price"`
+	MaxPrice null.Float    `json:"max_price"`
+	Axes     []VariantAxis `json:"axes"`
+}
+
+type VariantAxis struct {
+	ID     ulid.ULID `json:"id"`
+	Name   string    `json:"name"`
+	Values []string  `json:"values"`
+}
+
+// VariantAxisValue is an attribute value set by a variant.
+type VariantAxisValue struct {
+	ID    ulid.ULID
+	Name  string
+	Value string
+}
+
+// NewVariantAxes group the values set by the variants of a product into
+// one axis per attribute, ordered by name. Values of an axis are sorted
+// and distinct.
+func NewVariantAxes(values []VariantAxisValue) []VariantAxis {
+	axes := []VariantAxis{}
+	index := make(map[ulid.ULID]int)
+	for _, v := range values {
+		i, ok := index[v.ID]
+		if !ok {
+			i = len(axes)
+			index[v.ID] = i
+			axes = append(axes, VariantAxis{ID: v.ID, Name: v.Name})
+		}
+		axes[i].Values = append(axes[i].Values, v.Value)
+	}
+	for i := range axes {
+		sort.Strings(axes[i].Values)
+		axes[i].Values = slices.Compact(axes[i].Values)
+	}
+	sort.Slice(axes, func(i, j int) bool {
+		if axes[i].Name != axes[j].Name {
+			return axes[i].Name < axes[j].Name
+		}
+		return axes[i].ID.Compare(axes[j].ID) < 0
+	})
+	return axes
+}
+
+// ImageURL return the path serving the variant image, or empty when its
+// main product has no image.
+func (v Variant) ImageURL() string {
+	if !v.MainProduct.HasImage() {
+		return ""
+	}
+	return VariantImageURL(v.VariantID)
 }
 
 func NewVariant(name, desc string, price float64, mainId ulid.ULID) (Variant, error) {
@@ -0,0 +1,71 @@
+package domain_test
+
+import (
+	"flukis/product/domain"
+	"slices"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestNewVariantAxes(t *testing.T) {
+	color := ulid.MustParse("01J0000000000000000000000A")
+	size := ulid.MustParse("01J0000000000000000000000B")
+	other := ulid.MustParse("01J0000000000000000000000C")
+
+	tests := []struct {
+		name   string
+		values []domain.VariantAxisValue
+		want   []domain.VariantAxis
+	}{
+		{name: "no value", values: nil, want: []domain.VariantAxis{}},
+		{
+			name: "grouped by attribute and ordered by name",
+			values: []domain.VariantAxisValue{
+				{ID: size, Name: "size", Value: "xl"},
+				{ID: color, Name: "color", Value: "red"},
+				{ID: size, Name: "size", Value: "m"},
+				{ID: color, Name: "color", Value: "blue"},
+			},
+			want: []domain.VariantAxis{
+				{ID: color, Name: "color", Values: []string{"blue", "red"}},
+				{ID: size, Name: "size", Values: []string{"m", "xl"}},
+			},
+		},
+		{
+			name: "value shared by variants listed once",
+			values: []domain.VariantAxisValue{
+				{ID: color, Name: "color", Value: "red"},
+				{ID: color, Name: "color", Value: "blue"},
+				{ID: color, Name: "color", Value: "red"},
+			},
+			want: []domain.VariantAxis{
+				{ID: color, Name: "color", Values: []string{"blue", "red"}},
+			},
+		},
+		{
+			name: "same name ordered by id",
+			values: []domain.VariantAxisValue{
+				{ID: other, Name: "color", Value: "green"},
+				{ID: color, Name: "color", Value: "red"},
+			},
+			want: []domain.VariantAxis{
+				{ID: color, Name: "color", Values: []string{"red"}},
+				{ID: other, Name: "color", Values: []string{"green"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := domain.NewVariantAxes(tt.values)
+			if len(got) != len(tt.want) || got == nil {
+				t.Fatalf("axes = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].ID != tt.want[i].ID || got[i].Name != tt.want[i].Name || !slices.Equal(got[i].Values, tt.want[i].Values) {
+					t.Errorf("axis %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Include is a relation a read can expand.
+type Include string
+
+const (
+	IncludeVariants   Include = "variants"
+	IncludeCategories Include = "categories"
+	IncludeAttributes Include = "attributes"
+	IncludeImages     Include = "images"
+)
+
+var (
+	ErrInvalidInclude = errors.New("invalid include")
+	ErrInvalidFields  = errors.New("invalid fields")
+)
+
+// ProductIncludes and VariantIncludes are the relations product and
+// variant reads can expand.
+var (
+	ProductIncludes = []Include{IncludeVariants, IncludeCategories, IncludeAttributes, IncludeImages}
+	VariantIncludes = []Include{IncludeCategories, IncludeAttributes, IncludeImages}
+)
+
+// Includes is the set of relations asked for.
+type Includes map[Include]bool
+
+func (i Includes) Has(include Include) bool {
+	return i[include]
+}
+
+// ReadOptions shape a read. Fields keep only the named json fields of each
+// item, empty keep them all. Include expand the named relations, they are
+// loaded for the whole page at once.
+type ReadOptions struct {
+	Fields  []string
+	Include Includes
+}
+
+// NewReadOptions build options from "fields" and "include" query values,
+// both comma separated or repeated. Include must be one of allowed.
+func NewReadOptions(values url.Values, allowed []Include) (ReadOptions, error) {
+	res := ReadOptions{
+		Fields:  splitList(values["fields"]),
+		Include: make(Includes),
+	}
+	for _, field := range res.Fields {
+		if strings.ContainsAny(field, " \t\"") {
+			return ReadOptions{}, fmt.Errorf("%w: %q", ErrInvalidFields, field)
+		}
+	}
+	for _, raw := range splitList(values["include"]) {
+		include := Include(strings.ToLower(raw))
+		valid := false
+		for _, a := range allowed {
+			if include == a {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return ReadOptions{}, fmt.Errorf("%w: %q, must be one of %s", ErrInvalidInclude, raw, joinIncludes(allowed))
+		}
+		res.Include[include] = true
+	}
+	return res, nil
+}
+
+func joinIncludes(includes []Include) string {
+	names := make([]string, len(includes))
+	for i := range includes {
+		names[i] = string(includes[i])
+	}
+	return strings.Join(names, ", ")
+}
+
+// splitList read values given comma separated or repeated, blank entries
+// are dropped.
+func splitList(values []string) []string {
+	var res []string
+	for _, value := range values {
+		for _, raw := range strings.Split(value, ",") {
+			if raw = strings.TrimSpace(raw); raw != "" {
+				res = append(res, raw)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// SearchSort is the only order of search result, best match first.
+const SearchSort = "rank:desc"
+
+var (
+	ErrEmptySearch        = errors.New("search query can not be empty")
+	ErrInvalidSearchLimit = errors.New("limit must be between 1 and 100")
+
+	ErrUnknownSearchBackend = errors.New("search backend must be one of postgres or memory")
+)
+
+// MaxSearchLimit is the biggest page of search result.
+const MaxSearchLimit = 100
+
+// SearchHit is a product or variant matching a search query. ProductID is
+// the product itself or the main product of a variant. Score is the text
+// relevance and Rank the order after search rules. NameHighlight and
+// Snippet are HTML escaped and mark the matched words with <mark>.
+type SearchHit struct {
+	EntityType    EntityType
+	ID            ulid.ULID
+	ProductID     ulid.ULID
+	Name          string
+	Description   string
+	Price         float64
+	HasImage      bool
+	Score         float64
+	Rank          float64
+	NameHighlight string
+	Snippet       string
+}
+
+// sentinels put around the matched words by the database, they are only
+// turned into <mark> after the text is escaped so stored markup can not
+// get through
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+var highlightReplacer = strings.NewReplacer(HighlightStart, "<mark>", HighlightStop, "</mark>")
+
+// EscapeHighlight HTML escape text and replace the highlight sentinels
+// with <mark>.
+func EscapeHighlight(text string) string {
+	return highlightReplacer.Replace(html.EscapeString(text))
+}
+
+type SearchHighlightDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"desc"`
+}
+
+type SearchHitDTO struct {
+	Type        EntityType         `json:"type"`
+	ID          ulid.ULID          `json:"id"`
+	ProductID   ulid.ULID          `json:"product_id"`
+	Name        string             `json:"name"`
+	Description string             `json:"desc"`
+	Price       float64            `json:"price"`
+	Image       string             `json:"image"`
+	Score       float64            `json:"score"`
+	Highlight   SearchHighlightDTO `json:"highlight"`
+}
+
+// NormalizeSearch trim the query and collapse its whitespace.
+func NormalizeSearch(q string) (string, error) {
+	q = strings.Join(strings.Fields(q), " ")
+	if q == "" {
+		return "", ErrEmptySearch
+	}
+	return q, nil
+}
+
+// ImageURL return the path serving the image of the hit, or empty when it
+// has none.
+func (h SearchHit) ImageURL() string {
+	if !h.HasImage {
+		return ""
+	}
+	if h.EntityType == EntityVariant {
+		return VariantImageURL(h.ID)
+	}
+	return ProductImageURL(h.ID)
+}
+
+func NewSearchHitDTO(hit SearchHit) SearchHitDTO {
+	return SearchHitDTO{
+		Type:        hit.EntityType,
+		ID:          hit.ID,
+		ProductID:   hit.ProductID,
+		Name:        hit.Name,
+		Description: hit.Description,
+		Price:       hit.Price,
+		Image:       hit.ImageURL(),
+		Score:       hit.Score,
+		Highlight: SearchHighlightDTO{
+			Name:        hit.NameHighlight,
+			Description: hit.Snippet,
+		},
+	}
+}
+
+// SearchScope is the cursor scope of a search, the query and its filters.
+func SearchScope(q string, filter ListFilter) string {
+	return "q=" + url.QueryEscape(q) + "&" + filter.Scope()
+}
+
+// NewSearchCursor point after the given hit.
+func NewSearchCursor(hit SearchHit) ListCursor {
+	return ListCursor{
+		Sort:  SearchSort,
+		Value: strconv.FormatFloat(hit.Rank, 'g', -1, 64),
+		ID:    hit.ID,
+	}
+}
+
+// SearchRank return the rank of a cursor made by NewSearchCursor.
+func (c ListCursor) SearchRank() (float64, error) {
+	if c.Sort != SearchSort {
+		return 0, fmt.Errorf("%w: cursor was made for sort %s", ErrInvalidCursor, c.Sort)
+	}
+	rank, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return rank, nil
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// SearchDocument is a product or variant as a search backend index it.
+// Variant carry the categories and brand of its main product, and the
+// product attributes it does not override.
+type SearchDocument struct {
+	EntityType    EntityType
+	ID            ulid.ULID
+	ProductID     ulid.ULID
+	Name          string
+	ProductName   string
+	Description   string
+	Price         float64
+	HasImage      bool
+	BrandID       ulid.ULID
+	CategoryIDs   []ulid.ULID
+	CategoryNames []string
+	Tags          []string
+	Attributes    map[ulid.ULID]string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Matches tell whether the document pass the filter, the same way the
+// search query apply it. MainID is a listing filter and is ignored.
+func (d SearchDocument) Matches(filter ListFilter) bool {
+	if len(filter.Tags.Tags) > 0 {
+		matched := 0
+		for _, tag := range filter.Tags.Tags {
+			if containsString(d.Tags, tag) {
+				matched++
+			}
+		}
+		if matched == 0 || (filter.Tags.MatchAll && matched < len(filter.Tags.Tags)) {
+			return false
+		}
+	}
+	if filter.NameContains != "" && !strings.Contains(strings.ToLower(d.Name), strings.ToLower(filter.NameContains)) {
+		return false
+	}
+	if filter.CreatedFrom.Valid && d.CreatedAt.Before(filter.CreatedFrom.Time) {
+		return false
+	}
+	if filter.CreatedTo.Valid && d.CreatedAt.After(filter.CreatedTo.Time) {
+		return false
+	}
+	if filter.UpdatedFrom.Valid && d.UpdatedAt.Before(filter.UpdatedFrom.Time) {
+		return false
+	}
+	if filter.UpdatedTo.Valid && d.UpdatedAt.After(filter.UpdatedTo.Time) {
+		return false
+	}
+	if filter.MinPrice.Valid && d.Price < filter.MinPrice.Float64 {
+		return false
+	}
+	if filter.MaxPrice.Valid && d.Price > filter.MaxPrice.Float64 {
+		return false
+	}
+	if len(filter.CategoryIDs) > 0 {
+		matched := false
+		for _, id := range d.CategoryIDs {
+			if containsID(filter.CategoryIDs, id) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(filter.BrandIDs) > 0 && !containsID(filter.BrandIDs, d.BrandID) {
+		return false
+	}
+	for _, attr := range filter.Attributes {
+		value, ok := d.Attributes[attr.ID]
+		if !ok || !containsString(attr.Values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+	return false
+}
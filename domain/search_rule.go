@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+type SearchRuleAction string
+
+const (
+	// RuleBoost pin the product on top of the result, even when it does
+	// not match the text, ordered by position.
+	RuleBoost SearchRuleAction = "boost"
+	// RuleBury push the product and its variants below every other hit.
+	RuleBury SearchRuleAction = "bury"
+)
+
+const maxSearchTermLength = 100
+
+var (
+	ErrInvalidSynonym          = errors.New("synonym set must have at least two different terms of at most 100 characters")
+	ErrInvalidSearchRuleAction = errors.New("search rule action must be one of boost or bury")
+	ErrInvalidSearchRule       = errors.New("search rule must have a query of at most 100 characters and a product")
+	ErrSearchRuleProduct       = errors.New("search rule product does not exist")
+)
+
+func (a SearchRuleAction) Valid() bool {
+	return a == RuleBoost || a == RuleBury
+}
+
+// Synonym is a set of equivalent terms, searching any of them find the
+// others. A term may be several words, like "t shirt".
+type Synonym struct {
+	SynonymID ulid.ULID
+	Terms     []string
+	CreatedAt time.Time
+	UpdatedAt null.Time
+	DeletedAt null.Time
+}
+
+type SynonymDTO struct {
+	ID    ulid.ULID `json:"id"`
+	Terms []string  `json:"terms"`
+}
+
+// SearchRule boost or bury a product when the search text is Query.
+type SearchRule struct {
+	RuleID    ulid.ULID
+	Query     string
+	ProductID ulid.ULID
+	Action    SearchRuleAction
+	Position  int
+	CreatedAt time.Time
+	UpdatedAt null.Time
+	DeletedAt null.Time
+}
+
+type SearchRuleDTO struct {
+	ID        ulid.ULID        `json:"id"`
+	Query     string           `json:"query"`
+	ProductID ulid.ULID        `json:"product_id"`
+	Action    SearchRuleAction `json:"action"`
+	Position  int              `json:"position"`
+}
+
+// NormalizeSearchTerm lowercase the term and collapse its whitespace, rule
+// query and synonym term are compared in this form.
+func NormalizeSearchTerm(term string) string {
+	return strings.Join(strings.Fields(strings.ToLower(term)), " ")
+}
+
+// SynonymTerms normalize and dedupe terms.
+func SynonymTerms(terms []string) ([]string, error) {
+	res := make([]string, 0, len(terms))
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		term = NormalizeSearchTerm(term)
+		if term == "" || seen[term] {
+			continue
+		}
+		if utf8.RuneCountInString(term) > maxSearchTermLength {
+			return nil, ErrInvalidSynonym
+		}
+		seen[term] = true
+		res = append(res, term)
+	}
+	if len(res) < 2 {
+		return nil, ErrInvalidSynonym
+	}
+	return res, nil
+}
+
+func NewSynonym(terms []string) (Synonym, error) {
+	terms, err := SynonymTerms(terms)
+	if err != nil {
+		return Synonym{}, err
+	}
+	return Synonym{
+		SynonymID: ulid.Make(),
+		Terms:     terms,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func NewSynonymDTO(syn Synonym) SynonymDTO {
+	return SynonymDTO{
+		ID:    syn.SynonymID,
+		Terms: syn.Terms,
+	}
+}
+
+func NewSearchRule(query string, productId ulid.ULID, action SearchRuleAction, position int) (SearchRule, error) {
+	query = NormalizeSearchTerm(query)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchTermLength || productId == (ulid.ULID{}) {
+		return SearchRule{}, ErrInvalidSearchRule
+	}
+	if !action.Valid() {
+		return SearchRule{}, ErrInvalidSearchRuleAction
+	}
+	return SearchRule{
+		RuleID:    ulid.Make(),
+		Query:     query,
+		ProductID: productId,
+		Action:    action,
+		Position:  position,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func NewSearchRuleDTO(rule SearchRule) SearchRuleDTO {
+	return SearchRuleDTO{
+		ID:        rule.RuleID,
+		Query:     rule.Query,
+		ProductID: rule.ProductID,
+		Action:    rule.Action,
+		Position:  rule.Position,
+	}
+}
@@ -0,0 +1,71 @@
+package domain_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestSynonymTerms(t *testing.T) {
+	tests := []struct {
+		name    string
+		terms   []string
+		want    []string
+		wantErr error
+	}{
+		{name: "normalized", terms: []string{"T  Shirt", "Tee"}, want: []string{"t shirt", "tee"}},
+		{name: "deduplicated in order", terms: []string{"tee", "T-Shirt", "TEE", " tee "}, want: []string{"tee", "t-shirt"}},
+		{name: "blank terms skipped", terms: []string{"", "  ", "sofa", "couch"}, want: []string{"sofa", "couch"}},
+		{name: "one term", terms: []string{"sofa"}, wantErr: domain.ErrInvalidSynonym},
+		{name: "one term after dedupe", terms: []string{"Sofa", "sofa "}, wantErr: domain.ErrInvalidSynonym},
+		{name: "none", terms: nil, wantErr: domain.ErrInvalidSynonym},
+		{name: "term too long", terms: []string{"sofa", strings.Repeat("a", 101)}, wantErr: domain.ErrInvalidSynonym},
+		{name: "term at the limit", terms: []string{"sofa", strings.Repeat("é", 100)}, want: []string{"sofa", strings.Repeat("é", 100)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := domain.SynonymTerms(tt.terms)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SynonymTerms(%q) = %q, want %q", tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSearchRule(t *testing.T) {
+	product := ulid.MustParse("01J0000000000000000000000A")
+
+	tests := []struct {
+		name      string
+		query     string
+		productId ulid.ULID
+		action    domain.SearchRuleAction
+		wantQuery string
+		wantErr   error
+	}{
+		{name: "boost", query: "Red  Shoe", productId: product, action: domain.RuleBoost, wantQuery: "red shoe"},
+		{name: "bury", query: "shoe", productId: product, action: domain.RuleBury, wantQuery: "shoe"},
+		{name: "empty query", query: "  ", productId: product, action: domain.RuleBoost, wantErr: domain.ErrInvalidSearchRule},
+		{name: "query too long", query: strings.Repeat("a", 101), productId: product, action: domain.RuleBoost, wantErr: domain.ErrInvalidSearchRule},
+		{name: "no product", query: "shoe", action: domain.RuleBoost, wantErr: domain.ErrInvalidSearchRule},
+		{name: "unknown action", query: "shoe", productId: product, action: "pin", wantErr: domain.ErrInvalidSearchRuleAction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := domain.NewSearchRule(tt.query, tt.productId, tt.action, 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && rule.Query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", rule.Query, tt.wantQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+const (
+	DefaultWeightUnit    = "g"
+	DefaultDimensionUnit = "cm"
+)
+
+var ErrInvalidShipping = errors.New("invalid shipping data")
+
+var (
+	weightUnits    = map[string]bool{"g": true, "kg": true, "oz": true, "lb": true}
+	dimensionUnits = map[string]bool{"mm": true, "cm": true, "m": true, "in": true}
+)
+
+// Shipping hold weight and package dimension of a product or variant.
+// Unset value on variant is inherited from its main product.
+type Shipping struct {
+	Weight        null.Float
+	WeightUnit    string
+	Length        null.Float
+	Width         null.Float
+	Height        null.Float
+	DimensionUnit string
+	ShippingClass null.String
+}
+
+type ShippingDTO struct {
+	Weight        null.Float  `json:"weight"`
+	WeightUnit    string      `json:"weight_unit"`
+	Length        null.Float  `json:"length"`
+	Width         null.Float  `json:"width"`
+	Height        null.Float  `json:"height"`
+	DimensionUnit string      `json:"dimension_unit"`
+	ShippingClass null.String `json:"shipping_class"`
+}
+
+func NewShipping(weight, length, width, height null.Float, weightUnit, dimensionUnit string, class null.String) (Shipping, error) {
+	if weightUnit == "" {
+		weightUnit = DefaultWeightUnit
+	}
+	if dimensionUnit == "" {
+		dimensionUnit = DefaultDimensionUnit
+	}
+	if !weightUnits[weightUnit] {
+		return Shipping{}, fmt.Errorf("%w: weight unit must be one of g, kg, oz or lb", ErrInvalidShipping)
+	}
+	if !dimensionUnits[dimensionUnit] {
+		return Shipping{}, fmt.Errorf("%w: dimension unit must be one of mm, cm, m or in", ErrInvalidShipping)
+	}
+	if weight.Valid && weight.Float64 <= 0 {
+		return Shipping{}, fmt.Errorf("%w: weight must be greater than zero", ErrInvalidShipping)
+	}
+	dims := []null.Float{length, width, height}
+	set := 0
+	for _, d := range dims {
+		if !d.Valid {
+			continue
+		}
+		if d.Float64 <= 0 {
+			return Shipping{}, fmt.Errorf("%w: length, width and height must be greater than zero", ErrInvalidShipping)
+		}
+		set++
+	}
+	if set != 0 && set != len(dims) {
+		return Shipping{}, fmt.Errorf("%w: length, width and height must be set together", ErrInvalidShipping)
+	}
+	if class.Valid && class.String == "" {
+		class = null.String{}
+	}
+	return Shipping{
+		Weight:        weight,
+		WeightUnit:    weightUnit,
+		Length:        length,
+		Width:         width,
+		Height:        height,
+		DimensionUnit: dimensionUnit,
+		ShippingClass: class,
+	}, nil
+}
+
+// Inherit fill the unset weight, dimension and shipping class from parent.
+func (s Shipping) Inherit(parent Shipping) Shipping {
+	if !s.Weight.Valid {
+		s.Weight = parent.Weight
+		s.WeightUnit = parent.WeightUnit
+	}
+	if !s.Length.Valid {
+		s.Length = parent.Length
+		s.Width = parent.Width
+		s.Height = parent.Height
+		s.DimensionUnit = parent.DimensionUnit
+	}
+	if !s.ShippingClass.Valid {
+		s.ShippingClass = parent.ShippingClass
+	}
+	return s
+}
+
+func NewShippingDTO(s Shipping) *ShippingDTO {
+	return &ShippingDTO{
+		Weight:        s.Weight,
+		WeightUnit:    s.WeightUnit,
+		Length:        s.Length,
+		Width:         s.Width,
+		Height:        s.Height,
+		DimensionUnit: s.DimensionUnit,
+		ShippingClass: s.ShippingClass,
+	}
+}
@@ -0,0 +1,79 @@
+package domain_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"testing"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestNewShipping(t *testing.T) {
+	unset := null.Float{}
+	num := null.FloatFrom
+
+	tests := []struct {
+		name                      string
+		weight                    null.Float
+		length, width, height     null.Float
+		weightUnit, dimensionUnit string
+		class                     null.String
+		want                      domain.Shipping
+		wantErr                   bool
+	}{
+		{
+			name:   "defaults units",
+			weight: num(250),
+			want: domain.Shipping{
+				Weight:        num(250),
+				WeightUnit:    domain.DefaultWeightUnit,
+				DimensionUnit: domain.DefaultDimensionUnit,
+			},
+		},
+		{
+			name:   "every field",
+			weight: num(1.2), length: num(30), width: num(20), height: num(10),
+			weightUnit: "kg", dimensionUnit: "mm",
+			class: null.StringFrom("bulky"),
+			want: domain.Shipping{
+				Weight:        num(1.2),
+				WeightUnit:    "kg",
+				Length:        num(30),
+				Width:         num(20),
+				Height:        num(10),
+				DimensionUnit: "mm",
+				ShippingClass: null.StringFrom("bulky"),
+			},
+		},
+		{
+			name:  "empty class is unset",
+			class: null.StringFrom(""),
+			want: domain.Shipping{
+				WeightUnit:    domain.DefaultWeightUnit,
+				DimensionUnit: domain.DefaultDimensionUnit,
+			},
+		},
+		{name: "unknown weight unit", weightUnit: "stone", wantErr: true},
+		{name: "unknown dimension unit", dimensionUnit: "ft", wantErr: true},
+		{name: "zero weight", weight: num(0), wantErr: true},
+		{name: "negative dimension", length: num(-1), width: num(1), height: num(1), wantErr: true},
+		{name: "dimensions set partly", length: num(1), width: num(1), height: unset, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := domain.NewShipping(tt.weight, tt.length, tt.width, tt.height, tt.weightUnit, tt.dimensionUnit, tt.class)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidShipping) {
+					t.Fatalf("err = %v, want %v", err, domain.ErrInvalidShipping)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("shipping = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type SuggestionType string
+
+const (
+	SuggestProduct   SuggestionType = "product"
+	SuggestCategory  SuggestionType = "category"
+	SuggestAttribute SuggestionType = "attribute"
+)
+
+const (
+	DefaultSuggestLimit = 8
+	MaxSuggestLimit     = 20
+	// longer input is cut, typeahead does not need more
+	maxSuggestLength = 100
+)
+
+var ErrInvalidSuggestLimit = errors.New("limit must be between 1 and 20")
+
+// SuggestOptions bound the cost of a suggestion query. Threshold is the
+// minimum trigram word similarity of a fuzzy match, between 0 and 1.
+type SuggestOptions struct {
+	Timeout   time.Duration
+	Threshold float64
+}
+
+// Suggestion is a product name, category name or attribute value matching
+// what the user typed. For attribute value ID is the attribute.
+type Suggestion struct {
+	Type          SuggestionType
+	ID            ulid.ULID
+	Text          string
+	AttributeName string
+	Score         float64
+}
+
+type SuggestionDTO struct {
+	Type      SuggestionType `json:"type"`
+	ID        ulid.ULID      `json:"id"`
+	Text      string         `json:"text"`
+	Attribute string         `json:"attribute,omitempty"`
+}
+
+func NewSuggestionDTO(s Suggestion) SuggestionDTO {
+	return SuggestionDTO{
+		Type:      s.Type,
+		ID:        s.ID,
+		Text:      s.Text,
+		Attribute: s.AttributeName,
+	}
+}
+
+// NormalizeSuggest lowercase the input and collapse its whitespace.
+func NormalizeSuggest(q string) (string, error) {
+	q = strings.Join(strings.Fields(strings.ToLower(q)), " ")
+	if q == "" {
+		return "", ErrEmptySearch
+	}
+	if runes := []rune(q); len(runes) > maxSuggestLength {
+		q = string(runes[:maxSuggestLength])
+	}
+	return q, nil
+}
+
+// SuggestPatterns return the LIKE patterns matching text that start with q
+// and text having a word that start with q.
+func SuggestPatterns(q string) (prefix, wordPrefix string) {
+	escaped := likeEscaper.Replace(q)
+	return escaped + "%", "% " + escaped + "%"
+}
@@ -0,0 +1,57 @@
+package domain_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSuggest(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       string
+		want    string
+		wantErr error
+	}{
+		{name: "lowercased", q: "Red Shoe", want: "red shoe"},
+		{name: "whitespace collapsed", q: "  red \t shoe\n", want: "red shoe"},
+		{name: "empty", q: "", wantErr: domain.ErrEmptySearch},
+		{name: "only whitespace", q: " \t ", wantErr: domain.ErrEmptySearch},
+		{name: "long input cut", q: strings.Repeat("é", 150), want: strings.Repeat("é", 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := domain.NormalizeSuggest(tt.q)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeSuggest(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestPatterns(t *testing.T) {
+	tests := []struct {
+		name           string
+		q              string
+		wantPrefix     string
+		wantWordPrefix string
+	}{
+		{name: "plain", q: "red", wantPrefix: "red%", wantWordPrefix: "% red%"},
+		{name: "several words", q: "red sh", wantPrefix: "red sh%", wantWordPrefix: "% red sh%"},
+		{name: "percent escaped", q: "50%", wantPrefix: `50\%%`, wantWordPrefix: `% 50\%%`},
+		{name: "underscore escaped", q: "a_b", wantPrefix: `a\_b%`, wantWordPrefix: `% a\_b%`},
+		{name: "backslash escaped", q: `a\b`, wantPrefix: `a\\b%`, wantWordPrefix: `% a\\b%`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, wordPrefix := domain.SuggestPatterns(tt.q)
+			if prefix != tt.wantPrefix || wordPrefix != tt.wantWordPrefix {
+				t.Errorf("SuggestPatterns(%q) = %q, %q, want %q, %q", tt.q, prefix, wordPrefix, tt.wantPrefix, tt.wantWordPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+var ErrEmptyTag = errors.New("tag name can not be empty")
+
+type Tag struct {
+	TagID     ulid.ULID
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt null.Time
+	DeletedAt null.Time
+}
+
+type TagsDTO struct {
+	ID   ulid.ULID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// TagFilter narrow a listing to items that have any, or all when
+// MatchAll is set, of the given tags.
+type TagFilter struct {
+	Tags     []string
+	MatchAll bool
+}
+
+// NormalizeTag lowercase the name and collapse its whitespace, so "Eco ",
+// "eco" and "ECO" are the same tag.
+func NormalizeTag(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+func NewTag(name string) (Tag, error) {
+	name = NormalizeTag(name)
+	if name == "" {
+		return Tag{}, ErrEmptyTag
+	}
+	id := ulid.Make()
+	return Tag{
+		TagID:     id,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// NewTagFilter build filter from query values, each value may hold
+// several comma separated tags. match "all" require every tag.
+func NewTagFilter(values []string, match string) TagFilter {
+	tags := make([]string, 0, len(values))
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = NormalizeTag(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+	return TagFilter{
+		Tags:     tags,
+		MatchAll: strings.EqualFold(match, "all"),
+	}
+}
@@ -0,0 +1,71 @@
+package domain_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"testing"
+)
+
+func TestNewTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "lowercased", raw: "Eco", want: "eco"},
+		{name: "whitespace collapsed", raw: "  Hand \t Made ", want: "hand made"},
+		{name: "empty", raw: "", wantErr: true},
+		{name: "only whitespace", raw: " \n ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := domain.NewTag(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrEmptyTag) {
+					t.Fatalf("err = %v, want %v", err, domain.ErrEmptyTag)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("name = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTagFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		match    string
+		want     []string
+		matchAll bool
+	}{
+		{name: "none", values: nil, want: []string{}},
+		{name: "comma separated", values: []string{"red, Blue"}, want: []string{"red", "blue"}},
+		{name: "repeated and duplicated", values: []string{"red", "RED", "blue,red"}, want: []string{"red", "blue"}},
+		{name: "empty entries skipped", values: []string{",, ,green,"}, want: []string{"green"}},
+		{name: "match all", values: []string{"red"}, match: "ALL", want: []string{"red"}, matchAll: true},
+		{name: "unknown match is any", values: []string{"red"}, match: "every", want: []string{"red"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := domain.NewTagFilter(tt.values, tt.match)
+			if len(got.Tags) != len(tt.want) {
+				t.Fatalf("tags = %q, want %q", got.Tags, tt.want)
+			}
+			for i := range tt.want {
+				if got.Tags[i] != tt.want[i] {
+					t.Fatalf("tags = %q, want %q", got.Tags, tt.want)
+				}
+			}
+			if got.MatchAll != tt.matchAll {
+				t.Errorf("match all = %v, want %v", got.MatchAll, tt.matchAll)
+			}
+		})
+	}
+}
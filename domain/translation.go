@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+type EntityType string
+
+const (
+	EntityProduct  EntityType = "product"
+	EntityVariant  EntityType = "variant"
+	EntityCategory EntityType = "category"
+)
+
+var (
+	ErrInvalidLocale     = errors.New("locale must be a language tag like en or en-us")
+	ErrInvalidEntityType = errors.New("entity type must be one of product, variant or category")
+	ErrTranslationEntity = errors.New("translated entity does not exist")
+)
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+func (t EntityType) Valid() bool {
+	switch t {
+	case EntityProduct, EntityVariant, EntityCategory:
+		return true
+	}
+	return false
+}
+
+// Translation hold name and description of an entity in one locale,
+// base row of the entity is the content of default locale.
+type Translation struct {
+	TranslationID ulid.ULID
+	EntityType    EntityType
+	EntityID      ulid.ULID
+	Locale        string
+	Name          string
+	Description   string
+	CreatedAt     time.Time
+	UpdatedAt     null.Time
+	DeletedAt     null.Time
+}
+
+type TranslationDTO struct {
+	Locale      string `json:"locale"`
+	Name        string `json:"name"`
+	Description string `json:"desc"`
+}
+
+// NormalizeLocale lowercase the tag and use "-" as separator, "en_US"
+// become "en-us".
+func NormalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(locale)), "_", "-")
+}
+
+func NewTranslation(entityType EntityType, entityId ulid.ULID, locale, name, desc string) (Translation, error) {
+	if !entityType.Valid() {
+		return Translation{}, ErrInvalidEntityType
+	}
+	locale = NormalizeLocale(locale)
+	if !localePattern.MatchString(locale) {
+		return Translation{}, ErrInvalidLocale
+	}
+	id := ulid.Make()
+	return Translation{
+		TranslationID: id,
+		EntityType:    entityType,
+		EntityID:      entityId,
+		Locale:        locale,
+		Name:          name,
+		Description:   desc,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// Apply overwrite name and description with the translated one, empty
+// translated value keep the default content.
+func (t Translation) Apply(name, desc *string) {
+	if t.Name != "" {
+		*name = t.Name
+	}
+	if t.Description != "" {
+		*desc = t.Description
+	}
+}
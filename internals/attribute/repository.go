@@ -4,6 +4,7 @@ import (
 	"context"
 	"flukis/product/domain"
 	"flukis/product/utils/helper"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -18,11 +19,26 @@ type Repo interface {
 	SaveWithTransaction(ctx context.Context, tx pgx.Tx, attr *domain.Attribute) error
 	EditWithTransaction(ctx context.Context, tx pgx.Tx, attr *domain.Attribute) error
 	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, attr *domain.Attribute) error
-	GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.Attribute, string, error)
+	GetByCursor(ctx context.Context, req domain.PageRequest) ([]domain.Attribute, domain.Page, error)
+	GetValuesByAttributeID(ctx context.Context, id ulid.ULID) ([]domain.AttributeValue, error)
+	GetValuesByAttributeIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) ([]domain.AttributeValue, error)
+	GetUsedValuesWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) ([]string, error)
+	SaveValueWithTransaction(ctx context.Context, tx pgx.Tx, val *domain.AttributeValue) error
+	DeleteValueWithTransaction(ctx context.Context, tx pgx.Tx, attributeId ulid.ULID, value string) error
+	GetByProductID(ctx context.Context, id ulid.ULID) ([]domain.AssignedAttribute, error)
+	GetByProductIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) ([]domain.AssignedAttribute, error)
+	GetByProductIDs(ctx context.Context, ids []ulid.ULID) (map[ulid.ULID][]domain.AssignedAttribute, error)
+	SaveProductValueWithTransaction(ctx context.Context, tx pgx.Tx, productId ulid.ULID, attr *domain.AssignedAttribute) error
+	DeleteProductValueWithTransaction(ctx context.Context, tx pgx.Tx, productId, attributeId ulid.ULID) error
+	GetByVariantID(ctx context.Context, id ulid.ULID) ([]domain.AssignedAttribute, error)
+	GetByVariantIDs(ctx context.Context, ids []ulid.ULID) (map[ulid.ULID][]domain.AssignedAttribute, error)
+	SaveVariantValueWithTransaction(ctx context.Context, tx pgx.Tx, variantId ulid.ULID, attr *domain.AssignedAttribute) error
+	DeleteVariantValueWithTransaction(ctx context.Context, tx pgx.Tx, variantId, attributeId ulid.ULID) error
 }
 
 type repo struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	cursors *helper.Cursors
 }
 
 // GetByID implements Repo.
@@ -30,7 +46,12 @@ func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID
 	query := `
 		SELECT
 			attribute_id,
-			name
+			name,
+			data_type,
+			unit,
+			min_value,
+			max_value,
+			max_length
 		FROM
 			Attribute
 		WHERE
@@ -45,6 +66,11 @@ func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID
 	if err := row.Scan(
 		&attr.AttributeID,
 		&attr.Name,
+		&attr.Type,
+		&attr.Unit,
+		&attr.Min,
+		&attr.Max,
+		&attr.MaxLength,
 	); err != nil {
 		return nil, err
 	}
@@ -56,7 +82,12 @@ func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Attribute, er
 	query := `
 		SELECT
 			attribute_id,
-			name
+			name,
+			data_type,
+			unit,
+			min_value,
+			max_value,
+			max_length
 		FROM
 			Attribute
 		WHERE
@@ -71,6 +102,11 @@ func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Attribute, er
 	if err := row.Scan(
 		&attr.AttributeID,
 		&attr.Name,
+		&attr.Type,
+		&attr.Unit,
+		&attr.Min,
+		&attr.Max,
+		&attr.MaxLength,
 	); err != nil {
 		return nil, err
 	}
@@ -80,15 +116,20 @@ func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Attribute, er
 func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, attr *domain.Attribute) error {
 	query := `
 		INSERT INTO Attribute
-			(attribute_id, name, created_at)
+			(attribute_id, name, data_type, unit, min_value, max_value, max_length, created_at)
 		VALUES
-			($1, $2, $3)
+			($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	if _, err := tx.Exec(
 		ctx,
 		query,
 		&attr.AttributeID,
 		&attr.Name,
+		&attr.Type,
+		&attr.Unit,
+		&attr.Min,
+		&attr.Max,
+		&attr.MaxLength,
 		&attr.CreatedAt,
 	); err != nil {
 		return err
@@ -100,7 +141,12 @@ func (*repo) EditWithTransaction(ctx context.Context, tx pgx.Tx, attr *domain.At
 	query := `
 		UPDATE Attribute SET
 			name = $1,
-			updated_at = $2
+			updated_at = $2,
+			data_type = $4,
+			unit = $5,
+			min_value = $6,
+			max_value = $7,
+			max_length = $8
 		WHERE
 			attribute_id = $3 AND deleted_at IS NULL
 	`
@@ -111,6 +157,11 @@ func (*repo) EditWithTransaction(ctx context.Context, tx pgx.Tx, attr *domain.At
 		&attr.Name,
 		currentTime,
 		&attr.AttributeID,
+		&attr.Type,
+		&attr.Unit,
+		&attr.Min,
+		&attr.Max,
+		&attr.MaxLength,
 	); err != nil {
 		return err
 	}
@@ -136,47 +187,507 @@ func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, attr *domain.
 	return nil
 }
 
-func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.Attribute, string, error) {
-	query := `
+// listScope bind the cursors of GetByCursor to this listing, they are
+// refused by the other listings.
+const listScope = "attribute"
+
+// GetByCursor implements Repo. Oldest first, page after or before cursor,
+// attributes created at the same time are ordered by id.
+func (r *repo) GetByCursor(ctx context.Context, req domain.PageRequest) ([]domain.Attribute, domain.Page, error) {
+	decodedCursor, after, err := r.cursors.DecodeSort(req.Cursor, domain.CreatedSort, listScope)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	compare, direction := domain.CreatedSort.Keyset(decodedCursor)
+	query := fmt.Sprintf(`
 		SELECT
-			attribute_id, name, created_at FROM Attribute
+			attribute_id, name, data_type, unit, min_value, max_value, max_length, created_at FROM Attribute
 		WHERE
-			created_at > $1 AND deleted_at IS NULL
+			($2::timestamp IS NULL OR (created_at, attribute_id) %s ($2, $3)) AND deleted_at IS NULL
 		ORDER BY
-			created_at
-		LIMIT $2
-	`
-	decodedCursor, err := helper.DecodeCursor(cursor)
-	if err != nil && cursor != "" {
-		log.Warn().Err(err).Msg("failed to decode cursor")
-		return nil, "", err
-	}
+			created_at %s, attribute_id %s
+		LIMIT $1 OFFSET $4
+	`, compare, direction, direction)
 
-	rows, err := r.db.Query(ctx, query, decodedCursor, limit)
+	rows, err := r.db.Query(ctx, query, req.Limit+1, after, decodedCursor.ID, req.Offset())
 	if err != nil {
-		return nil, "", err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
 	var attributes []domain.Attribute
 	for rows.Next() {
 		var attribute domain.Attribute
-		if err := rows.Scan(&attribute.AttributeID, &attribute.Name, &attribute.CreatedAt); err != nil {
-			return nil, "", err
+		if err := rows.Scan(
+			&attribute.AttributeID,
+			&attribute.Name,
+			&attribute.Type,
+			&attribute.Unit,
+			&attribute.Min,
+			&attribute.Max,
+			&attribute.MaxLength,
+			&attribute.CreatedAt,
+		); err != nil {
+			return nil, domain.Page{}, err
 		}
 		attributes = append(attributes, attribute)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
+	}
+
+	var page domain.Page
+	if req.OffsetMode() {
+		attributes, page = helper.OffsetPage(attributes, req.Limit)
+	} else {
+		attributes, page = helper.CursorPage(r.cursors, listScope, attributes, req.Limit, decodedCursor, func(item domain.Attribute) domain.ListCursor {
+			return domain.NewCreatedCursor(item.CreatedAt, item.AttributeID)
+		})
+	}
+	if err := helper.PageTotal(ctx, r.db, req, &page, "SELECT attribute_id FROM Attribute WHERE deleted_at IS NULL"); err != nil {
+		return nil, domain.Page{}, err
+	}
+	return attributes, page, nil
+}
+
+// GetValuesByAttributeID implements Repo.
+func (r *repo) GetValuesByAttributeID(ctx context.Context, id ulid.ULID) ([]domain.AttributeValue, error) {
+	query := `
+		SELECT
+			attribute_value_id,
+			attribute_id,
+			value,
+			position
+		FROM Attribute_Value
+		WHERE attribute_id = $1
+			AND deleted_at IS NULL
+		ORDER BY
+			position, value
+	`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []domain.AttributeValue
+	for rows.Next() {
+		var val domain.AttributeValue
+		if err := rows.Scan(
+			&val.AttributeValueID,
+			&val.AttributeID,
+			&val.Value,
+			&val.Position,
+		); err != nil {
+			return nil, err
+		}
+		values = append(values, val)
+	}
+	return values, rows.Err()
+}
+
+// GetValuesByAttributeIDWithTransaction implements Repo.
+func (*repo) GetValuesByAttributeIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) ([]domain.AttributeValue, error) {
+	query := `
+		SELECT
+			attribute_value_id,
+			attribute_id,
+			value,
+			position
+		FROM Attribute_Value
+		WHERE attribute_id = $1
+			AND deleted_at IS NULL
+		ORDER BY
+			position, value
+	`
+	rows, err := tx.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []domain.AttributeValue
+	for rows.Next() {
+		var val domain.AttributeValue
+		if err := rows.Scan(
+			&val.AttributeValueID,
+			&val.AttributeID,
+			&val.Value,
+			&val.Position,
+		); err != nil {
+			return nil, err
+		}
+		values = append(values, val)
+	}
+	return values, rows.Err()
+}
+
+// GetUsedValuesWithTransaction implements Repo. It return the distinct
+// value products and variants have for the attribute.
+func (*repo) GetUsedValuesWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) ([]string, error) {
+	query := `
+		SELECT value
+		FROM Product_Attribute
+		WHERE attribute_id = $1 AND deleted_at IS NULL
+		UNION
+		SELECT value
+		FROM Variant_Attribute
+		WHERE attribute_id = $1 AND deleted_at IS NULL
+		ORDER BY
+			value
+	`
+	rows, err := tx.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// SaveValueWithTransaction implements Repo. Existing value only get its
+// position updated.
+func (*repo) SaveValueWithTransaction(ctx context.Context, tx pgx.Tx, val *domain.AttributeValue) error {
+	query := `
+		INSERT INTO Attribute_Value
+			(attribute_value_id, attribute_id, value, position, created_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+		ON CONFLICT (attribute_id, value) DO UPDATE SET
+			position = EXCLUDED.position,
+			deleted_at = NULL
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&val.AttributeValueID,
+		&val.AttributeID,
+		&val.Value,
+		&val.Position,
+		&val.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteValueWithTransaction(ctx context.Context, tx pgx.Tx, attributeId ulid.ULID, value string) error {
+	query := `
+		UPDATE Attribute_Value SET
+			deleted_at = $1
+		WHERE
+			attribute_id = $2 AND value = $3 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		attributeId,
+		domain.NormalizeAttributeValue(value),
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetByProductID implements Repo.
+func (r *repo) GetByProductID(ctx context.Context, id ulid.ULID) ([]domain.AssignedAttribute, error) {
+	query := `
+		SELECT
+			a.attribute_id,
+			a.name,
+			a.data_type,
+			a.unit,
+			pa.value
+		FROM Product_Attribute pa
+		JOIN Attribute a ON pa.attribute_id = a.attribute_id
+		WHERE pa.product_id = $1
+			AND pa.deleted_at IS NULL
+			AND a.deleted_at IS NULL
+		ORDER BY
+			a.name
+	`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attributes []domain.AssignedAttribute
+	for rows.Next() {
+		var attr domain.AssignedAttribute
+		if err := rows.Scan(
+			&attr.Attribute.AttributeID,
+			&attr.Attribute.Name,
+			&attr.Attribute.Type,
+			&attr.Attribute.Unit,
+			&attr.Value,
+		); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attr)
+	}
+	return attributes, rows.Err()
+}
+
+// GetByProductIDWithTransaction implements Repo.
+func (*repo) GetByProductIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) ([]domain.AssignedAttribute, error) {
+	query := `
+		SELECT
+			a.attribute_id,
+			a.name,
+			a.data_type,
+			a.unit,
+			pa.value
+		FROM Product_Attribute pa
+		JOIN Attribute a ON pa.attribute_id = a.attribute_id
+		WHERE pa.product_id = $1
+			AND pa.deleted_at IS NULL
+			AND a.deleted_at IS NULL
+		ORDER BY
+			a.name
+	`
+	rows, err := tx.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	nextCursor := ""
-	if len(attributes) == limit {
-		nextCursor = helper.EncodeCursor(attributes[len(attributes)-1].CreatedAt)
+	var attributes []domain.AssignedAttribute
+	for rows.Next() {
+		var attr domain.AssignedAttribute
+		if err := rows.Scan(
+			&attr.Attribute.AttributeID,
+			&attr.Attribute.Name,
+			&attr.Attribute.Type,
+			&attr.Attribute.Unit,
+			&attr.Value,
+		); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attr)
 	}
+	return attributes, rows.Err()
+}
+
+// GetByProductIDs implements Repo. It return the values of every product
+// in one query, keyed by product id.
+func (r *repo) GetByProductIDs(ctx context.Context, ids []ulid.ULID) (map[ulid.ULID][]domain.AssignedAttribute, error) {
+	query := `
+		SELECT
+			pa.product_id,
+			a.attribute_id,
+			a.name,
+			a.data_type,
+			a.unit,
+			pa.value
+		FROM Product_Attribute pa
+		JOIN Attribute a ON pa.attribute_id = a.attribute_id
+		WHERE pa.product_id = ANY($1)
+			AND pa.deleted_at IS NULL
+			AND a.deleted_at IS NULL
+		ORDER BY
+			a.name
+	`
+	return r.getAssignedByOwner(ctx, query, ids)
+}
 
-	return attributes, nextCursor, nil
+func (*repo) SaveProductValueWithTransaction(ctx context.Context, tx pgx.Tx, productId ulid.ULID, attr *domain.AssignedAttribute) error {
+	query := `
+		INSERT INTO Product_Attribute
+			(product_id, attribute_id, value)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (product_id, attribute_id) DO UPDATE SET
+			value = EXCLUDED.value,
+			updated_at = CURRENT_TIMESTAMP,
+			deleted_at = NULL
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		productId,
+		&attr.Attribute.AttributeID,
+		&attr.Value,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteProductValueWithTransaction(ctx context.Context, tx pgx.Tx, productId, attributeId ulid.ULID) error {
+	query := `
+		UPDATE Product_Attribute SET
+			deleted_at = $1
+		WHERE
+			product_id = $2 AND attribute_id = $3 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		productId,
+		attributeId,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetByVariantID implements Repo.
+func (r *repo) GetByVariantID(ctx context.Context, id ulid.ULID) ([]domain.AssignedAttribute, error) {
+	query := `
+		SELECT
+			a.attribute_id,
+			a.name,
+			a.data_type,
+			a.unit,
+			va.value
+		FROM Variant_Attribute va
+		JOIN Attribute a ON va.attribute_id = a.attribute_id
+		WHERE va.variant_id = $1
+			AND va.deleted_at IS NULL
+			AND a.deleted_at IS NULL
+		ORDER BY
+			a.name
+	`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attributes []domain.AssignedAttribute
+	for rows.Next() {
+		var attr domain.AssignedAttribute
+		if err := rows.Scan(
+			&attr.Attribute.AttributeID,
+			&attr.Attribute.Name,
+			&attr.Attribute.Type,
+			&attr.Attribute.Unit,
+			&attr.Value,
+		); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attr)
+	}
+	return attributes, rows.Err()
+}
+
+// GetByVariantIDs implements Repo. It return the values of every variant
+// in one query, keyed by variant id.
+func (r *repo) GetByVariantIDs(ctx context.Context, ids []ulid.ULID) (map[ulid.ULID][]domain.AssignedAttribute, error) {
+	query := `
+		SELECT
+			va.variant_id,
+			a.attribute_id,
+			a.name,
+			a.data_type,
+			a.unit,
+			va.value
+		FROM Variant_Attribute va
+		JOIN Attribute a ON va.attribute_id = a.attribute_id
+		WHERE va.variant_id = ANY($1)
+			AND va.deleted_at IS NULL
+			AND a.deleted_at IS NULL
+		ORDER BY
+			a.name
+	`
+	return r.getAssignedByOwner(ctx, query, ids)
+}
+
+// getAssignedByOwner run query, selecting the owner id then the assigned
+// value, for the owners ids.
+func (r *repo) getAssignedByOwner(ctx context.Context, query string, ids []ulid.ULID) (map[ulid.ULID][]domain.AssignedAttribute, error) {
+	res := make(map[ulid.ULID][]domain.AssignedAttribute)
+	if len(ids) == 0 {
+		return res, nil
+	}
+	rawIds := make([][]byte, len(ids))
+	for i := range ids {
+		rawIds[i] = ids[i][:]
+	}
+	rows, err := r.db.Query(ctx, query, rawIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ownerId ulid.ULID
+		var attr domain.AssignedAttribute
+		if err := rows.Scan(
+			&ownerId,
+			&attr.Attribute.AttributeID,
+			&attr.Attribute.Name,
+			&attr.Attribute.Type,
+			&attr.Attribute.Unit,
+			&attr.Value,
+		); err != nil {
+			return nil, err
+		}
+		res[ownerId] = append(res[ownerId], attr)
+	}
+	return res, rows.Err()
+}
+
+func (*repo) SaveVariantValueWithTransaction(ctx context.Context, tx pgx.Tx, variantId ulid.ULID, attr *domain.AssignedAttribute) error {
+	query := `
+		INSERT INTO Variant_Attribute
+			(variant_id, attribute_id, value)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (variant_id, attribute_id) DO UPDATE SET
+			value = EXCLUDED.value,
+			updated_at = CURRENT_TIMESTAMP,
+			deleted_at = NULL
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		variantId,
+		&attr.Attribute.AttributeID,
+		&attr.Value,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteVariantValueWithTransaction(ctx context.Context, tx pgx.Tx, variantId, attributeId ulid.ULID) error {
+	query := `
+		UPDATE Variant_Attribute SET
+			deleted_at = $1
+		WHERE
+			variant_id = $2 AND attribute_id = $3 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		variantId,
+		attributeId,
+	); err != nil {
+		return err
+	}
+	return nil
 }
 
-func NewRepo(db *pgxpool.Pool) Repo {
+func NewRepo(db *pgxpool.Pool, cursors *helper.Cursors) Repo {
 	return &repo{
-		db: db,
+		db:      db,
+		cursors: cursors,
 	}
 }
@@ -2,13 +2,15 @@ package attribute
 
 import (
 	"encoding/json"
+	"errors"
+	"flukis/product/domain"
 	"flukis/product/utils/resp"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/oklog/ulid/v2"
 	"github.com/rs/zerolog/log"
+	"gopkg.in/guregu/null.v4"
 )
 
 type Router struct {
@@ -28,6 +30,8 @@ func (r *Router) Routes() *chi.Mux {
 
 	route.Post("/", r.CreateAttributeHandler)
 	route.Patch("/{id}", r.UpdateAttributeHandler)
+	route.Patch("/type/{id}", r.UpdateAttributeTypeHandler)
+	route.Patch("/value/{id}", r.UpdateAttributeValuesHandler)
 	route.Delete("/{id}", r.DeleteAttributeHandler)
 	route.Get("/{id}", r.GetAttributeOneByIDHandler)
 	route.Get("/", r.GetAttributesHandler)
@@ -44,7 +48,13 @@ func (r *Router) CreateAttributeHandler(w http.ResponseWriter, req *http.Request
 	}
 	ctx := req.Context()
 	var input struct {
-		Name string `json:"name"`
+		Name      string               `json:"name"`
+		Type      domain.AttributeType `json:"type"`
+		Unit      null.String          `json:"unit"`
+		Min       null.Float           `json:"min"`
+		Max       null.Float           `json:"max"`
+		MaxLength null.Int             `json:"max_length"`
+		Values    []string             `json:"values"`
 	}
 	decoder := json.NewDecoder(req.Body)
 	decoder.DisallowUnknownFields()
@@ -55,9 +65,9 @@ func (r *Router) CreateAttributeHandler(w http.ResponseWriter, req *http.Request
 		}
 		return
 	}
-	res, err := r.service.CreateAttr(ctx, input.Name)
+	res, err := r.service.CreateAttr(ctx, input.Name, input.Type, input.Unit, input.Min, input.Max, input.MaxLength, input.Values)
 	if err != nil {
-		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
 			log.Error().Err(err)
 			return
 		}
@@ -161,8 +171,7 @@ func (r *Router) GetAttributeOneByIDHandler(w http.ResponseWriter, req *http.Req
 
 func (r *Router) GetAttributesHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
-	limitStr := req.URL.Query().Get("limit")
-	limitInt, err := strconv.Atoi(limitStr)
+	pageReq, err := domain.NewPageRequest(req.URL.Query())
 	if err != nil {
 		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
 			log.Error().Err(err)
@@ -170,28 +179,115 @@ func (r *Router) GetAttributesHandler(w http.ResponseWriter, req *http.Request)
 		}
 		return
 	}
-	cursor := req.URL.Query().Get("cursor")
-	res, length, next, err := r.service.GetAttrByCursor(ctx, limitInt, cursor)
+	res, length, page, err := r.service.GetAttrByCursor(ctx, pageReq)
 	if err != nil {
-		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+		if err = resp.WriteError(w, listErrorStatus(err), err); err != nil {
 			log.Error().Err(err)
 			return
 		}
 		return
 	}
 
-	var metaResp struct {
-		Limit    int    `json:"limit"`
-		ThisPage int    `json:"total_this_page"`
-		Next     string `json:"next_cursor"`
+	metaResp := domain.NewPageMeta(pageReq, page, length)
+
+	if err = resp.WriteResponse(w, "get all attribute success", http.StatusOK, res, metaResp); err != nil {
+		log.Error().Err(err)
+		return
 	}
+}
 
-	metaResp.Limit = limitInt
-	metaResp.Next = next
-	metaResp.ThisPage = length
+func (r *Router) UpdateAttributeTypeHandler(w http.ResponseWriter, req *http.Request) {
+	attributeId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(attributeId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Type      domain.AttributeType `json:"type"`
+		Unit      null.String          `json:"unit"`
+		Min       null.Float           `json:"min"`
+		Max       null.Float           `json:"max"`
+		MaxLength null.Int             `json:"max_length"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.UpdateTypeAttr(ctx, id, input.Type, input.Unit, input.Min, input.Max, input.MaxLength)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "update attribute type success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
 
-	if err = resp.WriteResponse(w, "get all attribute success", http.StatusOK, res, metaResp); err != nil {
+func (r *Router) UpdateAttributeValuesHandler(w http.ResponseWriter, req *http.Request) {
+	attributeId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(attributeId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Added   []string `json:"added"`
+		Removed []string `json:"removed"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.UpdateValuesAttr(ctx, id, input.Added, input.Removed)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "update attribute values success", http.StatusOK, res, nil); err != nil {
 		log.Error().Err(err)
 		return
 	}
 }
+
+// errorStatus map invalid attribute definition to bad request, and a
+// change conflicting with the value in use to conflict.
+func errorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidAttributeType) || errors.Is(err, domain.ErrInvalidAttribute) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, domain.ErrAttributeInUse) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func listErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -2,18 +2,24 @@ package attribute
 
 import (
 	"context"
+	"errors"
 	"flukis/product/domain"
+	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
 )
 
 type Service interface {
 	GetAttrById(ctx context.Context, id ulid.ULID) (domain.AttributesDTO, error)
-	GetAttrByCursor(ctx context.Context, limit int, cursor string) ([]domain.AttributesDTO, int, string, error)
+	GetAttrByCursor(ctx context.Context, req domain.PageRequest) ([]domain.AttributesDTO, int, domain.Page, error)
 	DeleteAttr(ctx context.Context, id ulid.ULID) error
 	UpdateNameAttr(ctx context.Context, id ulid.ULID, name string) (domain.AttributesDTO, error)
-	CreateAttr(ctx context.Context, name string) (domain.AttributesDTO, error)
+	CreateAttr(ctx context.Context, name string, attrType domain.AttributeType, unit null.String, min, max null.Float, maxLength null.Int, values []string) (domain.AttributesDTO, error)
+	UpdateTypeAttr(ctx context.Context, id ulid.ULID, attrType domain.AttributeType, unit null.String, min, max null.Float, maxLength null.Int) (domain.AttributesDTO, error)
+	UpdateValuesAttr(ctx context.Context, id ulid.ULID, added, removed []string) (domain.AttributesDTO, error)
 }
 
 type service struct {
@@ -22,11 +28,21 @@ type service struct {
 }
 
 // CreateAttr implements Service.
-func (s *service) CreateAttr(ctx context.Context, name string) (domain.AttributesDTO, error) {
-	newAttr, err := domain.NewAttribute(name)
+func (s *service) CreateAttr(ctx context.Context, name string, attrType domain.AttributeType, unit null.String, min, max null.Float, maxLength null.Int, values []string) (domain.AttributesDTO, error) {
+	newAttr, err := domain.NewAttribute(name, attrType, unit, min, max, maxLength)
 	if err != nil {
 		return domain.AttributesDTO{}, err
 	}
+	if len(values) > 0 && newAttr.Type != domain.AttributeEnum {
+		return domain.AttributesDTO{}, fmt.Errorf("%w: only enum attribute have allowed values", domain.ErrInvalidAttribute)
+	}
+	for idx := range values {
+		val, err := domain.NewAttributeValue(newAttr.AttributeID, values[idx], idx)
+		if err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		newAttr.Values = append(newAttr.Values, val)
+	}
 
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
@@ -40,12 +56,18 @@ func (s *service) CreateAttr(ctx context.Context, name string) (domain.Attribute
 		}
 		return domain.AttributesDTO{}, err
 	}
-
-	res := domain.AttributesDTO{
-		ID:   newAttr.AttributeID,
-		Name: newAttr.Name,
+	for idx := range newAttr.Values {
+		err = s.repo.SaveValueWithTransaction(ctx, tx, &newAttr.Values[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return domain.AttributesDTO{}, err
+			}
+			return domain.AttributesDTO{}, err
+		}
 	}
 
+	res := domain.NewAttributeDTO(newAttr)
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return domain.AttributesDTO{}, err
@@ -84,21 +106,20 @@ func (s *service) DeleteAttr(ctx context.Context, id ulid.ULID) error {
 }
 
 // GetAttrByCursor implements Service.
-func (s *service) GetAttrByCursor(ctx context.Context, limit int, cursor string) (res []domain.AttributesDTO, length int, nextCursor string, err error) {
-	attr, nextCursor, err := s.repo.GetByCursor(ctx, limit, cursor)
+func (s *service) GetAttrByCursor(ctx context.Context, req domain.PageRequest) (res []domain.AttributesDTO, length int, page domain.Page, err error) {
+	attr, page, err := s.repo.GetByCursor(ctx, req)
 	if err != nil {
-		return []domain.AttributesDTO{}, 0, "", err
+		return []domain.AttributesDTO{}, 0, domain.Page{}, err
 	}
 	dataLen := len(attr)
 	if dataLen == 0 {
-		return []domain.AttributesDTO{}, 0, "", nil
+		return []domain.AttributesDTO{}, 0, page, nil
 	}
 	var data = make([]domain.AttributesDTO, dataLen)
 	for i := range attr {
-		data[i].ID = attr[i].AttributeID
-		data[i].Name = attr[i].Name
+		data[i] = domain.NewAttributeDTO(attr[i])
 	}
-	return data, dataLen, nextCursor, nil
+	return data, dataLen, page, nil
 }
 
 // GetAttrById implements Service.
@@ -107,11 +128,11 @@ func (s *service) GetAttrById(ctx context.Context, id ulid.ULID) (domain.Attribu
 	if err != nil {
 		return domain.AttributesDTO{}, err
 	}
-	res := domain.AttributesDTO{
-		ID:   attr.AttributeID,
-		Name: attr.Name,
+	attr.Values, err = s.repo.GetValuesByAttributeID(ctx, id)
+	if err != nil {
+		return domain.AttributesDTO{}, err
 	}
-	return res, nil
+	return domain.NewAttributeDTO(*attr), nil
 }
 
 // UpdateNameAttr implements Service.
@@ -137,11 +158,53 @@ func (s *service) UpdateNameAttr(ctx context.Context, id ulid.ULID, name string)
 		}
 		return domain.AttributesDTO{}, err
 	}
-	res := domain.AttributesDTO{
-		ID:   currAttr.AttributeID,
-		Name: currAttr.Name,
+	res := domain.NewAttributeDTO(*currAttr)
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.AttributesDTO{}, err
+	}
+	return res, nil
+}
+
+// UpdateTypeAttr implements Service.
+func (s *service) UpdateTypeAttr(ctx context.Context, id ulid.ULID, attrType domain.AttributeType, unit null.String, min, max null.Float, maxLength null.Int) (domain.AttributesDTO, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.AttributesDTO{}, err
 	}
 
+	currAttr, err := s.repo.GetByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		return domain.AttributesDTO{}, err
+	}
+	err = currAttr.SetType(attrType, unit, min, max, maxLength)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		return domain.AttributesDTO{}, err
+	}
+	err = s.checkUsedValues(ctx, tx, currAttr)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		return domain.AttributesDTO{}, err
+	}
+
+	err = s.repo.EditWithTransaction(ctx, tx, currAttr)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		return domain.AttributesDTO{}, err
+	}
+	res := domain.NewAttributeDTO(*currAttr)
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return domain.AttributesDTO{}, err
@@ -149,6 +212,124 @@ func (s *service) UpdateNameAttr(ctx context.Context, id ulid.ULID, name string)
 	return res, nil
 }
 
+// UpdateValuesAttr implements Service. Added value is appended after the
+// existing one, re-adding a value move it to the end.
+func (s *service) UpdateValuesAttr(ctx context.Context, id ulid.ULID, added, removed []string) (domain.AttributesDTO, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.AttributesDTO{}, err
+	}
+
+	currAttr, err := s.repo.GetByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		return domain.AttributesDTO{}, err
+	}
+	if currAttr.Type != domain.AttributeEnum {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		return domain.AttributesDTO{}, fmt.Errorf("%w: only enum attribute have allowed values", domain.ErrInvalidAttribute)
+	}
+	currValues, err := s.repo.GetValuesByAttributeIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		return domain.AttributesDTO{}, err
+	}
+	position := 0
+	for idx := range currValues {
+		if currValues[idx].Position >= position {
+			position = currValues[idx].Position + 1
+		}
+	}
+
+	for idx := range removed {
+		err = s.repo.DeleteValueWithTransaction(ctx, tx, id, removed[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return domain.AttributesDTO{}, err
+			}
+			return domain.AttributesDTO{}, err
+		}
+	}
+	for idx := range added {
+		val, err := domain.NewAttributeValue(id, added[idx], position+idx)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return domain.AttributesDTO{}, err
+			}
+			return domain.AttributesDTO{}, err
+		}
+		err = s.repo.SaveValueWithTransaction(ctx, tx, &val)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return domain.AttributesDTO{}, err
+			}
+			return domain.AttributesDTO{}, err
+		}
+	}
+	err = s.checkUsedValues(ctx, tx, currAttr)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.AttributesDTO{}, err
+		}
+		return domain.AttributesDTO{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.AttributesDTO{}, err
+	}
+	return s.GetAttrById(ctx, id)
+}
+
+// checkUsedValues reject the change of attr when a product or variant
+// have a value the changed attribute does not allow.
+func (s *service) checkUsedValues(ctx context.Context, tx pgx.Tx, attr *domain.Attribute) error {
+	if attr.Type == domain.AttributeEnum {
+		values, err := s.repo.GetValuesByAttributeIDWithTransaction(ctx, tx, attr.AttributeID)
+		if err != nil {
+			return err
+		}
+		attr.Values = values
+	}
+	used, err := s.repo.GetUsedValuesWithTransaction(ctx, tx, attr.AttributeID)
+	if err != nil {
+		return err
+	}
+	return attr.CheckUsedValues(used)
+}
+
+// AssignValue validate raw against the type of attribute id, it is used by
+// product and variant to set their attribute value.
+func AssignValue(ctx context.Context, repo Repo, id ulid.ULID, raw string) (domain.AssignedAttribute, error) {
+	attr, err := repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.AssignedAttribute{}, fmt.Errorf("%w: attribute %s not found", domain.ErrInvalidAttributeValue, id)
+		}
+		return domain.AssignedAttribute{}, err
+	}
+	if attr.Type == domain.AttributeEnum {
+		attr.Values, err = repo.GetValuesByAttributeID(ctx, id)
+		if err != nil {
+			return domain.AssignedAttribute{}, err
+		}
+	}
+	value, err := attr.NormalizeValue(raw)
+	if err != nil {
+		return domain.AssignedAttribute{}, err
+	}
+	return domain.AssignedAttribute{
+		Attribute: *attr,
+		Value:     value,
+	}, nil
+}
+
 func NewService(
 	repo Repo,
 	db *pgxpool.Pool,
@@ -0,0 +1,276 @@
+package brand
+
+import (
+	"context"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type Repo interface {
+	GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) (*domain.Brand, error)
+	GetByID(ctx context.Context, id ulid.ULID) (*domain.Brand, error)
+	SaveWithTransaction(ctx context.Context, tx pgx.Tx, brand *domain.Brand) error
+	EditWithTransaction(ctx context.Context, tx pgx.Tx, brand *domain.Brand) error
+	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, brand *domain.Brand) error
+	GetByCursor(ctx context.Context, req domain.PageRequest) ([]domain.Brand, domain.Page, error)
+	GetProductsByCursor(ctx context.Context, id ulid.ULID, req domain.PageRequest) ([]domain.Product, domain.Page, error)
+}
+
+type repo struct {
+	db      *pgxpool.Pool
+	cursors *helper.Cursors
+}
+
+// GetByID implements Repo.
+func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) (*domain.Brand, error) {
+	query := `
+		SELECT
+			brand_id,
+			name
+		FROM
+			Brand
+		WHERE
+			brand_id = $1 AND deleted_at IS NULL
+	`
+	row := tx.QueryRow(
+		ctx,
+		query,
+		id,
+	)
+	var brand domain.Brand
+	if err := row.Scan(
+		&brand.BrandID,
+		&brand.Name,
+	); err != nil {
+		return nil, err
+	}
+	return &brand, nil
+}
+
+// GetByID implements Repo.
+func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Brand, error) {
+	query := `
+		SELECT
+			brand_id,
+			name,
+			description
+		FROM
+			Brand
+		WHERE
+			brand_id = $1  AND deleted_at IS NULL
+	`
+	row := r.db.QueryRow(
+		ctx,
+		query,
+		id,
+	)
+	var brand domain.Brand
+	if err := row.Scan(
+		&brand.BrandID,
+		&brand.Name,
+		&brand.Description,
+	); err != nil {
+		return nil, err
+	}
+	return &brand, nil
+}
+
+func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, brand *domain.Brand) error {
+	query := `
+		INSERT INTO Brand
+			(brand_id, name, description, created_at)
+		VALUES
+			($1, $2, $3, $4)
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&brand.BrandID,
+		&brand.Name,
+		&brand.Description,
+		&brand.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) EditWithTransaction(ctx context.Context, tx pgx.Tx, brand *domain.Brand) error {
+	query := `
+		UPDATE Brand SET
+			name = $1,
+			updated_at = $2,
+			description = $4
+		WHERE
+			brand_id = $3 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&brand.Name,
+		currentTime,
+		&brand.BrandID,
+		&brand.Description,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, brand *domain.Brand) error {
+	query := `
+		UPDATE Brand SET
+			deleted_at = $1
+		WHERE
+			brand_id = $2 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		&brand.BrandID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// listScope bind the cursors of GetByCursor to this listing, they are
+// refused by the other listings.
+const listScope = "brand"
+
+// GetByCursor implements Repo. Oldest first, page after or before cursor,
+// brands created at the same time are ordered by id.
+func (r *repo) GetByCursor(ctx context.Context, req domain.PageRequest) ([]domain.Brand, domain.Page, error) {
+	decodedCursor, after, err := r.cursors.DecodeSort(req.Cursor, domain.CreatedSort, listScope)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	compare, direction := domain.CreatedSort.Keyset(decodedCursor)
+	query := fmt.Sprintf(`
+		SELECT
+			brand_id, name, description, created_at FROM Brand
+		WHERE
+			($2::timestamp IS NULL OR (created_at, brand_id) %s ($2, $3)) AND deleted_at IS NULL
+		ORDER BY
+			created_at %s, brand_id %s
+		LIMIT $1 OFFSET $4
+	`, compare, direction, direction)
+
+	rows, err := r.db.Query(ctx, query, req.Limit+1, after, decodedCursor.ID, req.Offset())
+	if err != nil {
+		return nil, domain.Page{}, err
+	}
+	defer rows.Close()
+
+	var brands []domain.Brand
+	for rows.Next() {
+		var brand domain.Brand
+		if err := rows.Scan(&brand.BrandID, &brand.Name, &brand.Description, &brand.CreatedAt); err != nil {
+			return nil, domain.Page{}, err
+		}
+		brands = append(brands, brand)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
+	}
+
+	var page domain.Page
+	if req.OffsetMode() {
+		brands, page = helper.OffsetPage(brands, req.Limit)
+	} else {
+		brands, page = helper.CursorPage(r.cursors, listScope, brands, req.Limit, decodedCursor, func(item domain.Brand) domain.ListCursor {
+			return domain.NewCreatedCursor(item.CreatedAt, item.BrandID)
+		})
+	}
+	if err := helper.PageTotal(ctx, r.db, req, &page, "SELECT brand_id FROM Brand WHERE deleted_at IS NULL"); err != nil {
+		return nil, domain.Page{}, err
+	}
+	return brands, page, nil
+}
+
+// GetProductsByCursor implements Repo. Oldest first like GetByCursor.
+func (r *repo) GetProductsByCursor(ctx context.Context, id ulid.ULID, req domain.PageRequest) ([]domain.Product, domain.Page, error) {
+	decodedCursor, after, err := r.cursors.DecodeSort(req.Cursor, domain.CreatedSort, id.String())
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	compare, direction := domain.CreatedSort.Keyset(decodedCursor)
+	query := fmt.Sprintf(`
+		SELECT
+			product_id,
+			name,
+			description,
+			price,
+			image_preview,
+			COALESCE(image_key, ''),
+			created_at
+		FROM
+			Product
+		WHERE
+			brand_id = $1
+			AND ($3::timestamp IS NULL OR (created_at, product_id) %s ($3, $4))
+			AND deleted_at IS NULL
+		ORDER BY
+			created_at %s, product_id %s
+		LIMIT $2 OFFSET $5
+	`, compare, direction, direction)
+
+	rows, err := r.db.Query(ctx, query, id, req.Limit+1, after, decodedCursor.ID, req.Offset())
+	if err != nil {
+		return nil, domain.Page{}, err
+	}
+	defer rows.Close()
+
+	var products []domain.Product
+	for rows.Next() {
+		var product domain.Product
+		if err := rows.Scan(
+			&product.ProductID,
+			&product.Name,
+			&product.Description,
+			&product.Price,
+			&product.ImagePreview,
+			&product.Image.Key,
+			&product.CreatedAt,
+		); err != nil {
+			return nil, domain.Page{}, err
+		}
+		product.Brand.BrandID = id
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
+	}
+
+	var page domain.Page
+	if req.OffsetMode() {
+		products, page = helper.OffsetPage(products, req.Limit)
+	} else {
+		products, page = helper.CursorPage(r.cursors, id.String(), products, req.Limit, decodedCursor, func(item domain.Product) domain.ListCursor {
+			return domain.NewCreatedCursor(item.CreatedAt, item.ProductID)
+		})
+	}
+	if err := helper.PageTotal(ctx, r.db, req, &page, "SELECT product_id FROM Product WHERE brand_id = $1 AND deleted_at IS NULL", id); err != nil {
+		return nil, domain.Page{}, err
+	}
+	return products, page, nil
+}
+
+func NewRepo(db *pgxpool.Pool, cursors *helper.Cursors) Repo {
+	return &repo{
+		db:      db,
+		cursors: cursors,
+	}
+}
@@ -0,0 +1,233 @@
+package brand
+
+import (
+	"encoding/json"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/resp"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type Router struct {
+	service Service
+}
+
+func NewRouter(
+	service Service,
+) *Router {
+	return &Router{
+		service: service,
+	}
+}
+
+func (r *Router) Routes() *chi.Mux {
+	route := chi.NewMux()
+
+	route.Post("/", r.CreateBrandHandler)
+	route.Patch("/{id}", r.UpdateBrandHandler)
+	route.Delete("/{id}", r.DeleteBrandHandler)
+	route.Get("/{id}", r.GetBrandOneByIDHandler)
+	route.Get("/{id}/products", r.GetBrandProductsHandler)
+	route.Get("/", r.GetBrandsHandler)
+
+	return route
+}
+
+func (r *Router) CreateBrandHandler(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Name string `json:"name"`
+		Desc string `json:"desc"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.CreateBrand(ctx, input.Name, input.Desc)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "create brand success", http.StatusCreated, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) UpdateBrandHandler(w http.ResponseWriter, req *http.Request) {
+	brandId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(brandId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Name string `json:"name"`
+		Desc string `json:"desc"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.UpdateBrand(ctx, id, input.Name, input.Desc)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "update brand name success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) DeleteBrandHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	brandId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(brandId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	err = r.service.DeleteBrand(ctx, id)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "delete brand name success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) GetBrandOneByIDHandler(w http.ResponseWriter, req *http.Request) {
+	brandId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(brandId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	res, err := r.service.GetBrandById(ctx, id)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get one brand success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) GetBrandsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	pageReq, err := domain.NewPageRequest(req.URL.Query())
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	res, length, page, err := r.service.GetBrandByCursor(ctx, pageReq)
+	if err != nil {
+		if err = resp.WriteError(w, listErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+
+	metaResp := domain.NewPageMeta(pageReq, page, length)
+
+	if err = resp.WriteResponse(w, "get all brand success", http.StatusOK, res, metaResp); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) GetBrandProductsHandler(w http.ResponseWriter, req *http.Request) {
+	brandId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(brandId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	pageReq, err := domain.NewPageRequest(req.URL.Query())
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	res, length, page, err := r.service.GetProductsByBrand(ctx, id, pageReq)
+	if err != nil {
+		if err = resp.WriteError(w, listErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+
+	metaResp := domain.NewPageMeta(pageReq, page, length)
+
+	if err = resp.WriteResponse(w, "get brand products success", http.StatusOK, res, metaResp); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func listErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,187 @@
+package brand
+
+import (
+	"context"
+	"flukis/product/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+)
+
+type Service interface {
+	GetBrandById(ctx context.Context, id ulid.ULID) (domain.BrandsDTO, error)
+	GetBrandByCursor(ctx context.Context, req domain.PageRequest) ([]domain.BrandsDTO, int, domain.Page, error)
+	DeleteBrand(ctx context.Context, id ulid.ULID) error
+	UpdateBrand(ctx context.Context, id ulid.ULID, name, desc string) (domain.BrandsDTO, error)
+	CreateBrand(ctx context.Context, name, desc string) (domain.BrandsDTO, error)
+	GetProductsByBrand(ctx context.Context, id ulid.ULID, req domain.PageRequest) ([]domain.ProductDTO, int, domain.Page, error)
+}
+
+type service struct {
+	repo Repo
+	db   *pgxpool.Pool
+}
+
+// CreateBrand implements Service.
+func (s *service) CreateBrand(ctx context.Context, name, desc string) (domain.BrandsDTO, error) {
+	newBrand, err := domain.NewBrand(name, desc)
+	if err != nil {
+		return domain.BrandsDTO{}, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.BrandsDTO{}, err
+	}
+
+	err = s.repo.SaveWithTransaction(ctx, tx, &newBrand)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.BrandsDTO{}, err
+		}
+		return domain.BrandsDTO{}, err
+	}
+
+	res := domain.BrandsDTO{
+		ID:          newBrand.BrandID,
+		Name:        newBrand.Name,
+		Description: newBrand.Description,
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.BrandsDTO{}, err
+	}
+	return res, nil
+}
+
+// DeleteBrand implements Service.
+func (s *service) DeleteBrand(ctx context.Context, id ulid.ULID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	currBrand, err := s.repo.GetByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = s.repo.DeleteWithTransaction(ctx, tx, currBrand)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetBrandByCursor implements Service.
+func (s *service) GetBrandByCursor(ctx context.Context, req domain.PageRequest) (res []domain.BrandsDTO, length int, page domain.Page, err error) {
+	brands, page, err := s.repo.GetByCursor(ctx, req)
+	if err != nil {
+		return []domain.BrandsDTO{}, 0, domain.Page{}, err
+	}
+	dataLen := len(brands)
+	if dataLen == 0 {
+		return []domain.BrandsDTO{}, 0, page, nil
+	}
+	var data = make([]domain.BrandsDTO, dataLen)
+	for i := range brands {
+		data[i].ID = brands[i].BrandID
+		data[i].Name = brands[i].Name
+		data[i].Description = brands[i].Description
+	}
+	return data, dataLen, page, nil
+}
+
+// GetBrandById implements Service.
+func (s *service) GetBrandById(ctx context.Context, id ulid.ULID) (domain.BrandsDTO, error) {
+	brand, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.BrandsDTO{}, err
+	}
+	res := domain.BrandsDTO{
+		ID:          brand.BrandID,
+		Name:        brand.Name,
+		Description: brand.Description,
+	}
+	return res, nil
+}
+
+// UpdateBrand implements Service.
+func (s *service) UpdateBrand(ctx context.Context, id ulid.ULID, name, desc string) (domain.BrandsDTO, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.BrandsDTO{}, err
+	}
+
+	currBrand, err := s.repo.GetByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.BrandsDTO{}, err
+		}
+		return domain.BrandsDTO{}, err
+	}
+	currBrand.Name = name
+	currBrand.Description = desc
+
+	err = s.repo.EditWithTransaction(ctx, tx, currBrand)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.BrandsDTO{}, err
+		}
+		return domain.BrandsDTO{}, err
+	}
+	res := domain.BrandsDTO{
+		ID:          currBrand.BrandID,
+		Name:        currBrand.Name,
+		Description: currBrand.Description,
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.BrandsDTO{}, err
+	}
+	return res, nil
+}
+
+// GetProductsByBrand implements Service.
+func (s *service) GetProductsByBrand(ctx context.Context, id ulid.ULID, req domain.PageRequest) (res []domain.ProductDTO, length int, page domain.Page, err error) {
+	prd, page, err := s.repo.GetProductsByCursor(ctx, id, req)
+	if err != nil {
+		return []domain.ProductDTO{}, 0, domain.Page{}, err
+	}
+	dataLen := len(prd)
+	if dataLen == 0 {
+		return []domain.ProductDTO{}, 0, page, nil
+	}
+	var data = make([]domain.ProductDTO, dataLen)
+	for i := range prd {
+		data[i].ID = prd[i].ProductID
+		data[i].Name = prd[i].Name
+		data[i].Description = prd[i].Description
+		data[i].Image = prd[i].ImageURL()
+		data[i].Price = prd[i].Price
+	}
+	return data, dataLen, page, nil
+}
+
+func NewService(
+	repo Repo,
+	db *pgxpool.Pool,
+) Service {
+	return &service{
+		repo: repo,
+		db:   db,
+	}
+}
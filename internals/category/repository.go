@@ -4,6 +4,7 @@ import (
 	"context"
 	"flukis/product/domain"
 	"flukis/product/utils/helper"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -18,11 +19,12 @@ type Repo interface {
 	SaveWithTransaction(ctx context.Context, tx pgx.Tx, cat *domain.Category) error
 	EditWithTransaction(ctx context.Context, tx pgx.Tx, cat *domain.Category) error
 	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, cat *domain.Category) error
-	GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.Category, string, error)
+	GetByCursor(ctx context.Context, req domain.PageRequest) ([]domain.Category, domain.Page, error)
 }
 
 type repo struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	cursors *helper.Cursors
 }
 
 // GetByID implements Repo.
@@ -141,25 +143,32 @@ func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, cat *domain.C
 	return nil
 }
 
-func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.Category, string, error) {
-	query := `
+// listScope bind the cursors of GetByCursor to this listing, they are
+// refused by the other listings.
+const listScope = "category"
+
+// GetByCursor implements Repo. Oldest first, page after or before cursor,
+// categories created at the same time are ordered by id.
+func (r *repo) GetByCursor(ctx context.Context, req domain.PageRequest) ([]domain.Category, domain.Page, error) {
+	decodedCursor, after, err := r.cursors.DecodeSort(req.Cursor, domain.CreatedSort, listScope)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	compare, direction := domain.CreatedSort.Keyset(decodedCursor)
+	query := fmt.Sprintf(`
 		SELECT
 			category_id, name, description, created_at FROM category
 		WHERE
-			created_at > $1 AND deleted_at IS NULL
+			($2::timestamp IS NULL OR (created_at, category_id) %s ($2, $3)) AND deleted_at IS NULL
 		ORDER BY
-			created_at
-		LIMIT $2
-	`
-	decodedCursor, err := helper.DecodeCursor(cursor)
-	if err != nil && cursor != "" {
-		log.Warn().Err(err).Msg("failed to decode cursor")
-		return nil, "", err
-	}
+			created_at %s, category_id %s
+		LIMIT $1 OFFSET $4
+	`, compare, direction, direction)
 
-	rows, err := r.db.Query(ctx, query, decodedCursor, limit)
+	rows, err := r.db.Query(ctx, query, req.Limit+1, after, decodedCursor.ID, req.Offset())
 	if err != nil {
-		return nil, "", err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
@@ -167,21 +176,31 @@ func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]dom
 	for rows.Next() {
 		var category domain.Category
 		if err := rows.Scan(&category.CategoryID, &category.Name, &category.Description, &category.CreatedAt); err != nil {
-			return nil, "", err
+			return nil, domain.Page{}, err
 		}
 		categories = append(categories, category)
 	}
-
-	nextCursor := ""
-	if len(categories) == limit {
-		nextCursor = helper.EncodeCursor(categories[len(categories)-1].CreatedAt)
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
 	}
 
-	return categories, nextCursor, nil
+	var page domain.Page
+	if req.OffsetMode() {
+		categories, page = helper.OffsetPage(categories, req.Limit)
+	} else {
+		categories, page = helper.CursorPage(r.cursors, listScope, categories, req.Limit, decodedCursor, func(item domain.Category) domain.ListCursor {
+			return domain.NewCreatedCursor(item.CreatedAt, item.CategoryID)
+		})
+	}
+	if err := helper.PageTotal(ctx, r.db, req, &page, "SELECT category_id FROM Category WHERE deleted_at IS NULL"); err != nil {
+		return nil, domain.Page{}, err
+	}
+	return categories, page, nil
 }
 
-func NewRepo(db *pgxpool.Pool) Repo {
+func NewRepo(db *pgxpool.Pool, cursors *helper.Cursors) Repo {
 	return &repo{
-		db: db,
+		db:      db,
+		cursors: cursors,
 	}
 }
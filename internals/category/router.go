@@ -2,9 +2,11 @@ package category
 
 import (
 	"encoding/json"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
 	"flukis/product/utils/resp"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/oklog/ulid/v2"
@@ -31,6 +33,8 @@ func (r *Router) Routes() *chi.Mux {
 	route.Delete("/{id}", r.DeleteCategoryHandler)
 	route.Get("/{id}", r.GetCategoryOneByIDHandler)
 	route.Get("/", r.GetCategorysHandler)
+	route.Get("/attribute/{id}", r.GetAttributeSetCategoryHandler)
+	route.Patch("/attribute/{id}", r.UpdateAttributeSetCategoryHandler)
 
 	return route
 }
@@ -147,7 +151,7 @@ func (r *Router) GetCategoryOneByIDHandler(w http.ResponseWriter, req *http.Requ
 		return
 	}
 	ctx := req.Context()
-	res, err := r.service.GetCategoryById(ctx, id)
+	res, err := r.service.GetCategoryById(ctx, id, helper.ParseLocales(req))
 	if err != nil {
 		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
 			log.Error().Err(err)
@@ -163,8 +167,7 @@ func (r *Router) GetCategoryOneByIDHandler(w http.ResponseWriter, req *http.Requ
 
 func (r *Router) GetCategorysHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
-	limitStr := req.URL.Query().Get("limit")
-	limitInt, err := strconv.Atoi(limitStr)
+	pageReq, err := domain.NewPageRequest(req.URL.Query())
 	if err != nil {
 		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
 			log.Error().Err(err)
@@ -172,28 +175,99 @@ func (r *Router) GetCategorysHandler(w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
-	cursor := req.URL.Query().Get("cursor")
-	res, length, next, err := r.service.GetCategoryByCursor(ctx, limitInt, cursor)
+	res, length, page, err := r.service.GetCategoryByCursor(ctx, pageReq, helper.ParseLocales(req))
 	if err != nil {
-		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+		if err = resp.WriteError(w, listErrorStatus(err), err); err != nil {
 			log.Error().Err(err)
 			return
 		}
 		return
 	}
 
-	var metaResp struct {
-		Limit    int    `json:"limit"`
-		ThisPage int    `json:"total_this_page"`
-		Next     string `json:"next_cursor"`
+	metaResp := domain.NewPageMeta(pageReq, page, length)
+
+	if err = resp.WriteResponse(w, "get all Category success", http.StatusOK, res, metaResp); err != nil {
+		log.Error().Err(err)
+		return
 	}
+}
 
-	metaResp.Limit = limitInt
-	metaResp.Next = next
-	metaResp.ThisPage = length
+func (r *Router) GetAttributeSetCategoryHandler(w http.ResponseWriter, req *http.Request) {
+	categoryId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(categoryId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	res, err := r.service.GetAttributeSetCategory(ctx, id)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get attribute set of Category success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
 
-	if err = resp.WriteResponse(w, "get all Category success", http.StatusOK, res, metaResp); err != nil {
+func (r *Router) UpdateAttributeSetCategoryHandler(w http.ResponseWriter, req *http.Request) {
+	categoryId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(categoryId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Added   []domain.CategoryAttributeInput `json:"added"`
+		Removed []ulid.ULID                     `json:"removed"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	if len(input.Added) > 0 {
+		err = r.service.UpdateAttributeSetCategory(ctx, id, input.Added)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if len(input.Removed) > 0 {
+		err = r.service.DeleteAttributeSetCategoryBatch(ctx, id, input.Removed)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if err = resp.WriteResponse(w, "update attribute set of Category success", http.StatusOK, nil, nil); err != nil {
 		log.Error().Err(err)
 		return
 	}
 }
+
+func listErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
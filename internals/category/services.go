@@ -3,22 +3,109 @@ package category
 import (
 	"context"
 	"flukis/product/domain"
+	"flukis/product/internals/category_attribute"
+	"flukis/product/internals/search"
+	"flukis/product/internals/translation"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/oklog/ulid/v2"
 )
 
 type Service interface {
-	GetCategoryById(ctx context.Context, id ulid.ULID) (domain.CategoriesDTO, error)
-	GetCategoryByCursor(ctx context.Context, limit int, cursor string) ([]domain.CategoriesDTO, int, string, error)
+	GetCategoryById(ctx context.Context, id ulid.ULID, locales []string) (domain.CategoriesDTO, error)
+	GetCategoryByCursor(ctx context.Context, req domain.PageRequest, locales []string) ([]domain.CategoriesDTO, int, domain.Page, error)
 	DeleteCategory(ctx context.Context, id ulid.ULID) error
 	UpdateCategory(ctx context.Context, id ulid.ULID, name, desc string) (domain.CategoriesDTO, error)
 	CreateCategory(ctx context.Context, name, desc string) (domain.CategoriesDTO, error)
+	GetAttributeSetCategory(ctx context.Context, id ulid.ULID) ([]domain.CategoryAttributeDTO, error)
+	UpdateAttributeSetCategory(ctx context.Context, id ulid.ULID, attributes []domain.CategoryAttributeInput) error
+	DeleteAttributeSetCategoryBatch(ctx context.Context, id ulid.ULID, attributeIds []ulid.ULID) error
 }
 
 type service struct {
-	repo Repo
-	db   *pgxpool.Pool
+	repo                  Repo
+	translationRepo       translation.Repo
+	categoryAttributeRepo category_attribute.Repo
+	indexer               search.Indexer
+	db                    *pgxpool.Pool
+}
+
+func (s *service) GetAttributeSetCategory(ctx context.Context, id ulid.ULID) ([]domain.CategoryAttributeDTO, error) {
+	set, err := s.categoryAttributeRepo.GetByCategoryID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var res = make([]domain.CategoryAttributeDTO, 0, len(set))
+	for idx := range set {
+		res = append(res, domain.NewCategoryAttributeDTO(set[idx]))
+	}
+	return res, nil
+}
+
+func (s *service) UpdateAttributeSetCategory(ctx context.Context, id ulid.ULID, attributes []domain.CategoryAttributeInput) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.GetByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	for idx := range attributes {
+		newSet, err := domain.NewCategoryAttribute(
+			id,
+			attributes[idx].ID,
+			attributes[idx].Required,
+			attributes[idx].Position,
+		)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		err = s.categoryAttributeRepo.SaveWithTransaction(ctx, tx, &newSet)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *service) DeleteAttributeSetCategoryBatch(ctx context.Context, id ulid.ULID, attributeIds []ulid.ULID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range attributeIds {
+		err = s.categoryAttributeRepo.DeleteWithTransaction(ctx, tx, id, attributeIds[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
 }
 
 // Createcat implements Service.
@@ -81,18 +168,19 @@ func (s *service) DeleteCategory(ctx context.Context, id ulid.ULID) error {
 	if err != nil {
 		return err
 	}
+	s.indexer.CategoryChanged(ctx, id)
 	return nil
 }
 
 // GetcatByCursor implements Service.
-func (s *service) GetCategoryByCursor(ctx context.Context, limit int, cursor string) (res []domain.CategoriesDTO, length int, nextCursor string, err error) {
-	category, nextCursor, err := s.repo.GetByCursor(ctx, limit, cursor)
+func (s *service) GetCategoryByCursor(ctx context.Context, req domain.PageRequest, locales []string) (res []domain.CategoriesDTO, length int, page domain.Page, err error) {
+	category, page, err := s.repo.GetByCursor(ctx, req)
 	if err != nil {
-		return []domain.CategoriesDTO{}, 0, "", err
+		return []domain.CategoriesDTO{}, 0, domain.Page{}, err
 	}
 	dataLen := len(category)
 	if dataLen == 0 {
-		return []domain.CategoriesDTO{}, 0, "", nil
+		return []domain.CategoriesDTO{}, 0, page, nil
 	}
 	var data = make([]domain.CategoriesDTO, dataLen)
 	for i := range category {
@@ -100,19 +188,44 @@ func (s *service) GetCategoryByCursor(ctx context.Context, limit int, cursor str
 		data[i].Name = category[i].Name
 		data[i].Description = category[i].Description
 	}
-	return data, dataLen, nextCursor, nil
+	ids := make([]ulid.ULID, dataLen)
+	for i := range category {
+		ids[i] = category[i].CategoryID
+	}
+	translated, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityCategory, ids, locales)
+	if err != nil {
+		return []domain.CategoriesDTO{}, 0, domain.Page{}, err
+	}
+	for i := range data {
+		if tr, ok := translated[data[i].ID]; ok {
+			tr.Apply(&data[i].Name, &data[i].Description)
+		}
+	}
+	return data, dataLen, page, nil
 }
 
 // GetcatById implements Service.
-func (s *service) GetCategoryById(ctx context.Context, id ulid.ULID) (domain.CategoriesDTO, error) {
+func (s *service) GetCategoryById(ctx context.Context, id ulid.ULID, locales []string) (domain.CategoriesDTO, error) {
 	cat, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return domain.CategoriesDTO{}, err
 	}
+	translated, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityCategory, []ulid.ULID{id}, locales)
+	if err != nil {
+		return domain.CategoriesDTO{}, err
+	}
+	if tr, ok := translated[id]; ok {
+		tr.Apply(&cat.Name, &cat.Description)
+	}
+	attributes, err := s.GetAttributeSetCategory(ctx, id)
+	if err != nil {
+		return domain.CategoriesDTO{}, err
+	}
 	res := domain.CategoriesDTO{
 		ID:          cat.CategoryID,
 		Name:        cat.Name,
 		Description: cat.Description,
+		Attributes:  attributes,
 	}
 	return res, nil
 }
@@ -151,15 +264,22 @@ func (s *service) UpdateCategory(ctx context.Context, id ulid.ULID, name, desc s
 	if err != nil {
 		return domain.CategoriesDTO{}, err
 	}
+	s.indexer.CategoryChanged(ctx, id)
 	return res, nil
 }
 
 func NewService(
 	repo Repo,
+	translationRepo translation.Repo,
+	categoryAttributeRepo category_attribute.Repo,
+	indexer search.Indexer,
 	db *pgxpool.Pool,
 ) Service {
 	return &service{
-		repo: repo,
-		db:   db,
+		repo:                  repo,
+		translationRepo:       translationRepo,
+		categoryAttributeRepo: categoryAttributeRepo,
+		indexer:               indexer,
+		db:                    db,
 	}
 }
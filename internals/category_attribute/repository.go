@@ -0,0 +1,202 @@
+package category_attribute
+
+import (
+	"context"
+	"flukis/product/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+)
+
+type Repo interface {
+	GetByCategoryID(ctx context.Context, id ulid.ULID) ([]domain.CategoryAttribute, error)
+	GetByCategoryIDs(ctx context.Context, ids []ulid.ULID) ([]domain.CategoryAttribute, error)
+	GetByCategoryIDsWithTransaction(ctx context.Context, tx pgx.Tx, ids []ulid.ULID) ([]domain.CategoryAttribute, error)
+	SaveWithTransaction(ctx context.Context, tx pgx.Tx, ca *domain.CategoryAttribute) error
+	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, categoryId, attributeId ulid.ULID) error
+}
+
+type repo struct {
+	db *pgxpool.Pool
+}
+
+// GetByCategoryID implements Repo.
+func (r *repo) GetByCategoryID(ctx context.Context, id ulid.ULID) ([]domain.CategoryAttribute, error) {
+	return r.GetByCategoryIDs(ctx, []ulid.ULID{id})
+}
+
+// GetByCategoryIDs implements Repo. Attribute on more than one category
+// is returned once per category.
+func (r *repo) GetByCategoryIDs(ctx context.Context, ids []ulid.ULID) ([]domain.CategoryAttribute, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query := `
+		SELECT
+			ca.category_attribute_id,
+			ca.category_id,
+			ca.required,
+			ca.position,
+			a.attribute_id,
+			a.name,
+			a.data_type,
+			a.unit,
+			a.min_value,
+			a.max_value,
+			a.max_length
+		FROM Category_Attribute ca
+		JOIN Attribute a ON ca.attribute_id = a.attribute_id
+		WHERE ca.category_id = ANY($1)
+			AND ca.deleted_at IS NULL
+			AND a.deleted_at IS NULL
+		ORDER BY
+			ca.category_id, ca.position, a.name
+	`
+	rawIds := make([][]byte, len(ids))
+	for i := range ids {
+		rawIds[i] = ids[i][:]
+	}
+	rows, err := r.db.Query(ctx, query, rawIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var set []domain.CategoryAttribute
+	for rows.Next() {
+		var ca domain.CategoryAttribute
+		if err := rows.Scan(
+			&ca.CategoryAttributeID,
+			&ca.Category.CategoryID,
+			&ca.Required,
+			&ca.Position,
+			&ca.Attribute.AttributeID,
+			&ca.Attribute.Name,
+			&ca.Attribute.Type,
+			&ca.Attribute.Unit,
+			&ca.Attribute.Min,
+			&ca.Attribute.Max,
+			&ca.Attribute.MaxLength,
+		); err != nil {
+			return nil, err
+		}
+		set = append(set, ca)
+	}
+	return set, rows.Err()
+}
+
+// GetByCategoryIDsWithTransaction implements Repo.
+func (*repo) GetByCategoryIDsWithTransaction(ctx context.Context, tx pgx.Tx, ids []ulid.ULID) ([]domain.CategoryAttribute, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query := `
+		SELECT
+			ca.category_attribute_id,
+			ca.category_id,
+			ca.required,
+			ca.position,
+			a.attribute_id,
+			a.name,
+			a.data_type,
+			a.unit,
+			a.min_value,
+			a.max_value,
+			a.max_length
+		FROM Category_Attribute ca
+		JOIN Attribute a ON ca.attribute_id = a.attribute_id
+		WHERE ca.category_id = ANY($1)
+			AND ca.deleted_at IS NULL
+			AND a.deleted_at IS NULL
+		ORDER BY
+			ca.category_id, ca.position, a.name
+	`
+	rawIds := make([][]byte, len(ids))
+	for i := range ids {
+		rawIds[i] = ids[i][:]
+	}
+	rows, err := tx.Query(ctx, query, rawIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var set []domain.CategoryAttribute
+	for rows.Next() {
+		var ca domain.CategoryAttribute
+		if err := rows.Scan(
+			&ca.CategoryAttributeID,
+			&ca.Category.CategoryID,
+			&ca.Required,
+			&ca.Position,
+			&ca.Attribute.AttributeID,
+			&ca.Attribute.Name,
+			&ca.Attribute.Type,
+			&ca.Attribute.Unit,
+			&ca.Attribute.Min,
+			&ca.Attribute.Max,
+			&ca.Attribute.MaxLength,
+		); err != nil {
+			return nil, err
+		}
+		set = append(set, ca)
+	}
+	return set, rows.Err()
+}
+
+// SaveWithTransaction implements Repo. Saving an attribute that is already
+// in the set update its required flag and position.
+func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, ca *domain.CategoryAttribute) error {
+	query := `
+		INSERT INTO Category_Attribute
+			(category_attribute_id, category_id, attribute_id, required, position, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (category_id, attribute_id) DO UPDATE SET
+			required = EXCLUDED.required,
+			position = EXCLUDED.position,
+			updated_at = CURRENT_TIMESTAMP,
+			deleted_at = NULL
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&ca.CategoryAttributeID,
+		&ca.Category.CategoryID,
+		&ca.Attribute.AttributeID,
+		&ca.Required,
+		&ca.Position,
+		&ca.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, categoryId, attributeId ulid.ULID) error {
+	query := `
+		UPDATE Category_Attribute SET
+			deleted_at = $1
+		WHERE
+			category_id = $2 AND attribute_id = $3 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		categoryId,
+		attributeId,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewRepo(db *pgxpool.Pool) Repo {
+	return &repo{
+		db: db,
+	}
+}
@@ -0,0 +1,289 @@
+package facet
+
+import (
+	"context"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+)
+
+type Repo interface {
+	Count(ctx context.Context, scope domain.FacetScope, filter domain.ListFilter) (domain.Facets, error)
+}
+
+type repo struct {
+	db *pgxpool.Pool
+}
+
+// Count implements Repo. Items are first narrowed by the search text, with
+// boosted product of the search rules fetched apart so the match use the
+// search index, and the filters that are not facets (tag, name and dates),
+// then each item is flagged with the facet filters it pass. A facet only
+// require the flags of the other facets. For attribute facet the item must
+// match every requested attribute except the counted one.
+func (r *repo) Count(ctx context.Context, scope domain.FacetScope, filter domain.ListFilter) (domain.Facets, error) {
+	query := `
+		WITH q AS (
+			SELECT CASE WHEN $1::text = '' THEN NULL ELSE search_query($1) END AS query
+		), matched_products AS (
+			SELECT p.product_id
+			FROM Product p, q
+			WHERE q.query IS NULL AND p.deleted_at IS NULL
+			UNION ALL
+			SELECT p.product_id
+			FROM Product p, q
+			WHERE p.deleted_at IS NULL AND p.search_vector @@ q.query
+			UNION ALL
+			SELECT p.product_id
+			FROM Search_Rule sr
+			JOIN Product p ON p.product_id = sr.product_id, q
+			WHERE sr.query = $11
+				AND sr.action = 'boost'
+				AND sr.deleted_at IS NULL
+				AND p.deleted_at IS NULL
+				AND q.query IS NOT NULL
+				AND (p.search_vector @@ q.query) IS NOT TRUE
+		), matched_variants AS (
+			SELECT v.variant_id
+			FROM Variant v, q
+			WHERE $2 AND q.query IS NULL AND v.deleted_at IS NULL
+			UNION ALL
+			SELECT v.variant_id
+			FROM Variant v, q
+			WHERE $2 AND v.deleted_at IS NULL AND v.search_vector @@ q.query
+		), items AS (
+			SELECT
+				p.product_id AS id,
+				p.product_id,
+				p.brand_id,
+				COALESCE(p.price, 0)::float8 AS price,
+				FALSE AS is_variant
+			FROM
+				matched_products m
+			JOIN
+				Product p ON p.product_id = m.product_id
+			WHERE
+				%s
+			UNION ALL
+			SELECT
+				v.variant_id,
+				v.main_product_id,
+				p.brand_id,
+				COALESCE(v.price, 0)::float8,
+				TRUE
+			FROM
+				matched_variants m
+			JOIN
+				Variant v ON v.variant_id = m.variant_id
+			JOIN
+				Product p ON p.product_id = v.main_product_id
+			WHERE
+				p.deleted_at IS NULL
+				AND %s
+		), item_attrs AS (
+			SELECT i.id, va.attribute_id, va.value
+			FROM items i
+			JOIN Variant_Attribute va ON va.variant_id = i.id AND va.deleted_at IS NULL
+			WHERE i.is_variant
+			UNION ALL
+			SELECT i.id, pa.attribute_id, pa.value
+			FROM items i
+			JOIN Product_Attribute pa ON pa.product_id = i.product_id AND pa.deleted_at IS NULL
+			WHERE NOT i.is_variant
+				OR NOT EXISTS (
+					SELECT 1
+					FROM Variant_Attribute va
+					WHERE va.variant_id = i.id
+						AND va.attribute_id = pa.attribute_id
+						AND va.deleted_at IS NULL
+				)
+		), wanted AS (
+			SELECT DISTINCT f.attribute_id, f.value
+			FROM unnest($7::bytea[], $8::text[]) AS f(attribute_id, value)
+		), wanted_attrs AS (
+			SELECT DISTINCT attribute_id
+			FROM wanted
+		), wanted_count AS (
+			SELECT COUNT(*) AS n
+			FROM wanted_attrs
+		), matched AS (
+			SELECT ia.id, ia.attribute_id
+			FROM item_attrs ia
+			JOIN wanted w ON w.attribute_id = ia.attribute_id AND w.value = ia.value
+			GROUP BY ia.id, ia.attribute_id
+		), matched_count AS (
+			SELECT id, COUNT(*) AS n
+			FROM matched
+			GROUP BY id
+		), flags AS (
+			SELECT
+				i.id,
+				i.product_id,
+				i.brand_id,
+				i.price,
+				COALESCE(
+					COALESCE(cardinality($5::bytea[]), 0) = 0
+					OR EXISTS (
+						SELECT 1
+						FROM Product_Category pc
+						WHERE pc.product_id = i.product_id
+							AND pc.deleted_at IS NULL
+							AND pc.category_id = ANY($5)
+					),
+					FALSE
+				) AS in_category,
+				COALESCE(COALESCE(cardinality($6::bytea[]), 0) = 0 OR i.brand_id = ANY($6), FALSE) AS in_brand,
+				($3::float8 IS NULL OR i.price >= $3) AND ($4::float8 IS NULL OR i.price <= $4) AS in_price,
+				COALESCE(mc.n, 0) AS attr_matched
+			FROM
+				items i
+			LEFT JOIN
+				matched_count mc ON mc.id = i.id
+		), attribute_counts AS (
+			SELECT
+				a.attribute_id,
+				a.name,
+				ia.value,
+				COUNT(DISTINCT f.id) AS total,
+				row_number() OVER (
+					PARTITION BY a.attribute_id
+					ORDER BY COUNT(DISTINCT f.id) DESC, ia.value
+				) AS rank
+			FROM
+				flags f
+			CROSS JOIN
+				wanted_count w
+			JOIN
+				item_attrs ia ON ia.id = f.id
+			JOIN
+				Attribute a ON a.attribute_id = ia.attribute_id AND a.deleted_at IS NULL
+			LEFT JOIN
+				matched m ON m.id = f.id AND m.attribute_id = ia.attribute_id
+			LEFT JOIN
+				wanted_attrs wa ON wa.attribute_id = ia.attribute_id
+			WHERE
+				f.in_category AND f.in_brand AND f.in_price
+				AND f.attr_matched - (m.id IS NOT NULL)::int = w.n - (wa.attribute_id IS NOT NULL)::int
+			GROUP BY
+				a.attribute_id, a.name, ia.value
+		)
+		SELECT 'category', c.category_id, c.name, '', COUNT(DISTINCT f.id), 0
+		FROM flags f
+		CROSS JOIN wanted_count w
+		JOIN Product_Category pc ON pc.product_id = f.product_id AND pc.deleted_at IS NULL
+		JOIN Category c ON c.category_id = pc.category_id AND c.deleted_at IS NULL
+		WHERE f.in_brand AND f.in_price AND f.attr_matched = w.n
+		GROUP BY c.category_id, c.name
+		UNION ALL
+		SELECT 'brand', b.brand_id, b.name, '', COUNT(DISTINCT f.id), 0
+		FROM flags f
+		CROSS JOIN wanted_count w
+		JOIN Brand b ON b.brand_id = f.brand_id AND b.deleted_at IS NULL
+		WHERE f.in_category AND f.in_price AND f.attr_matched = w.n
+		GROUP BY b.brand_id, b.name
+		UNION ALL
+		SELECT 'attribute', attribute_id, name, value, total, 0
+		FROM attribute_counts
+		WHERE rank <= $10
+		UNION ALL
+		SELECT
+			'price', NULL, '', '', COUNT(f.id),
+			CASE WHEN cardinality($9::float8[]) = 0 THEN 0 ELSE width_bucket(f.price, $9::float8[]) END
+		FROM flags f
+		CROSS JOIN wanted_count w
+		WHERE f.in_category AND f.in_brand AND f.attr_matched = w.n
+		GROUP BY 6
+		ORDER BY 1, 5 DESC, 3, 4
+	`
+	bounds := scope.PriceBounds
+	if bounds == nil {
+		bounds = []float64{}
+	}
+	valueLimit := scope.ValueLimit
+	if valueLimit <= 0 {
+		valueLimit = domain.DefaultFacetValueLimit
+	}
+	attrIds, attrValues := filter.AttributePairs()
+	args := []any{
+		scope.Query,
+		scope.Variants,
+		filter.MinPrice,
+		filter.MaxPrice,
+		filter.CategoryKeys(),
+		filter.BrandKeys(),
+		attrIds,
+		attrValues,
+		bounds,
+		valueLimit,
+		domain.NormalizeSearchTerm(scope.Query),
+	}
+	productWhere, args := helper.ProductFilter(filter.WithoutFacets(), args)
+	variantWhere, args := helper.VariantFilter(filter.WithoutFacets(), args)
+	query = fmt.Sprintf(query, productWhere, variantWhere)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return domain.Facets{}, err
+	}
+	defer rows.Close()
+
+	res := domain.Facets{
+		Categories: []domain.CategoryFacet{},
+		Brands:     []domain.BrandFacet{},
+		Attributes: []domain.AttributeFacet{},
+		Prices:     []domain.PriceFacet{},
+	}
+	attrIndex := make(map[ulid.ULID]int)
+	priceCounts := make(map[int]int)
+	for rows.Next() {
+		var (
+			kind   string
+			rawId  []byte
+			name   string
+			value  string
+			count  int
+			bucket int
+		)
+		if err := rows.Scan(&kind, &rawId, &name, &value, &count, &bucket); err != nil {
+			return domain.Facets{}, err
+		}
+		var id ulid.ULID
+		copy(id[:], rawId)
+		switch kind {
+		case "category":
+			res.Categories = append(res.Categories, domain.CategoryFacet{ID: id, Name: name, Count: count})
+		case "brand":
+			res.Brands = append(res.Brands, domain.BrandFacet{ID: id, Name: name, Count: count})
+		case "attribute":
+			idx, ok := attrIndex[id]
+			if !ok {
+				idx = len(res.Attributes)
+				attrIndex[id] = idx
+				res.Attributes = append(res.Attributes, domain.AttributeFacet{ID: id, Name: name})
+			}
+			res.Attributes[idx].Values = append(res.Attributes[idx].Values, domain.AttributeValueFacet{Value: value, Count: count})
+		case "price":
+			priceCounts[bucket] = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return domain.Facets{}, err
+	}
+	// price buckets go from cheapest to most expensive, empty one skipped
+	for bucket := 0; bucket <= len(bounds); bucket++ {
+		if count := priceCounts[bucket]; count > 0 {
+			res.Prices = append(res.Prices, domain.NewPriceFacet(bounds, bucket, count))
+		}
+	}
+	res.MarkSelected(filter)
+	return res, nil
+}
+
+func NewRepo(db *pgxpool.Pool) Repo {
+	return &repo{
+		db: db,
+	}
+}
@@ -0,0 +1,197 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+	"flukis/product/utils/storage"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/guregu/null.v4"
+)
+
+// BlobPrefix is the storage key prefix of every image.
+const BlobPrefix = "images"
+
+const webpContentType = "image/webp"
+
+// ErrUndecodable is returned when stored bytes are not a decodable image.
+var ErrUndecodable = errors.New("stored image can not be decoded")
+
+// BlobStore keep image bytes in storage together with the resized
+// renditions generated on upload.
+type BlobStore struct {
+	storage    storage.Storage
+	renditions []helper.Rendition
+}
+
+func NewBlobStore(st storage.Storage, renditions []helper.Rendition) *BlobStore {
+	return &BlobStore{
+		storage:    st,
+		renditions: renditions,
+	}
+}
+
+// Store put the original data and every rendition in storage and return
+// the blob reference of the original with its perceptual hash. WebP copy
+// of a rendition is only stored when it is smaller. Data that does not
+// decode return ErrUndecodable and nothing is stored.
+func (b *BlobStore) Store(ctx context.Context, data []byte) (domain.Blob, error) {
+	src, format, err := helper.DecodeImage(data)
+	if err != nil {
+		return domain.Blob{}, fmt.Errorf("%w: %v", ErrUndecodable, err)
+	}
+	blob := domain.NewBlob(BlobPrefix, data)
+	if err := b.storage.Put(ctx, blob.Key, data, blob.ContentType); err != nil {
+		return domain.Blob{}, err
+	}
+	blob.PHash = null.IntFrom(int64(helper.PerceptualHash(src)))
+
+	resized, err := helper.ResizeImage(src, format, b.renditions)
+	if err != nil {
+		return domain.Blob{}, err
+	}
+	for _, r := range resized {
+		if err := b.storage.Put(ctx, blob.RenditionKey(r.Rendition.Name), r.Data, http.DetectContentType(r.Data)); err != nil {
+			return domain.Blob{}, err
+		}
+		if r.WebP == nil {
+			continue
+		}
+		if err := b.storage.Put(ctx, blob.WebPKey(r.Rendition.Name), r.WebP, webpContentType); err != nil {
+			return domain.Blob{}, err
+		}
+	}
+	return blob, nil
+}
+
+// Delete remove the original of blob and every rendition generated from
+// it. Missing object is not an error.
+func (b *BlobStore) Delete(ctx context.Context, blob domain.Blob) error {
+	keys := []string{blob.Key}
+	for _, r := range b.renditions {
+		keys = append(keys, blob.RenditionKey(r.Name), blob.WebPKey(r.Name))
+	}
+	for _, key := range keys {
+		if err := b.storage.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Discard delete the blob stored for an upload whose transaction failed.
+// Blob is keyed by its content, so it is kept when any image or product
+// already reference the same bytes.
+func Discard(ctx context.Context, repo Repo, blobs *BlobStore, blob domain.Blob) {
+	inUse, err := repo.BlobInUse(ctx, blob.Key)
+	if err != nil {
+		log.Error().Err(err).Str("key", blob.Key).Msg("failed to check blob usage")
+		return
+	}
+	if inUse {
+		return
+	}
+	if err := blobs.Delete(ctx, blob); err != nil {
+		log.Error().Err(err).Str("key", blob.Key).Msg("failed to delete orphaned blob")
+	}
+}
+
+// PerceptualHash read the original stored under key and return its
+// perceptual hash.
+func (b *BlobStore) PerceptualHash(ctx context.Context, key string) (int64, error) {
+	data, err := storage.ReadAll(ctx, b.storage, key)
+	if err != nil {
+		return 0, err
+	}
+	src, _, err := helper.DecodeImage(data)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUndecodable, err)
+	}
+	return int64(helper.PerceptualHash(src)), nil
+}
+
+// Read return the named rendition of blob, or the original when the
+// rendition was never generated.
+func (b *BlobStore) Read(ctx context.Context, blob domain.Blob, rendition string) ([]byte, error) {
+	data, err := storage.ReadAll(ctx, b.storage, blob.RenditionKey(rendition))
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.ReadAll(ctx, b.storage, blob.Key)
+	}
+	return data, err
+}
+
+// Open return the rendition of an image ready to be served, as WebP when
+// webp is set and a WebP copy exist. Image not migrated yet is served from
+// its inline data whatever the rendition.
+func (b *BlobStore) Open(ctx context.Context, blob domain.Blob, inline []byte, rendition string, webp bool) (domain.ImageContent, error) {
+	if rendition == "" {
+		rendition = helper.RenditionPreview
+	}
+	if !b.validRendition(rendition) {
+		return domain.ImageContent{}, fmt.Errorf("%w: %s", domain.ErrInvalidRendition, rendition)
+	}
+	if blob.Empty() {
+		if len(inline) == 0 {
+			return domain.ImageContent{}, domain.ErrImageNotFound
+		}
+		sum := sha256.Sum256(inline)
+		return domain.ImageContent{
+			Data:        inline,
+			ContentType: http.DetectContentType(inline),
+			ETag:        hex.EncodeToString(sum[:]),
+		}, nil
+	}
+
+	if webp && rendition != helper.RenditionOriginal {
+		data, err := storage.ReadAll(ctx, b.storage, blob.WebPKey(rendition))
+		if err == nil {
+			return domain.ImageContent{
+				Data:        data,
+				ContentType: webpContentType,
+				ETag:        blob.Hash + "-" + rendition + "-webp",
+			}, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return domain.ImageContent{}, err
+		}
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if rendition == helper.RenditionOriginal {
+		data, err = storage.ReadAll(ctx, b.storage, blob.Key)
+	} else {
+		data, err = b.Read(ctx, blob, rendition)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return domain.ImageContent{}, domain.ErrImageNotFound
+		}
+		return domain.ImageContent{}, err
+	}
+	return domain.ImageContent{
+		Data:        data,
+		ContentType: http.DetectContentType(data),
+		ETag:        blob.Hash + "-" + rendition,
+	}, nil
+}
+
+func (b *BlobStore) validRendition(name string) bool {
+	if name == helper.RenditionOriginal {
+		return true
+	}
+	for _, r := range b.renditions {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// MigrateBlobs move inline image bytes of Product.image_preview and
+// Image.data into storage together with their renditions, batch rows at a
+// time, and clear the inline column. Image that does not decode is logged
+// and left inline. It return the number of moved rows and can be run again
+// safely. batch must be positive.
+func MigrateBlobs(ctx context.Context, db *pgxpool.Pool, blobs *BlobStore, batch int) (int, error) {
+	if batch <= 0 {
+		return 0, fmt.Errorf("batch must be positive, got %d", batch)
+	}
+	moved := 0
+	tables := []struct {
+		selectQuery string
+		updateQuery string
+	}{
+		{
+			selectQuery: `
+				SELECT
+					product_id,
+					image_preview
+				FROM Product
+				WHERE image_preview IS NOT NULL AND image_key IS NULL AND product_id > $1
+				ORDER BY product_id
+				LIMIT $2
+			`,
+			updateQuery: `
+				UPDATE Product SET
+					image_key = $1,
+					image_size = $2,
+					image_content_type = $3,
+					image_hash = $4,
+					image_phash = $5,
+					image_preview = NULL
+				WHERE
+					product_id = $6
+			`,
+		},
+		{
+			selectQuery: `
+				SELECT
+					image_id,
+					data
+				FROM Image
+				WHERE data IS NOT NULL AND blob_key IS NULL AND image_id > $1
+				ORDER BY image_id
+				LIMIT $2
+			`,
+			updateQuery: `
+				UPDATE Image SET
+					blob_key = $1,
+					blob_size = $2,
+					content_type = $3,
+					hash = $4,
+					phash = $5,
+					data = NULL
+				WHERE
+					image_id = $6
+			`,
+		},
+	}
+	for _, table := range tables {
+		lastId := []byte{}
+		for {
+			n, read, err := migrateBatch(ctx, db, blobs, table.selectQuery, table.updateQuery, &lastId, batch)
+			moved += n
+			if err != nil {
+				return moved, err
+			}
+			if read < batch {
+				break
+			}
+		}
+	}
+	return moved, nil
+}
+
+// migrateBatch move the batch rows following lastId and advance lastId
+// past them. It return the number of moved and read rows.
+func migrateBatch(ctx context.Context, db *pgxpool.Pool, blobs *BlobStore, selectQuery, updateQuery string, lastId *[]byte, batch int) (int, int, error) {
+	rows, err := db.Query(ctx, selectQuery, *lastId, batch)
+	if err != nil {
+		return 0, 0, err
+	}
+	type inline struct {
+		id   []byte
+		data []byte
+	}
+	var pending []inline
+	for rows.Next() {
+		var row inline
+		if err := rows.Scan(&row.id, &row.data); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		pending = append(pending, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	moved := 0
+	for idx := range pending {
+		*lastId = pending[idx].id
+		blob, err := blobs.Store(ctx, pending[idx].data)
+		if errors.Is(err, ErrUndecodable) {
+			var id ulid.ULID
+			copy(id[:], pending[idx].id)
+			log.Warn().Err(err).Str("id", id.String()).Msg("image left inline")
+			continue
+		}
+		if err != nil {
+			return moved, len(pending), err
+		}
+		if _, err := db.Exec(
+			ctx,
+			updateQuery,
+			blob.Key,
+			blob.Size,
+			blob.ContentType,
+			blob.Hash,
+			blob.PHash,
+			pending[idx].id,
+		); err != nil {
+			return moved, len(pending), err
+		}
+		moved++
+	}
+	return moved, len(pending), nil
+}
+
+// BackfillPerceptualHash compute the perceptual hash of images moved to
+// storage before hashes existed, batch rows at a time. Image that does not
+// decode is skipped. It return the number of hashed rows.
+func BackfillPerceptualHash(ctx context.Context, db *pgxpool.Pool, blobs *BlobStore, batch int) (int, error) {
+	if batch <= 0 {
+		return 0, fmt.Errorf("batch must be positive, got %d", batch)
+	}
+	hashed := 0
+	tables := []struct {
+		selectQuery string
+		updateQuery string
+	}{
+		{
+			selectQuery: `
+				SELECT
+					product_id,
+					image_key
+				FROM Product
+				WHERE image_key IS NOT NULL AND image_phash IS NULL AND product_id > $1
+				ORDER BY product_id
+				LIMIT $2
+			`,
+			updateQuery: `
+				UPDATE Product SET
+					image_phash = $1
+				WHERE
+					product_id = $2
+			`,
+		},
+		{
+			selectQuery: `
+				SELECT
+					image_id,
+					blob_key
+				FROM Image
+				WHERE blob_key IS NOT NULL AND phash IS NULL AND image_id > $1
+				ORDER BY image_id
+				LIMIT $2
+			`,
+			updateQuery: `
+				UPDATE Image SET
+					phash = $1
+				WHERE
+					image_id = $2
+			`,
+		},
+	}
+	for _, table := range tables {
+		lastId := []byte{}
+		for {
+			rows, err := db.Query(ctx, table.selectQuery, lastId, batch)
+			if err != nil {
+				return hashed, err
+			}
+			type stored struct {
+				id  []byte
+				key string
+			}
+			var pending []stored
+			for rows.Next() {
+				var row stored
+				if err := rows.Scan(&row.id, &row.key); err != nil {
+					rows.Close()
+					return hashed, err
+				}
+				pending = append(pending, row)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return hashed, err
+			}
+
+			for idx := range pending {
+				lastId = pending[idx].id
+				phash, err := blobs.PerceptualHash(ctx, pending[idx].key)
+				if errors.Is(err, ErrUndecodable) {
+					continue
+				}
+				if err != nil {
+					return hashed, err
+				}
+				if _, err := db.Exec(ctx, table.updateQuery, phash, pending[idx].id); err != nil {
+					return hashed, err
+				}
+				hashed++
+			}
+			if len(pending) < batch {
+				break
+			}
+		}
+	}
+	return hashed, nil
+}
@@ -0,0 +1,416 @@
+package image
+
+import (
+	"context"
+	"flukis/product/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+)
+
+type Repo interface {
+	GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, entityType domain.EntityType, entityId, id ulid.ULID) (*domain.Image, error)
+	GetByID(ctx context.Context, entityType domain.EntityType, entityId, id ulid.ULID) (*domain.Image, error)
+	GetByEntityID(ctx context.Context, entityType domain.EntityType, id ulid.ULID) ([]domain.Image, error)
+	GetPrimaryByEntityIDs(ctx context.Context, entityType domain.EntityType, ids []ulid.ULID) (map[ulid.ULID]domain.Image, error)
+	GetByEntityIDs(ctx context.Context, entityType domain.EntityType, ids []ulid.ULID) (map[ulid.ULID][]domain.Image, error)
+	CountByEntityIDWithTransaction(ctx context.Context, tx pgx.Tx, entityType domain.EntityType, id ulid.ULID) (int, error)
+	SaveWithTransaction(ctx context.Context, tx pgx.Tx, img *domain.Image) error
+	EditWithTransaction(ctx context.Context, tx pgx.Tx, img *domain.Image) error
+	ClearPrimaryWithTransaction(ctx context.Context, tx pgx.Tx, entityType domain.EntityType, id ulid.ULID) error
+	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, img *domain.Image) error
+	BlobInUse(ctx context.Context, key string) (bool, error)
+}
+
+type repo struct {
+	db *pgxpool.Pool
+}
+
+// GetByIDWithTransaction implements Repo.
+func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, entityType domain.EntityType, entityId, id ulid.ULID) (*domain.Image, error) {
+	query := `
+		SELECT
+			image_id,
+			entity_type,
+			entity_id,
+			alt_text,
+			position,
+			is_primary
+		FROM Image
+		WHERE image_id = $1
+			AND entity_type = $2
+			AND entity_id = $3
+			AND deleted_at IS NULL
+	`
+	row := tx.QueryRow(
+		ctx,
+		query,
+		id,
+		entityType,
+		entityId,
+	)
+	var img domain.Image
+	if err := row.Scan(
+		&img.ImageID,
+		&img.EntityType,
+		&img.EntityID,
+		&img.AltText,
+		&img.Position,
+		&img.IsPrimary,
+	); err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// GetByID implements Repo.
+func (r *repo) GetByID(ctx context.Context, entityType domain.EntityType, entityId, id ulid.ULID) (*domain.Image, error) {
+	query := `
+		SELECT
+			image_id,
+			entity_type,
+			entity_id,
+			data,
+			COALESCE(blob_key, ''),
+			COALESCE(blob_size, 0),
+			COALESCE(content_type, ''),
+			COALESCE(hash, ''),
+			alt_text,
+			position,
+			is_primary
+		FROM Image
+		WHERE image_id = $1
+			AND entity_type = $2
+			AND entity_id = $3
+			AND deleted_at IS NULL
+	`
+	row := r.db.QueryRow(
+		ctx,
+		query,
+		id,
+		entityType,
+		entityId,
+	)
+	var img domain.Image
+	if err := row.Scan(
+		&img.ImageID,
+		&img.EntityType,
+		&img.EntityID,
+		&img.Data,
+		&img.Blob.Key,
+		&img.Blob.Size,
+		&img.Blob.ContentType,
+		&img.Blob.Hash,
+		&img.AltText,
+		&img.Position,
+		&img.IsPrimary,
+	); err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// GetByEntityID implements Repo.
+func (r *repo) GetByEntityID(ctx context.Context, entityType domain.EntityType, id ulid.ULID) ([]domain.Image, error) {
+	query := `
+		SELECT
+			image_id,
+			entity_type,
+			entity_id,
+			data,
+			COALESCE(blob_key, ''),
+			COALESCE(blob_size, 0),
+			COALESCE(content_type, ''),
+			COALESCE(hash, ''),
+			alt_text,
+			position,
+			is_primary
+		FROM Image
+		WHERE entity_type = $1
+			AND entity_id = $2
+			AND deleted_at IS NULL
+		ORDER BY
+			position, created_at
+	`
+	rows, err := r.db.Query(ctx, query, entityType, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []domain.Image
+	for rows.Next() {
+		var img domain.Image
+		if err := rows.Scan(
+			&img.ImageID,
+			&img.EntityType,
+			&img.EntityID,
+			&img.Data,
+			&img.Blob.Key,
+			&img.Blob.Size,
+			&img.Blob.ContentType,
+			&img.Blob.Hash,
+			&img.AltText,
+			&img.Position,
+			&img.IsPrimary,
+		); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// GetPrimaryByEntityIDs implements Repo. Entity without primary image
+// get its first image by position.
+func (r *repo) GetPrimaryByEntityIDs(ctx context.Context, entityType domain.EntityType, ids []ulid.ULID) (map[ulid.ULID]domain.Image, error) {
+	res := make(map[ulid.ULID]domain.Image)
+	if len(ids) == 0 {
+		return res, nil
+	}
+	query := `
+		SELECT DISTINCT ON (entity_id)
+			image_id,
+			entity_type,
+			entity_id,
+			data,
+			COALESCE(blob_key, ''),
+			COALESCE(blob_size, 0),
+			COALESCE(content_type, ''),
+			COALESCE(hash, ''),
+			alt_text,
+			position,
+			is_primary
+		FROM Image
+		WHERE entity_type = $1
+			AND entity_id = ANY($2)
+			AND deleted_at IS NULL
+		ORDER BY
+			entity_id, is_primary DESC, position, created_at
+	`
+	rawIds := make([][]byte, len(ids))
+	for i := range ids {
+		rawIds[i] = ids[i][:]
+	}
+	rows, err := r.db.Query(ctx, query, entityType, rawIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var img domain.Image
+		if err := rows.Scan(
+			&img.ImageID,
+			&img.EntityType,
+			&img.EntityID,
+			&img.Data,
+			&img.Blob.Key,
+			&img.Blob.Size,
+			&img.Blob.ContentType,
+			&img.Blob.Hash,
+			&img.AltText,
+			&img.Position,
+			&img.IsPrimary,
+		); err != nil {
+			return nil, err
+		}
+		res[img.EntityID] = img
+	}
+	return res, rows.Err()
+}
+
+// GetByEntityIDs implements Repo. It return the gallery of every entity
+// in one query, keyed by entity id, in gallery order.
+func (r *repo) GetByEntityIDs(ctx context.Context, entityType domain.EntityType, ids []ulid.ULID) (map[ulid.ULID][]domain.Image, error) {
+	res := make(map[ulid.ULID][]domain.Image)
+	if len(ids) == 0 {
+		return res, nil
+	}
+	query := `
+		SELECT
+			image_id,
+			entity_type,
+			entity_id,
+			data,
+			COALESCE(blob_key, ''),
+			COALESCE(blob_size, 0),
+			COALESCE(content_type, ''),
+			COALESCE(hash, ''),
+			alt_text,
+			position,
+			is_primary
+		FROM Image
+		WHERE entity_type = $1
+			AND entity_id = ANY($2)
+			AND deleted_at IS NULL
+		ORDER BY
+			entity_id, position, created_at
+	`
+	rawIds := make([][]byte, len(ids))
+	for i := range ids {
+		rawIds[i] = ids[i][:]
+	}
+	rows, err := r.db.Query(ctx, query, entityType, rawIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var img domain.Image
+		if err := rows.Scan(
+			&img.ImageID,
+			&img.EntityType,
+			&img.EntityID,
+			&img.Data,
+			&img.Blob.Key,
+			&img.Blob.Size,
+			&img.Blob.ContentType,
+			&img.Blob.Hash,
+			&img.AltText,
+			&img.Position,
+			&img.IsPrimary,
+		); err != nil {
+			return nil, err
+		}
+		res[img.EntityID] = append(res[img.EntityID], img)
+	}
+	return res, rows.Err()
+}
+
+// CountByEntityIDWithTransaction implements Repo.
+func (*repo) CountByEntityIDWithTransaction(ctx context.Context, tx pgx.Tx, entityType domain.EntityType, id ulid.ULID) (int, error) {
+	query := `
+		SELECT
+			COUNT(*)
+		FROM Image
+		WHERE entity_type = $1
+			AND entity_id = $2
+			AND deleted_at IS NULL
+	`
+	var count int
+	if err := tx.QueryRow(ctx, query, entityType, id).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, img *domain.Image) error {
+	query := `
+		INSERT INTO Image
+			(image_id, entity_type, entity_id, blob_key, blob_size, content_type, hash, phash, alt_text, position, is_primary, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&img.ImageID,
+		&img.EntityType,
+		&img.EntityID,
+		&img.Blob.Key,
+		&img.Blob.Size,
+		&img.Blob.ContentType,
+		&img.Blob.Hash,
+		&img.Blob.PHash,
+		&img.AltText,
+		&img.Position,
+		&img.IsPrimary,
+		&img.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) EditWithTransaction(ctx context.Context, tx pgx.Tx, img *domain.Image) error {
+	query := `
+		UPDATE Image SET
+			alt_text = $1,
+			position = $2,
+			is_primary = $3,
+			updated_at = $4
+		WHERE
+			image_id = $5 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&img.AltText,
+		&img.Position,
+		&img.IsPrimary,
+		currentTime,
+		&img.ImageID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClearPrimaryWithTransaction implements Repo. It unset the primary flag
+// of every image of the entity.
+func (*repo) ClearPrimaryWithTransaction(ctx context.Context, tx pgx.Tx, entityType domain.EntityType, id ulid.ULID) error {
+	query := `
+		UPDATE Image SET
+			is_primary = FALSE,
+			updated_at = $1
+		WHERE
+			entity_type = $2 AND entity_id = $3 AND is_primary AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		entityType,
+		id,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, img *domain.Image) error {
+	query := `
+		UPDATE Image SET
+			is_primary = FALSE,
+			deleted_at = $1
+		WHERE
+			image_id = $2 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		&img.ImageID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// BlobInUse implements Repo. Deleted rows count too, their blob is never
+// removed.
+func (r *repo) BlobInUse(ctx context.Context, key string) (bool, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM Image WHERE blob_key = $1)
+			OR EXISTS (SELECT 1 FROM Product WHERE image_key = $1)
+	`
+	var inUse bool
+	if err := r.db.QueryRow(ctx, query, key).Scan(&inUse); err != nil {
+		return false, err
+	}
+	return inUse, nil
+}
+
+func NewRepo(db *pgxpool.Pool) Repo {
+	return &repo{
+		db: db,
+	}
+}
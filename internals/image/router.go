@@ -0,0 +1,234 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+	"flukis/product/utils/resp"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/guregu/null.v4"
+)
+
+type Router struct {
+	service    Service
+	imageRules helper.ImageRules
+}
+
+func NewRouter(
+	service Service,
+	imageRules helper.ImageRules,
+) *Router {
+	return &Router{
+		service:    service,
+		imageRules: imageRules,
+	}
+}
+
+func (r *Router) Routes() *chi.Mux {
+	route := chi.NewMux()
+
+	route.Get("/{type}/{id}", r.GetImagesHandler)
+	route.Post("/{type}/{id}", r.UploadImageHandler)
+	route.Patch("/{type}/{id}", r.ReorderImagesHandler)
+	route.Get("/{type}/{id}/{imageId}", r.GetImageContentHandler)
+	route.Get("/{type}/{id}/{imageId}/{rendition}", r.GetImageContentHandler)
+	route.Delete("/{type}/{id}/{imageId}", r.DeleteImageHandler)
+
+	return route
+}
+
+func (r *Router) GetImagesHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	entityType := domain.EntityType(chi.URLParam(req, "type"))
+	res, err := r.service.GetImages(ctx, entityType, id)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get images success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+// UploadImageHandler take multipart form with "image" file and optional
+// "alt", "position" and "primary" field.
+func (r *Router) UploadImageHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	imageData, err := helper.UploadImageHandler(w, req, r.imageRules)
+	if err != nil {
+		if err = helper.WriteImageError(w, err); err != nil {
+			return
+		}
+		return
+	}
+	var position null.Int
+	if positionStr := req.FormValue("position"); positionStr != "" {
+		positionInt, err := strconv.Atoi(positionStr)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+				return
+			}
+			return
+		}
+		position = null.IntFrom(int64(positionInt))
+	}
+	var primary bool
+	if primaryStr := req.FormValue("primary"); primaryStr != "" {
+		primary, err = strconv.ParseBool(primaryStr)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+				return
+			}
+			return
+		}
+	}
+	entityType := domain.EntityType(chi.URLParam(req, "type"))
+	res, err := r.service.UploadImage(ctx, entityType, id, imageData, req.FormValue("alt"), position, primary)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "upload image success", http.StatusCreated, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) ReorderImagesHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input []domain.ImageInput
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	entityType := domain.EntityType(chi.URLParam(req, "type"))
+	res, err := r.service.ReorderImages(ctx, entityType, id, input)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "reorder images success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) DeleteImageHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	imageId, err := ulid.Parse(chi.URLParam(req, "imageId"))
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	entityType := domain.EntityType(chi.URLParam(req, "type"))
+	err = r.service.DeleteImage(ctx, entityType, id, imageId)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "delete image success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+// GetImageContentHandler stream one gallery image, the "rendition" path
+// param choose one of the generated sizes and default to the preview.
+func (r *Router) GetImageContentHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	imageId, err := ulid.Parse(chi.URLParam(req, "imageId"))
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	entityType := domain.EntityType(chi.URLParam(req, "type"))
+	res, err := r.service.GetImageContent(ctx, entityType, id, imageId, chi.URLParam(req, "rendition"), helper.AcceptsWebP(req))
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	helper.ServeImage(w, req, res.Data, res.ContentType, res.ETag)
+}
+
+func errorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidImageOwner) ||
+		errors.Is(err, domain.ErrInvalidImage) ||
+		errors.Is(err, domain.ErrInvalidRendition) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, domain.ErrImageNotFound) || errors.Is(err, pgx.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,242 @@
+package image
+
+import (
+	"context"
+	"flukis/product/domain"
+	"flukis/product/internals/search"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+type Service interface {
+	GetImages(ctx context.Context, entityType domain.EntityType, id ulid.ULID) ([]domain.ImageDTO, error)
+	UploadImage(ctx context.Context, entityType domain.EntityType, id ulid.ULID, data []byte, alt string, position null.Int, primary bool) (domain.ImageDTO, error)
+	ReorderImages(ctx context.Context, entityType domain.EntityType, id ulid.ULID, images []domain.ImageInput) ([]domain.ImageDTO, error)
+	DeleteImage(ctx context.Context, entityType domain.EntityType, id, imageId ulid.ULID) error
+	GetImageContent(ctx context.Context, entityType domain.EntityType, id, imageId ulid.ULID, rendition string, webp bool) (domain.ImageContent, error)
+}
+
+type service struct {
+	repo    Repo
+	blobs   *BlobStore
+	indexer search.Indexer
+	db      *pgxpool.Pool
+}
+
+// GetImages implements Service.
+func (s *service) GetImages(ctx context.Context, entityType domain.EntityType, id ulid.ULID) ([]domain.ImageDTO, error) {
+	if !domain.ValidImageOwner(entityType) {
+		return []domain.ImageDTO{}, domain.ErrInvalidImageOwner
+	}
+	images, err := s.repo.GetByEntityID(ctx, entityType, id)
+	if err != nil {
+		return []domain.ImageDTO{}, err
+	}
+	return domain.NewImagesDTO(images), nil
+}
+
+// GetImageContent implements Service.
+func (s *service) GetImageContent(ctx context.Context, entityType domain.EntityType, id, imageId ulid.ULID, rendition string, webp bool) (domain.ImageContent, error) {
+	if !domain.ValidImageOwner(entityType) {
+		return domain.ImageContent{}, domain.ErrInvalidImageOwner
+	}
+	img, err := s.repo.GetByID(ctx, entityType, id, imageId)
+	if err != nil {
+		return domain.ImageContent{}, err
+	}
+	return s.blobs.Open(ctx, img.Blob, img.Data, rendition, webp)
+}
+
+// UploadImage implements Service. Without position the image is appended
+// to the gallery, and the first image of a gallery is always primary.
+func (s *service) UploadImage(ctx context.Context, entityType domain.EntityType, id ulid.ULID, data []byte, alt string, position null.Int, primary bool) (domain.ImageDTO, error) {
+	newImg, err := domain.NewImage(entityType, id, data, alt, int(position.Int64), primary)
+	if err != nil {
+		return domain.ImageDTO{}, err
+	}
+	newImg.Blob, err = s.blobs.Store(ctx, data)
+	if err != nil {
+		return domain.ImageDTO{}, err
+	}
+
+	err = s.saveImage(ctx, &newImg, position.Valid)
+	if err != nil {
+		Discard(ctx, s.repo, s.blobs, newImg.Blob)
+		return domain.ImageDTO{}, err
+	}
+	s.ownerChanged(ctx, entityType, id)
+	return domain.NewImageDTO(newImg), nil
+}
+
+// saveImage insert the image in its owner gallery, at the end unless
+// positioned.
+func (s *service) saveImage(ctx context.Context, img *domain.Image, positioned bool) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	count, err := s.repo.CountByEntityIDWithTransaction(ctx, tx, img.EntityType, img.EntityID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+	if !positioned {
+		img.Position = count
+	}
+	if count == 0 {
+		img.IsPrimary = true
+	}
+
+	if img.IsPrimary {
+		err = s.repo.ClearPrimaryWithTransaction(ctx, tx, img.EntityType, img.EntityID)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = s.repo.SaveWithTransaction(ctx, tx, img)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReorderImages implements Service. It change position, alt text and
+// primary flag of the given images, other images are left as is.
+func (s *service) ReorderImages(ctx context.Context, entityType domain.EntityType, id ulid.ULID, images []domain.ImageInput) ([]domain.ImageDTO, error) {
+	if !domain.ValidImageOwner(entityType) {
+		return []domain.ImageDTO{}, domain.ErrInvalidImageOwner
+	}
+	primaries := 0
+	for idx := range images {
+		if images[idx].Primary {
+			primaries++
+		}
+	}
+	if primaries > 1 {
+		return []domain.ImageDTO{}, fmt.Errorf("%w: only one image can be primary", domain.ErrInvalidImage)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return []domain.ImageDTO{}, err
+	}
+
+	for idx := range images {
+		if !images[idx].Primary {
+			continue
+		}
+		err = s.repo.ClearPrimaryWithTransaction(ctx, tx, entityType, id)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return []domain.ImageDTO{}, err
+			}
+			return []domain.ImageDTO{}, err
+		}
+		break
+	}
+
+	for idx := range images {
+		currImg, err := s.repo.GetByIDWithTransaction(ctx, tx, entityType, id, images[idx].ID)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return []domain.ImageDTO{}, err
+			}
+			return []domain.ImageDTO{}, err
+		}
+		currImg.Position = images[idx].Position
+		if images[idx].Primary {
+			currImg.IsPrimary = true
+		}
+		if images[idx].AltText.Valid {
+			currImg.AltText, err = domain.NormalizeAltText(images[idx].AltText.String)
+			if err != nil {
+				if err := tx.Rollback(ctx); err != nil {
+					return []domain.ImageDTO{}, err
+				}
+				return []domain.ImageDTO{}, err
+			}
+		}
+		err = s.repo.EditWithTransaction(ctx, tx, currImg)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return []domain.ImageDTO{}, err
+			}
+			return []domain.ImageDTO{}, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return []domain.ImageDTO{}, err
+	}
+	return s.GetImages(ctx, entityType, id)
+}
+
+// DeleteImage implements Service.
+func (s *service) DeleteImage(ctx context.Context, entityType domain.EntityType, id, imageId ulid.ULID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	currImg, err := s.repo.GetByIDWithTransaction(ctx, tx, entityType, id, imageId)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = s.repo.DeleteWithTransaction(ctx, tx, currImg)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	s.ownerChanged(ctx, entityType, id)
+	return nil
+}
+
+// ownerChanged tell the search indexer, hits show whether the owner has an
+// image.
+func (s *service) ownerChanged(ctx context.Context, entityType domain.EntityType, id ulid.ULID) {
+	if entityType == domain.EntityVariant {
+		s.indexer.VariantChanged(ctx, id)
+		return
+	}
+	s.indexer.ProductChanged(ctx, id)
+}
+
+func NewService(
+	repo Repo,
+	blobs *BlobStore,
+	indexer search.Indexer,
+	db *pgxpool.Pool,
+) Service {
+	return &service{
+		repo:    repo,
+		blobs:   blobs,
+		indexer: indexer,
+		db:      db,
+	}
+}
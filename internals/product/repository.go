@@ -4,6 +4,8 @@ import (
 	"context"
 	"flukis/product/domain"
 	"flukis/product/utils/helper"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -17,12 +19,16 @@ type Repo interface {
 	GetByID(ctx context.Context, id ulid.ULID) (*domain.Product, error)
 	SaveWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Product) error
 	EditWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Product) error
+	EditShippingWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Product) error
+	EditImageWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Product) error
 	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Product) error
-	GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.Product, string, error)
+	GetByCursor(ctx context.Context, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort) ([]domain.Product, domain.Page, error)
+	GetDuplicates(ctx context.Context, distance, limit int) ([]domain.DuplicateProduct, error)
 }
 
 type repo struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	cursors *helper.Cursors
 }
 
 // GetByID implements Repo.
@@ -33,7 +39,19 @@ func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID
 			name,
 			description,
 			price,
-			image_preview
+			image_preview,
+			COALESCE(image_key, ''),
+			COALESCE(image_size, 0),
+			COALESCE(image_content_type, ''),
+			COALESCE(image_hash, ''),
+			brand_id,
+			weight,
+			weight_unit,
+			length,
+			width,
+			height,
+			dimension_unit,
+			shipping_class
 		FROM
 			Product
 		WHERE
@@ -51,6 +69,18 @@ func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID
 		&prd.Description,
 		&prd.Price,
 		&prd.ImagePreview,
+		&prd.Image.Key,
+		&prd.Image.Size,
+		&prd.Image.ContentType,
+		&prd.Image.Hash,
+		&prd.Brand.BrandID,
+		&prd.Shipping.Weight,
+		&prd.Shipping.WeightUnit,
+		&prd.Shipping.Length,
+		&prd.Shipping.Width,
+		&prd.Shipping.Height,
+		&prd.Shipping.DimensionUnit,
+		&prd.Shipping.ShippingClass,
 	); err != nil {
 		return nil, err
 	}
@@ -65,7 +95,19 @@ func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Product, erro
 			name,
 			description,
 			price,
-			image_preview
+			image_preview,
+			COALESCE(image_key, ''),
+			COALESCE(image_size, 0),
+			COALESCE(image_content_type, ''),
+			COALESCE(image_hash, ''),
+			brand_id,
+			weight,
+			weight_unit,
+			length,
+			width,
+			height,
+			dimension_unit,
+			shipping_class
 		FROM
 			Product
 		WHERE
@@ -83,6 +125,18 @@ func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Product, erro
 		&prd.Description,
 		&prd.Price,
 		&prd.ImagePreview,
+		&prd.Image.Key,
+		&prd.Image.Size,
+		&prd.Image.ContentType,
+		&prd.Image.Hash,
+		&prd.Brand.BrandID,
+		&prd.Shipping.Weight,
+		&prd.Shipping.WeightUnit,
+		&prd.Shipping.Length,
+		&prd.Shipping.Width,
+		&prd.Shipping.Height,
+		&prd.Shipping.DimensionUnit,
+		&prd.Shipping.ShippingClass,
 	); err != nil {
 		return nil, err
 	}
@@ -92,9 +146,9 @@ func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Product, erro
 func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Product) error {
 	query := `
 		INSERT INTO Product
-			(product_id, name, description, price)
+			(product_id, name, description, price, brand_id)
 		VALUES
-			($1, $2, $3, $4)
+			($1, $2, $3, $4, $5)
 	`
 	if _, err := tx.Exec(
 		ctx,
@@ -103,6 +157,7 @@ func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Pro
 		&prd.Name,
 		&prd.Description,
 		&prd.Price,
+		nullableID(prd.Brand.BrandID),
 	); err != nil {
 		return err
 	}
@@ -115,10 +170,10 @@ func (*repo) EditWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Pro
 			name = $1,
 			description = $2,
 			price = $3,
-			image_preview = $4,
-			updated_at = $5
+			updated_at = $4,
+			brand_id = $6
 		WHERE
-			product_id = $6 AND deleted_at IS NULL
+			product_id = $5 AND deleted_at IS NULL
 	`
 	currentTime := time.Now()
 	if _, err := tx.Exec(
@@ -127,7 +182,72 @@ func (*repo) EditWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Pro
 		&prd.Name,
 		&prd.Description,
 		&prd.Price,
-		&prd.ImagePreview,
+		currentTime,
+		&prd.ProductID,
+		nullableID(prd.Brand.BrandID),
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EditImageWithTransaction implements Repo. The inline image_preview is
+// cleared, image content live in storage under the blob key.
+func (*repo) EditImageWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Product) error {
+	query := `
+		UPDATE Product SET
+			image_preview = NULL,
+			image_key = $1,
+			image_size = $2,
+			image_content_type = $3,
+			image_hash = $4,
+			image_phash = $5,
+			updated_at = $6
+		WHERE
+			product_id = $7 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&prd.Image.Key,
+		&prd.Image.Size,
+		&prd.Image.ContentType,
+		&prd.Image.Hash,
+		&prd.Image.PHash,
+		currentTime,
+		&prd.ProductID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) EditShippingWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.Product) error {
+	query := `
+		UPDATE Product SET
+			weight = $1,
+			weight_unit = $2,
+			length = $3,
+			width = $4,
+			height = $5,
+			dimension_unit = $6,
+			shipping_class = $7,
+			updated_at = $8
+		WHERE
+			product_id = $9 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&prd.Shipping.Weight,
+		&prd.Shipping.WeightUnit,
+		&prd.Shipping.Length,
+		&prd.Shipping.Width,
+		&prd.Shipping.Height,
+		&prd.Shipping.DimensionUnit,
+		&prd.Shipping.ShippingClass,
 		currentTime,
 		&prd.ProductID,
 	); err != nil {
@@ -155,32 +275,48 @@ func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.P
 	return nil
 }
 
-func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.Product, string, error) {
-	query := `
+// GetByCursor implements Repo. Page after, or before, cursor in the given
+// order, the cursor carry the sort value and id of the product it point
+// at. In offset mode the page is read by number instead. One more product
+// is read to know whether the listing go on.
+func (r *repo) GetByCursor(ctx context.Context, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort) ([]domain.Product, domain.Page, error) {
+	decodedCursor, after, err := r.cursors.DecodeSort(req.Cursor, sort, filter.Scope())
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	sortColumn := productSortColumns[sort.Field]
+	compare, direction := sort.Keyset(decodedCursor)
+
+	from, filterArgs := productListFrom(filter)
+	args := slices.Clone(filterArgs)
+	keyset := ""
+	if req.Cursor != "" {
+		args = append(args, after, decodedCursor.ID)
+		keyset = fmt.Sprintf("AND (%s, product_id) %s ($%d, $%d)", sortColumn, compare, len(args)-1, len(args))
+	}
+	limit, args := helper.PageLimit(req, args)
+
+	query := fmt.Sprintf(`
 		SELECT
 			product_id,
 			name,
 			description,
 			price,
 			image_preview,
-			created_at
-		FROM
-			Product
-		WHERE
-			created_at > $1 AND deleted_at IS NULL
+			COALESCE(image_key, ''),
+			created_at,
+			COALESCE(updated_at, created_at)
+		%s
+			%s
 		ORDER BY
-			created_at
-		LIMIT $2
-	`
-	decodedCursor, err := helper.DecodeCursor(cursor)
-	if err != nil && cursor != "" {
-		log.Warn().Err(err).Msg("failed to decode cursor")
-		return nil, "", err
-	}
+			%s %s, product_id %s
+		%s
+	`, from, keyset, sortColumn, direction, direction, limit)
 
-	rows, err := r.db.Query(ctx, query, decodedCursor, limit)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, "", err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
@@ -193,23 +329,210 @@ func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]dom
 			&product.Description,
 			&product.Price,
 			&product.ImagePreview,
+			&product.Image.Key,
 			&product.CreatedAt,
+			&product.UpdatedAt,
 		); err != nil {
-			return nil, "", err
+			return nil, domain.Page{}, err
 		}
 		products = append(products, product)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
+	}
+
+	var page domain.Page
+	if req.OffsetMode() {
+		products, page = helper.OffsetPage(products, req.Limit)
+	} else {
+		products, page = helper.CursorPage(r.cursors, filter.Scope(), products, req.Limit, decodedCursor, func(prd domain.Product) domain.ListCursor {
+			return domain.ListCursor{
+				Sort:  sort.String(),
+				Value: sort.SortValue(prd.Name, prd.Price, prd.CreatedAt, prd.UpdatedAt),
+				ID:    prd.ProductID,
+			}
+		})
+	}
+	if err := helper.PageTotal(ctx, r.db, req, &page, "SELECT product_id "+from, filterArgs...); err != nil {
+		return nil, domain.Page{}, err
+	}
+	return products, page, nil
+}
+
+// productListFrom select the products passing filter, it is shared by
+// the listing and its count.
+func productListFrom(filter domain.ListFilter) (string, []any) {
+	where, args := helper.ProductFilter(filter, nil)
+	return `
+	FROM
+		Product AS p
+	WHERE
+		deleted_at IS NULL
+		AND ` + where, args
+}
+
+// productSortColumns map sort field to the ordered column.
+var productSortColumns = map[domain.SortField]string{
+	domain.SortCreated: "created_at",
+	domain.SortUpdated: "COALESCE(updated_at, created_at)",
+	domain.SortPrice:   "COALESCE(price, 0)",
+	domain.SortName:    "name",
+}
+
+// nullableID store zero ULID as NULL, for optional reference.
+func nullableID(id ulid.ULID) *ulid.ULID {
+	if id == (ulid.ULID{}) {
+		return nil
+	}
+	return &id
+}
+
+// maxBandDistance is the largest distance looked up through the
+// phash_bands index. Above it a band may differ by 4 bits or more and the
+// probes of each hash outnumber the pairwise comparison.
+const maxBandDistance = 12
 
-	nextCursor := ""
-	if len(products) == limit {
-		nextCursor = helper.EncodeCursor(products[len(products)-1].CreatedAt)
+// GetDuplicates implements Repo. Every image of a product is compared,
+// preview and gallery of the product and of its variants, and each pair
+// is reported once with its closest distance.
+//
+// Two hashes at most distance bits apart have a 16 bit band at most
+// distance/4 bits apart. Up to maxBandDistance only hashes sharing such a
+// band, looked up in the phash_bands index, are compared, above it every
+// pair is.
+func (r *repo) GetDuplicates(ctx context.Context, distance, limit int) ([]domain.DuplicateProduct, error) {
+	pairs := `
+		pairs AS (
+			SELECT
+				a.product_id,
+				b.product_id AS duplicate_id,
+				MIN(length(replace((a.phash # b.phash)::bit(64)::text, '0', ''))) AS distance
+			FROM hashes AS a
+			JOIN hashes AS b ON a.product_id < b.product_id
+			WHERE length(replace((a.phash # b.phash)::bit(64)::text, '0', '')) <= $1
+			GROUP BY a.product_id, b.product_id
+		)
+	`
+	args := []any{distance, limit, domain.EntityProduct, domain.EntityVariant}
+	if distance <= maxBandDistance {
+		pairs = `
+		probes AS (
+			SELECT
+				h.product_id,
+				h.phash,
+				array_agg((band << 16) | (((h.phash >> (16 * band)) & 65535)::INT # m.mask)) AS bands
+			FROM hashes AS h
+			CROSS JOIN generate_series(0, 3) AS band
+			JOIN Phash_Band_Mask AS m ON m.bits <= $5
+			GROUP BY h.product_id, h.phash
+		), candidates AS (
+			SELECT
+				pr.product_id,
+				pr.phash,
+				p.product_id AS duplicate_id,
+				p.image_phash AS duplicate_phash
+			FROM probes AS pr
+			JOIN Product AS p ON phash_bands(p.image_phash) && pr.bands AND p.deleted_at IS NULL
+			UNION ALL
+			SELECT
+				pr.product_id,
+				pr.phash,
+				i.entity_id,
+				i.phash
+			FROM probes AS pr
+			JOIN Image AS i ON phash_bands(i.phash) && pr.bands AND i.deleted_at IS NULL
+			WHERE i.entity_type = $3
+			UNION ALL
+			SELECT
+				pr.product_id,
+				pr.phash,
+				v.main_product_id,
+				i.phash
+			FROM probes AS pr
+			JOIN Image AS i ON phash_bands(i.phash) && pr.bands AND i.deleted_at IS NULL
+			JOIN Variant AS v ON v.variant_id = i.entity_id AND v.deleted_at IS NULL
+			WHERE i.entity_type = $4
+		), pairs AS (
+			SELECT
+				product_id,
+				duplicate_id,
+				MIN(length(replace((phash # duplicate_phash)::bit(64)::text, '0', ''))) AS distance
+			FROM candidates
+			WHERE product_id < duplicate_id
+				AND length(replace((phash # duplicate_phash)::bit(64)::text, '0', '')) <= $1
+			GROUP BY product_id, duplicate_id
+		)
+	`
+		args = append(args, distance/4)
 	}
+	query := fmt.Sprintf(`
+		WITH hashes AS (
+			SELECT
+				product_id,
+				image_phash AS phash
+			FROM Product
+			WHERE image_phash IS NOT NULL AND deleted_at IS NULL
+			UNION
+			SELECT
+				i.entity_id,
+				i.phash
+			FROM Image AS i
+			WHERE i.entity_type = $3 AND i.phash IS NOT NULL AND i.deleted_at IS NULL
+			UNION
+			SELECT
+				v.main_product_id,
+				i.phash
+			FROM Image AS i
+			JOIN Variant AS v ON v.variant_id = i.entity_id
+			WHERE i.entity_type = $4 AND i.phash IS NOT NULL AND i.deleted_at IS NULL AND v.deleted_at IS NULL
+		), %s
+		SELECT
+			p.product_id,
+			p.name,
+			p.description,
+			p.price,
+			d.product_id,
+			d.name,
+			d.description,
+			d.price,
+			pairs.distance
+		FROM pairs
+		JOIN Product AS p ON p.product_id = pairs.product_id AND p.deleted_at IS NULL
+		JOIN Product AS d ON d.product_id = pairs.duplicate_id AND d.deleted_at IS NULL
+		ORDER BY
+			pairs.distance, p.product_id, d.product_id
+		LIMIT $2
+	`, pairs)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return products, nextCursor, nil
+	var res []domain.DuplicateProduct
+	for rows.Next() {
+		var dup domain.DuplicateProduct
+		if err := rows.Scan(
+			&dup.Product.ProductID,
+			&dup.Product.Name,
+			&dup.Product.Description,
+			&dup.Product.Price,
+			&dup.Duplicate.ProductID,
+			&dup.Duplicate.Name,
+			&dup.Duplicate.Description,
+			&dup.Duplicate.Price,
+			&dup.Distance,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, dup)
+	}
+	return res, rows.Err()
 }
 
-func NewRepo(db *pgxpool.Pool) Repo {
+func NewRepo(db *pgxpool.Pool, cursors *helper.Cursors) Repo {
 	return &repo{
-		db: db,
+		db:      db,
+		cursors: cursors,
 	}
 }
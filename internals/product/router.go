@@ -3,35 +3,54 @@ package product
 import (
 	"encoding/json"
 	"errors"
+	"flukis/product/domain"
 	"flukis/product/utils/helper"
 	"flukis/product/utils/resp"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/oklog/ulid/v2"
 	"github.com/rs/zerolog/log"
 )
 
 type Router struct {
-	service Service
+	service           Service
+	imageRules        helper.ImageRules
+	duplicateDistance int
 }
 
 func NewRouter(
 	service Service,
+	imageRules helper.ImageRules,
+	duplicateDistance int,
 ) *Router {
 	return &Router{
-		service: service,
+		service:           service,
+		imageRules:        imageRules,
+		duplicateDistance: duplicateDistance,
 	}
 }
 
+const defaultDuplicateLimit = 50
+
 func (r *Router) Routes() *chi.Mux {
 	route := chi.NewMux()
 
 	route.Post("/", r.CreateProductHandler)
 	route.Post("/upload-image/{id}", r.UploadProductImageHandler)
 	route.Patch("/category/{id}", r.UpdateCategoryProductHandler)
+	route.Get("/related/{id}", r.GetRelatedProductsHandler)
+	route.Get("/duplicate", r.GetDuplicateProductsHandler)
+	route.Patch("/related/{id}", r.UpdateRelatedProductHandler)
+	route.Patch("/tag/{id}", r.UpdateTagProductHandler)
+	route.Patch("/shipping/{id}", r.UpdateShippingProductHandler)
+	route.Patch("/attribute/{id}", r.UpdateAttributeProductHandler)
 	route.Get("/{id}", r.GetProductOneByIDHandler)
+	route.Get("/{id}/variants", r.GetProductVariantsHandler)
+	route.Get("/{id}/image", r.GetProductImageHandler)
+	route.Get("/{id}/image/{rendition}", r.GetProductImageHandler)
 	route.Get("/", r.GetProductsHandler)
 	route.Patch("/{id}", r.UpdateDataProductHandler)
 	route.Delete("/{id}", r.DeleteProductHandler)
@@ -68,8 +87,91 @@ func (r *Router) UpdateCategoryProductHandler(w http.ResponseWriter, req *http.R
 		}
 		return
 	}
+	err = r.service.UpdateCategoryProduct(ctx, id, input.Added, input.Removed)
+	if err != nil {
+		if err = resp.WriteError(w, attributeErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "update category to product success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) GetRelatedProductsHandler(w http.ResponseWriter, req *http.Request) {
+	productId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(productId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	relType := domain.RelationType(req.URL.Query().Get("type"))
+	res, err := r.service.GetRelatedProducts(ctx, id, relType, helper.ParseLocales(req))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidRelationType) {
+			status = http.StatusBadRequest
+		}
+		if err = resp.WriteError(w, status, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get related product success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) UpdateRelatedProductHandler(w http.ResponseWriter, req *http.Request) {
+	productId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(productId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Added   []RelatedInput `json:"added"`
+		Removed []RelatedInput `json:"removed"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
 	if len(input.Added) > 0 {
-		err = r.service.UpdateCategoryProduct(ctx, id, input.Added)
+		err = r.service.UpdateRelatedProduct(ctx, id, input.Added)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrInvalidRelationType) || errors.Is(err, domain.ErrSelfRelation) {
+				status = http.StatusBadRequest
+			}
+			if errors.Is(err, domain.ErrRelationProduct) {
+				status = http.StatusNotFound
+			}
+			if err = resp.WriteError(w, status, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if len(input.Removed) > 0 {
+		err = r.service.DeleteRelatedProductBatch(ctx, id, input.Removed)
 		if err != nil {
 			if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
 				log.Error().Err(err)
@@ -78,8 +180,51 @@ func (r *Router) UpdateCategoryProductHandler(w http.ResponseWriter, req *http.R
 			return
 		}
 	}
+	if err = resp.WriteResponse(w, "update related product success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) UpdateTagProductHandler(w http.ResponseWriter, req *http.Request) {
+	productId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(productId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Added   []string `json:"added"`
+		Removed []string `json:"removed"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	if len(input.Added) > 0 {
+		err = r.service.UpdateTagProduct(ctx, id, input.Added)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrEmptyTag) {
+				status = http.StatusBadRequest
+			}
+			if err = resp.WriteError(w, status, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
 	if len(input.Removed) > 0 {
-		err = r.service.DeleteCategoryProductBatch(ctx, id, input.Removed)
+		err = r.service.DeleteTagProductBatch(ctx, id, input.Removed)
 		if err != nil {
 			if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
 				log.Error().Err(err)
@@ -88,7 +233,7 @@ func (r *Router) UpdateCategoryProductHandler(w http.ResponseWriter, req *http.R
 			return
 		}
 	}
-	if err = resp.WriteResponse(w, "update category to product success", http.StatusOK, nil, nil); err != nil {
+	if err = resp.WriteResponse(w, "update tag to product success", http.StatusOK, nil, nil); err != nil {
 		log.Error().Err(err)
 		return
 	}
@@ -127,8 +272,16 @@ func (r *Router) GetProductOneByIDHandler(w http.ResponseWriter, req *http.Reque
 		}
 		return
 	}
+	opts, err := domain.NewReadOptions(req.URL.Query(), domain.ProductIncludes)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
 	ctx := req.Context()
-	res, err := r.service.GetProductByID(ctx, id)
+	res, err := r.service.GetProductByID(ctx, id, helper.ParseLocales(req), opts.Include)
 	if err != nil {
 		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
 			log.Error().Err(err)
@@ -136,7 +289,68 @@ func (r *Router) GetProductOneByIDHandler(w http.ResponseWriter, req *http.Reque
 		}
 		return
 	}
-	if err = resp.WriteResponse(w, "get one product success", http.StatusOK, res, nil); err != nil {
+	data, err := resp.SelectFields(res, opts.Fields)
+	if err != nil {
+		if err = resp.WriteError(w, fieldsErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get one product success", http.StatusOK, data, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+// GetDuplicateProductsHandler list pairs of products with near-duplicate
+// images. "distance" is the max Hamming distance of the perceptual hashes
+// and default to the configured one.
+func (r *Router) GetDuplicateProductsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	distance := r.duplicateDistance
+	if distanceStr := req.URL.Query().Get("distance"); distanceStr != "" {
+		distanceInt, err := strconv.Atoi(distanceStr)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+				return
+			}
+			return
+		}
+		distance = distanceInt
+	}
+	limit := defaultDuplicateLimit
+	if limitStr := req.URL.Query().Get("limit"); limitStr != "" {
+		limitInt, err := strconv.Atoi(limitStr)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+				return
+			}
+			return
+		}
+		limit = limitInt
+	}
+	res, err := r.service.GetDuplicateProducts(ctx, distance, limit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidImageDistance) || errors.Is(err, domain.ErrInvalidDuplicateLimit) {
+			status = http.StatusBadRequest
+		}
+		if err = resp.WriteError(w, status, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+
+	var metaResp struct {
+		Distance int `json:"distance"`
+		Limit    int `json:"limit"`
+	}
+	metaResp.Distance = distance
+	metaResp.Limit = limit
+
+	if err = resp.WriteResponse(w, "get duplicate products success", http.StatusOK, res, metaResp); err != nil {
 		log.Error().Err(err)
 		return
 	}
@@ -144,8 +358,7 @@ func (r *Router) GetProductOneByIDHandler(w http.ResponseWriter, req *http.Reque
 
 func (r *Router) GetProductsHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
-	limitStr := req.URL.Query().Get("limit")
-	limitInt, err := strconv.Atoi(limitStr)
+	pageReq, err := domain.NewPageRequest(req.URL.Query())
 	if err != nil {
 		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
 			log.Error().Err(err)
@@ -153,10 +366,33 @@ func (r *Router) GetProductsHandler(w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
-	cursor := req.URL.Query().Get("cursor")
-	res, length, next, err := r.service.GetProductsByCursor(ctx, limitInt, cursor)
+	filter, err := domain.NewListFilter(req.URL.Query())
 	if err != nil {
-		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	sort, err := domain.NewListSort(req.URL.Query().Get("sort"), req.URL.Query().Get("order"))
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	opts, err := domain.NewReadOptions(req.URL.Query(), domain.ProductIncludes)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	res, length, page, err := r.service.GetProductsByCursor(ctx, pageReq, filter, sort, helper.ParseLocales(req), opts.Include)
+	if err != nil {
+		if err = resp.WriteError(w, listErrorStatus(err), err); err != nil {
 			log.Error().Err(err)
 			return
 		}
@@ -164,16 +400,101 @@ func (r *Router) GetProductsHandler(w http.ResponseWriter, req *http.Request) {
 	}
 
 	var metaResp struct {
-		Limit    int    `json:"limit"`
-		ThisPage int    `json:"total_this_page"`
-		Next     string `json:"next_cursor"`
+		domain.PageMeta
+		Facets *domain.Facets `json:"facets,omitempty"`
+	}
+	metaResp.PageMeta = domain.NewPageMeta(pageReq, page, length)
+
+	// facets do not change between pages, only the first page carry them
+	if pageReq.Cursor == "" && pageReq.Number <= 1 {
+		facets, err := r.service.GetProductFacets(ctx, filter)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+		metaResp.Facets = &facets
 	}
 
-	metaResp.Limit = limitInt
-	metaResp.Next = next
-	metaResp.ThisPage = length
+	data, err := resp.SelectFields(res, opts.Fields)
+	if err != nil {
+		if err = resp.WriteError(w, fieldsErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get all products success", http.StatusOK, data, metaResp); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+// GetProductVariantsHandler list the variants of one product, it take the
+// pagination, sort, filters, fields and include of the variant listing.
+func (r *Router) GetProductVariantsHandler(w http.ResponseWriter, req *http.Request) {
+	productId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(productId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	pageReq, err := domain.NewPageRequest(req.URL.Query())
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	filter, err := domain.NewListFilter(req.URL.Query())
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	sort, err := domain.NewListSort(req.URL.Query().Get("sort"), req.URL.Query().Get("order"))
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	opts, err := domain.NewReadOptions(req.URL.Query(), domain.VariantIncludes)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	res, length, page, err := r.service.GetProductVariants(ctx, id, pageReq, filter, sort, helper.ParseLocales(req), opts.Include)
+	if err != nil {
+		if err = resp.WriteError(w, listErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	data, err := resp.SelectFields(res, opts.Fields)
+	if err != nil {
+		if err = resp.WriteError(w, fieldsErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	metaResp := domain.NewPageMeta(pageReq, page, length)
 
-	if err = resp.WriteResponse(w, "get all products success", http.StatusOK, res, metaResp); err != nil {
+	if err = resp.WriteResponse(w, "get product variants success", http.StatusOK, data, metaResp); err != nil {
 		log.Error().Err(err)
 		return
 	}
@@ -196,9 +517,12 @@ func (r *Router) UpdateDataProductHandler(w http.ResponseWriter, req *http.Reque
 	}
 	ctx := req.Context()
 	var input struct {
-		Name        string  `json:"name"`
-		Description string  `json:"desc"`
-		Price       float64 `json:"price"`
+		Name        string                  `json:"name"`
+		Description string                  `json:"desc"`
+		Price       float64                 `json:"price"`
+		BrandID     ulid.ULID               `json:"brand_id"`
+		Categories  []ulid.ULID             `json:"categories"`
+		Attributes  []domain.AttributeInput `json:"attributes"`
 	}
 	decoder := json.NewDecoder(req.Body)
 	decoder.DisallowUnknownFields()
@@ -209,9 +533,9 @@ func (r *Router) UpdateDataProductHandler(w http.ResponseWriter, req *http.Reque
 		}
 		return
 	}
-	res, err := r.service.UpdateDataProduct(ctx, id, input.Name, input.Description, input.Price)
+	res, err := r.service.UpdateDataProduct(ctx, id, input.Name, input.Description, input.Price, input.BrandID, input.Categories, input.Attributes)
 	if err != nil {
-		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+		if err = resp.WriteError(w, attributeErrorStatus(err), err); err != nil {
 			log.Error().Err(err)
 			return
 		}
@@ -232,9 +556,12 @@ func (r *Router) CreateProductHandler(w http.ResponseWriter, req *http.Request)
 	}
 	ctx := req.Context()
 	var input struct {
-		Name        string  `json:"name"`
-		Description string  `json:"desc"`
-		Price       float64 `json:"price"`
+		Name        string                  `json:"name"`
+		Description string                  `json:"desc"`
+		Price       float64                 `json:"price"`
+		BrandID     ulid.ULID               `json:"brand_id"`
+		Categories  []ulid.ULID             `json:"categories"`
+		Attributes  []domain.AttributeInput `json:"attributes"`
 	}
 	decoder := json.NewDecoder(req.Body)
 	decoder.DisallowUnknownFields()
@@ -245,9 +572,9 @@ func (r *Router) CreateProductHandler(w http.ResponseWriter, req *http.Request)
 		}
 		return
 	}
-	res, err := r.service.CreateProduct(ctx, input.Name, input.Description, input.Price)
+	res, err := r.service.CreateProduct(ctx, input.Name, input.Description, input.Price, input.BrandID, input.Categories, input.Attributes)
 	if err != nil {
-		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+		if err = resp.WriteError(w, attributeErrorStatus(err), err); err != nil {
 			log.Error().Err(err)
 			return
 		}
@@ -275,9 +602,9 @@ func (r *Router) UploadProductImageHandler(w http.ResponseWriter, req *http.Requ
 		return
 	}
 	ctx := req.Context()
-	imageData, err := helper.UploadImageHandler(req)
+	imageData, err := helper.UploadImageHandler(w, req, r.imageRules)
 	if err != nil {
-		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+		if err = helper.WriteImageError(w, err); err != nil {
 			return
 		}
 		return
@@ -295,3 +622,150 @@ func (r *Router) UploadProductImageHandler(w http.ResponseWriter, req *http.Requ
 		return
 	}
 }
+
+func (r *Router) UpdateShippingProductHandler(w http.ResponseWriter, req *http.Request) {
+	productId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(productId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input domain.ShippingDTO
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.UpdateShippingProduct(ctx, id, input)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidShipping) {
+			status = http.StatusBadRequest
+		}
+		if err = resp.WriteError(w, status, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "update product shipping success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) UpdateAttributeProductHandler(w http.ResponseWriter, req *http.Request) {
+	productId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(productId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Added   []domain.AttributeInput `json:"added"`
+		Removed []ulid.ULID             `json:"removed"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	if len(input.Added) > 0 {
+		err = r.service.UpdateAttributeProduct(ctx, id, input.Added)
+		if err != nil {
+			if err = resp.WriteError(w, attributeErrorStatus(err), err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if len(input.Removed) > 0 {
+		err = r.service.DeleteAttributeProductBatch(ctx, id, input.Removed)
+		if err != nil {
+			if err = resp.WriteError(w, attributeErrorStatus(err), err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if err = resp.WriteResponse(w, "update attribute to product success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+// attributeErrorStatus map attribute validation error to bad request.
+func attributeErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidAttributeValue) ||
+		errors.Is(err, domain.ErrMissingAttribute) ||
+		errors.Is(err, domain.ErrAttributeNotAllowed) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetProductImageHandler stream the product image, the "rendition" path param choose
+// one of the generated sizes and default to the preview.
+func (r *Router) GetProductImageHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	res, err := r.service.GetImageProduct(ctx, id, chi.URLParam(req, "rendition"), helper.AcceptsWebP(req))
+	if err != nil {
+		if err = resp.WriteError(w, imageErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	helper.ServeImage(w, req, res.Data, res.ContentType, res.ETag)
+}
+
+func fieldsErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidFields) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func listErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func imageErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrImageNotFound) || errors.Is(err, pgx.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, domain.ErrInvalidRendition) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
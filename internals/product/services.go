@@ -4,7 +4,17 @@ import (
 	"context"
 	"errors"
 	"flukis/product/domain"
+	"flukis/product/internals/attribute"
+	"flukis/product/internals/brand"
+	"flukis/product/internals/category_attribute"
+	"flukis/product/internals/facet"
+	"flukis/product/internals/image"
 	"flukis/product/internals/product_category"
+	"flukis/product/internals/product_relation"
+	"flukis/product/internals/search"
+	"flukis/product/internals/tag"
+	"flukis/product/internals/translation"
+	"flukis/product/internals/variant"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -12,37 +22,134 @@ import (
 )
 
 type Service interface {
-	GetProductByID(ctx context.Context, id ulid.ULID) (domain.ProductDetailDTO, error)
-	CreateProduct(ctx context.Context, name, desc string, price float64) (domain.ProductDTO, error)
+	GetProductByID(ctx context.Context, id ulid.ULID, locales []string, include domain.Includes) (domain.ProductDetailDTO, error)
+	CreateProduct(ctx context.Context, name, desc string, price float64, brandId ulid.ULID, categoryIds []ulid.ULID, values []domain.AttributeInput) (domain.ProductDTO, error)
 	UpdateImageProduct(ctx context.Context, id ulid.ULID, image []byte) (domain.ProductDTO, error)
-	UpdateDataProduct(ctx context.Context, id ulid.ULID, name, desc string, price float64) (domain.ProductDTO, error)
-	GetProductsByCursor(ctx context.Context, limit int, cursor string) (res []domain.ProductDetailDTO, length int, nextCursor string, err error)
+	GetImageProduct(ctx context.Context, id ulid.ULID, rendition string, webp bool) (domain.ImageContent, error)
+	GetDuplicateProducts(ctx context.Context, distance, limit int) ([]domain.DuplicateProductDTO, error)
+	UpdateDataProduct(ctx context.Context, id ulid.ULID, name, desc string, price float64, brandId ulid.ULID, categoryIds []ulid.ULID, values []domain.AttributeInput) (domain.ProductDTO, error)
+	GetProductVariants(ctx context.Context, id ulid.ULID, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort, locales []string, include domain.Includes) (res []domain.VariantDetailDTO, length int, page domain.Page, err error)
+	GetProductsByCursor(ctx context.Context, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort, locales []string, include domain.Includes) (res []domain.ProductDetailDTO, length int, page domain.Page, err error)
+	GetProductFacets(ctx context.Context, filter domain.ListFilter) (domain.Facets, error)
 	DeleteProduct(ctx context.Context, id ulid.ULID) error
-	UpdateCategoryProduct(ctx context.Context, id ulid.ULID, categoryIds []ulid.ULID) error
-	DeleteCategoryProductBatch(ctx context.Context, id ulid.ULID, categoryIds []ulid.ULID) error
+	UpdateCategoryProduct(ctx context.Context, id ulid.ULID, added, removed []ulid.ULID) error
+	GetRelatedProducts(ctx context.Context, id ulid.ULID, relType domain.RelationType, locales []string) ([]domain.RelatedProductDTO, error)
+	UpdateRelatedProduct(ctx context.Context, id ulid.ULID, relations []RelatedInput) error
+	DeleteRelatedProductBatch(ctx context.Context, id ulid.ULID, relations []RelatedInput) error
+	UpdateTagProduct(ctx context.Context, id ulid.ULID, tags []string) error
+	DeleteTagProductBatch(ctx context.Context, id ulid.ULID, tags []string) error
+	UpdateShippingProduct(ctx context.Context, id ulid.ULID, input domain.ShippingDTO) (domain.ShippingDTO, error)
+	UpdateAttributeProduct(ctx context.Context, id ulid.ULID, values []domain.AttributeInput) error
+	DeleteAttributeProductBatch(ctx context.Context, id ulid.ULID, attributeIds []ulid.ULID) error
+}
+
+// RelatedInput is one entry of related product management request.
+type RelatedInput struct {
+	ID            ulid.ULID           `json:"id"`
+	Type          domain.RelationType `json:"type"`
+	Position      int                 `json:"position"`
+	Bidirectional bool                `json:"bidirectional"`
 }
 
 type service struct {
-	repo                 Repo
-	categoryRelationrepo product_category.Repo
-	db                   *pgxpool.Pool
+	repo                  Repo
+	categoryRelationrepo  product_category.Repo
+	productRelationRepo   product_relation.Repo
+	tagRepo               tag.Repo
+	brandRepo             brand.Repo
+	translationRepo       translation.Repo
+	attributeRepo         attribute.Repo
+	categoryAttributeRepo category_attribute.Repo
+	imageRepo             image.Repo
+	variantRepo           variant.Repo
+	variantSvc            variant.Service
+	facetRepo             facet.Repo
+	facetScope            domain.FacetScope
+	blobs                 *image.BlobStore
+	indexer               search.Indexer
+	db                    *pgxpool.Pool
+}
+
+// assignValues validate every input value against its attribute type.
+func (s *service) assignValues(ctx context.Context, values []domain.AttributeInput) ([]domain.AssignedAttribute, error) {
+	assigned := make([]domain.AssignedAttribute, len(values))
+	for idx := range values {
+		attr, err := attribute.AssignValue(ctx, s.attributeRepo, values[idx].ID, values[idx].Value)
+		if err != nil {
+			return nil, err
+		}
+		assigned[idx] = attr
+	}
+	return assigned, nil
 }
 
-func (s *service) DeleteCategoryProductBatch(ctx context.Context, id ulid.ULID, categoryIds []ulid.ULID) error {
+// checkAttributeSet validate the attribute of the product against the
+// attribute set of its categories. It run in the write transaction after
+// the change, so it see the final categories and attributes.
+func (s *service) checkAttributeSet(ctx context.Context, tx pgx.Tx, id ulid.ULID) error {
+	currCategories, err := s.categoryRelationrepo.GetByProductIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	categories := make([]ulid.ULID, len(currCategories))
+	for idx := range currCategories {
+		categories[idx] = currCategories[idx].Category.CategoryID
+	}
+	attributes, err := s.attributeRepo.GetByProductIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	set, err := s.categoryAttributeRepo.GetByCategoryIDsWithTransaction(ctx, tx, categories)
+	if err != nil {
+		return err
+	}
+	return domain.CheckAttributeSet(set, attributes)
+}
+
+func (s *service) UpdateAttributeProduct(ctx context.Context, id ulid.ULID, values []domain.AttributeInput) error {
+	assigned, err := s.assignValues(ctx, values)
+	if err != nil {
+		return err
+	}
+
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	for idx := range categoryIds {
-		prd, err := s.categoryRelationrepo.GetByProductIDCategoryID(ctx, id, categoryIds[idx])
+	for idx := range assigned {
+		err = s.attributeRepo.SaveProductValueWithTransaction(ctx, tx, id, &assigned[idx])
 		if err != nil {
 			if err := tx.Rollback(ctx); err != nil {
 				return err
 			}
 			return err
 		}
-		err = s.categoryRelationrepo.DeleteWithTransaction(ctx, tx, prd)
+	}
+	err = s.checkAttributeSet(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	s.indexer.ProductChanged(ctx, id)
+	return nil
+}
+
+func (s *service) DeleteAttributeProductBatch(ctx context.Context, id ulid.ULID, attributeIds []ulid.ULID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range attributeIds {
+		err = s.attributeRepo.DeleteProductValueWithTransaction(ctx, tx, id, attributeIds[idx])
 		if err != nil {
 			if err := tx.Rollback(ctx); err != nil {
 				return err
@@ -50,50 +157,354 @@ func (s *service) DeleteCategoryProductBatch(ctx context.Context, id ulid.ULID,
 			return err
 		}
 	}
+	err = s.checkAttributeSet(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		return err
 	}
+	s.indexer.ProductChanged(ctx, id)
 	return nil
 }
 
-func (s *service) UpdateCategoryProduct(ctx context.Context, id ulid.ULID, categoryIds []ulid.ULID) error {
+func (s *service) UpdateShippingProduct(ctx context.Context, id ulid.ULID, input domain.ShippingDTO) (domain.ShippingDTO, error) {
+	shipping, err := domain.NewShipping(
+		input.Weight,
+		input.Length,
+		input.Width,
+		input.Height,
+		input.WeightUnit,
+		input.DimensionUnit,
+		input.ShippingClass,
+	)
+	if err != nil {
+		return domain.ShippingDTO{}, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.ShippingDTO{}, err
+	}
+
+	currentPrd, err := s.repo.GetByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.ShippingDTO{}, err
+		}
+		return domain.ShippingDTO{}, err
+	}
+	currentPrd.Shipping = shipping
+
+	err = s.repo.EditShippingWithTransaction(ctx, tx, currentPrd)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.ShippingDTO{}, err
+		}
+		return domain.ShippingDTO{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.ShippingDTO{}, err
+	}
+	return *domain.NewShippingDTO(currentPrd.Shipping), nil
+}
+
+func (s *service) UpdateTagProduct(ctx context.Context, id ulid.ULID, tags []string) error {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	for idx := range categoryIds {
-		_, err := s.categoryRelationrepo.GetByProductIDCategoryID(ctx, id, categoryIds[idx])
+	for idx := range tags {
+		newTag, err := domain.NewTag(tags[idx])
 		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				newRelation, err := domain.NewRelationProductCategory(
-					id,
-					categoryIds[idx],
-				)
-				if err != nil {
-					if err := tx.Rollback(ctx); err != nil {
-						return err
-					}
-					return err
-				}
-				err = s.categoryRelationrepo.SaveWithTransaction(ctx, tx, &newRelation)
-				if err != nil {
-					if err := tx.Rollback(ctx); err != nil {
-						return err
-					}
-					return err
-				}
+			if err := tx.Rollback(ctx); err != nil {
+				return err
 			}
+			return err
+		}
+		err = s.tagRepo.SaveWithTransaction(ctx, tx, &newTag)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		err = s.tagRepo.SaveProductTagWithTransaction(ctx, tx, id, &newTag)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	s.indexer.ProductChanged(ctx, id)
+	return nil
+}
+
+func (s *service) DeleteTagProductBatch(ctx context.Context, id ulid.ULID, tags []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range tags {
+		err = s.tagRepo.DeleteProductTagWithTransaction(ctx, tx, id, tags[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	s.indexer.ProductChanged(ctx, id)
+	return nil
+}
+
+func (s *service) GetRelatedProducts(ctx context.Context, id ulid.ULID, relType domain.RelationType, locales []string) ([]domain.RelatedProductDTO, error) {
+	if relType != "" && !relType.Valid() {
+		return nil, domain.ErrInvalidRelationType
+	}
+	relations, err := s.productRelationRepo.GetByProductID(ctx, id, relType)
+	if err != nil {
+		return nil, err
+	}
+	var related = make([]domain.RelatedProductDTO, 0, len(relations))
+	for idx := range relations {
+		buf := domain.RelatedProductDTO{
+			ProductDTO: domain.ProductDTO{
+				ID:          relations[idx].RelatedProduct.ProductID,
+				Name:        relations[idx].RelatedProduct.Name,
+				Description: relations[idx].RelatedProduct.Description,
+				Price:       relations[idx].RelatedProduct.Price,
+				Image:       relations[idx].RelatedProduct.ImageURL(),
+			},
+			Type:     relations[idx].Type,
+			Position: relations[idx].Position,
+		}
+		related = append(related, buf)
+	}
+	ids := make([]ulid.ULID, len(related))
+	for idx := range related {
+		ids[idx] = related[idx].ID
+	}
+	translated, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityProduct, ids, locales)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range related {
+		if tr, ok := translated[related[idx].ID]; ok {
+			tr.Apply(&related[idx].Name, &related[idx].Description)
+		}
+	}
+	return related, nil
+}
+
+// saveRelation save the relation and keep its reverse in line. A
+// bidirectional relation save the mirrored one too, an unidirectional one
+// remove the mirror left when the relation was bidirectional.
+func (s *service) saveRelation(ctx context.Context, tx pgx.Tx, rel *domain.ProductRelation) error {
+	err := s.productRelationRepo.SaveWithTransaction(ctx, tx, rel)
+	if err != nil {
+		return err
+	}
+	if rel.Bidirectional {
+		reverse, err := rel.Reverse()
+		if err != nil {
+			return err
+		}
+		return s.productRelationRepo.SaveWithTransaction(ctx, tx, &reverse)
+	}
+	reverse, err := s.productRelationRepo.GetByProductIDRelatedIDWithTransaction(ctx, tx, rel.RelatedProduct.ProductID, rel.Product.ProductID, rel.Type)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if !reverse.Bidirectional {
+		return nil
+	}
+	return s.productRelationRepo.DeleteWithTransaction(ctx, tx, reverse)
+}
+
+func (s *service) UpdateRelatedProduct(ctx context.Context, id ulid.ULID, relations []RelatedInput) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range relations {
+		newRelation, err := domain.NewRelationProduct(
+			id,
+			relations[idx].ID,
+			relations[idx].Type,
+			relations[idx].Position,
+			relations[idx].Bidirectional,
+		)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		err = s.saveRelation(ctx, tx, &newRelation)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *service) DeleteRelatedProductBatch(ctx context.Context, id ulid.ULID, relations []RelatedInput) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range relations {
+		rel, err := s.productRelationRepo.GetByProductIDRelatedIDWithTransaction(ctx, tx, id, relations[idx].ID, relations[idx].Type)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		err = s.productRelationRepo.DeleteWithTransaction(ctx, tx, rel)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		if !rel.Bidirectional {
 			continue
 		}
+		reverse, err := s.productRelationRepo.GetByProductIDRelatedIDWithTransaction(ctx, tx, relations[idx].ID, id, relations[idx].Type)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		err = s.productRelationRepo.DeleteWithTransaction(ctx, tx, reverse)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateCategoryProduct implements Service. The removed categories are
+// dropped before the added one are saved, the attribute set is checked
+// against the categories the product end with.
+func (s *service) UpdateCategoryProduct(ctx context.Context, id ulid.ULID, added, removed []ulid.ULID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range removed {
+		prd, err := s.categoryRelationrepo.GetByProductIDCategoryIDWithTransaction(ctx, tx, id, removed[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		err = s.categoryRelationrepo.DeleteWithTransaction(ctx, tx, prd)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+	err = s.saveCategoryAttribute(ctx, tx, id, added, nil)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+	err = s.checkAttributeSet(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		return err
 	}
+	s.indexer.ProductChanged(ctx, id)
+	return nil
+}
+
+// saveCategoryAttribute add the categories the product do not have yet
+// and save the attribute values.
+func (s *service) saveCategoryAttribute(ctx context.Context, tx pgx.Tx, id ulid.ULID, categoryIds []ulid.ULID, assigned []domain.AssignedAttribute) error {
+	for idx := range categoryIds {
+		_, err := s.categoryRelationrepo.GetByProductIDCategoryIDWithTransaction(ctx, tx, id, categoryIds[idx])
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		newRelation, err := domain.NewRelationProductCategory(id, categoryIds[idx])
+		if err != nil {
+			return err
+		}
+		err = s.categoryRelationrepo.SaveWithTransaction(ctx, tx, &newRelation)
+		if err != nil {
+			return err
+		}
+	}
+	for idx := range assigned {
+		err := s.attributeRepo.SaveProductValueWithTransaction(ctx, tx, id, &assigned[idx])
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -124,31 +535,193 @@ func (s *service) DeleteProduct(ctx context.Context, id ulid.ULID) error {
 	if err != nil {
 		return err
 	}
+	s.indexer.ProductChanged(ctx, id)
 	return nil
 }
 
-func (s *service) GetProductsByCursor(ctx context.Context, limit int, cursor string) (res []domain.ProductDetailDTO, length int, nextCursor string, err error) {
-	prd, nextCursor, err := s.repo.GetByCursor(ctx, limit, cursor)
+// GetProductVariants implements Service. It list the variants of the
+// product like the variant listing filtered on main_id, an unknown product
+// return pgx.ErrNoRows.
+func (s *service) GetProductVariants(ctx context.Context, id ulid.ULID, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort, locales []string, include domain.Includes) (res []domain.VariantDetailDTO, length int, page domain.Page, err error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return []domain.VariantDetailDTO{}, 0, domain.Page{}, err
+	}
+	filter.MainID = id
+	return s.variantSvc.GetVariantsByCursor(ctx, req, filter, sort, locales, include)
+}
+
+// GetProductsByCursor implements Service. Products only carry their own
+// fields, relations in include are loaded for the whole page at once.
+func (s *service) GetProductsByCursor(ctx context.Context, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort, locales []string, include domain.Includes) (res []domain.ProductDetailDTO, length int, page domain.Page, err error) {
+	prd, page, err := s.repo.GetByCursor(ctx, req, filter, sort)
 	if err != nil {
-		return []domain.ProductDetailDTO{}, 0, "", err
+		return []domain.ProductDetailDTO{}, 0, domain.Page{}, err
 	}
 	dataLen := len(prd)
 	if dataLen == 0 {
-		return []domain.ProductDetailDTO{}, 0, "", nil
+		return []domain.ProductDetailDTO{}, 0, page, nil
 	}
 	var data = make([]domain.ProductDetailDTO, dataLen)
 	for i := range prd {
 		data[i].ID = prd[i].ProductID
 		data[i].Name = prd[i].Name
 		data[i].Description = prd[i].Description
-		data[i].Image = prd[i].ImagePreview
 		data[i].Price = prd[i].Price
+		if include.Has(domain.IncludeImages) {
+			data[i].Image = prd[i].ImageURL()
+		}
 	}
-	return data, dataLen, nextCursor, nil
+	ids := make([]ulid.ULID, dataLen)
+	for i := range prd {
+		ids[i] = prd[i].ProductID
+	}
+	translated, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityProduct, ids, locales)
+	if err != nil {
+		return []domain.ProductDetailDTO{}, 0, domain.Page{}, err
+	}
+	for i := range data {
+		if tr, ok := translated[data[i].ID]; ok {
+			tr.Apply(&data[i].Name, &data[i].Description)
+		}
+	}
+	if include.Has(domain.IncludeCategories) {
+		if err = s.expandCategories(ctx, data, ids, locales); err != nil {
+			return []domain.ProductDetailDTO{}, 0, domain.Page{}, err
+		}
+	}
+	if include.Has(domain.IncludeAttributes) {
+		if err = s.expandAttributes(ctx, data, ids); err != nil {
+			return []domain.ProductDetailDTO{}, 0, domain.Page{}, err
+		}
+	}
+	if include.Has(domain.IncludeImages) {
+		if err = s.expandImages(ctx, data, ids); err != nil {
+			return []domain.ProductDetailDTO{}, 0, domain.Page{}, err
+		}
+	}
+	if include.Has(domain.IncludeVariants) {
+		if err = s.expandVariants(ctx, data, ids, locales); err != nil {
+			return []domain.ProductDetailDTO{}, 0, domain.Page{}, err
+		}
+	}
+	return data, dataLen, page, nil
+}
+
+// expandCategories set the translated categories of every product of data,
+// ids are the product ids in the same order.
+func (s *service) expandCategories(ctx context.Context, data []domain.ProductDetailDTO, ids []ulid.ULID, locales []string) error {
+	relations, err := s.categoryRelationrepo.GetByProductIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	var categoryIds []ulid.ULID
+	for _, rels := range relations {
+		for idx := range rels {
+			categoryIds = append(categoryIds, rels[idx].Category.CategoryID)
+		}
+	}
+	translated, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityCategory, categoryIds, locales)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		rels := relations[ids[i]]
+		categories := make([]domain.CategoriesDTO, len(rels))
+		for idx := range rels {
+			categories[idx] = domain.CategoriesDTO{
+				ID:          rels[idx].Category.CategoryID,
+				Name:        rels[idx].Category.Name,
+				Description: rels[idx].Category.Description,
+			}
+			if tr, ok := translated[categories[idx].ID]; ok {
+				tr.Apply(&categories[idx].Name, &categories[idx].Description)
+			}
+		}
+		data[i].Category = categories
+	}
+	return nil
+}
+
+func (s *service) expandAttributes(ctx context.Context, data []domain.ProductDetailDTO, ids []ulid.ULID) error {
+	attributes, err := s.attributeRepo.GetByProductIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		data[i].Attribute = domain.NewAssignedAttributesDTO(attributes[ids[i]])
+	}
+	return nil
+}
+
+// expandImages set the gallery of every product, product with a gallery
+// is served its primary image.
+func (s *service) expandImages(ctx context.Context, data []domain.ProductDetailDTO, ids []ulid.ULID) error {
+	images, err := s.imageRepo.GetByEntityIDs(ctx, domain.EntityProduct, ids)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		if gallery := images[ids[i]]; len(gallery) > 0 {
+			data[i].Images = domain.NewImagesDTO(gallery)
+			data[i].Image = domain.ProductImageURL(ids[i])
+		}
+	}
+	return nil
+}
+
+// expandVariants set the translated variants of every product, oldest
+// first.
+func (s *service) expandVariants(ctx context.Context, data []domain.ProductDetailDTO, ids []ulid.ULID, locales []string) error {
+	variants, err := s.variantRepo.GetByMainProductIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	var variantIds []ulid.ULID
+	for _, list := range variants {
+		for idx := range list {
+			variantIds = append(variantIds, list[idx].VariantID)
+		}
+	}
+	translated, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityVariant, variantIds, locales)
+	if err != nil {
+		return err
+	}
+	variantImages, err := s.imageRepo.GetByEntityIDs(ctx, domain.EntityVariant, variantIds)
+	if err != nil {
+		return err
+	}
+	productImages, err := s.imageRepo.GetByEntityIDs(ctx, domain.EntityProduct, ids)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		list := variants[ids[i]]
+		res := make([]domain.VariantDTO, len(list))
+		for idx := range list {
+			res[idx] = domain.NewVariantDTO(list[idx])
+			res[idx].MainProductName = data[i].Name
+			// variant show its gallery, else the one of its main product,
+			// else the main product image
+			res[idx].Image = list[idx].ImageURL()
+			if len(variantImages[list[idx].VariantID]) > 0 || len(productImages[ids[i]]) > 0 {
+				res[idx].Image = domain.VariantImageURL(list[idx].VariantID)
+			}
+			if tr, ok := translated[res[idx].ID]; ok {
+				tr.Apply(&res[idx].Name, &res[idx].Description)
+			}
+		}
+		data[i].Variants = res
+	}
+	return nil
 }
 
 // CreateProduct implements Service.
-func (s *service) UpdateDataProduct(ctx context.Context, id ulid.ULID, name, desc string, price float64) (domain.ProductDTO, error) {
+func (s *service) UpdateDataProduct(ctx context.Context, id ulid.ULID, name, desc string, price float64, brandId ulid.ULID, categoryIds []ulid.ULID, values []domain.AttributeInput) (domain.ProductDTO, error) {
+	assigned, err := s.assignValues(ctx, values)
+	if err != nil {
+		return domain.ProductDTO{}, err
+	}
+
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return domain.ProductDTO{}, err
@@ -165,6 +738,7 @@ func (s *service) UpdateDataProduct(ctx context.Context, id ulid.ULID, name, des
 	currentPrd.Name = name
 	currentPrd.Description = desc
 	currentPrd.Price = price
+	currentPrd.Brand.BrandID = brandId
 
 	err = s.repo.EditWithTransaction(ctx, tx, currentPrd)
 	if err != nil {
@@ -174,24 +748,44 @@ func (s *service) UpdateDataProduct(ctx context.Context, id ulid.ULID, name, des
 		return domain.ProductDTO{}, err
 	}
 
+	err = s.saveCategoryAttribute(ctx, tx, id, categoryIds, assigned)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.ProductDTO{}, err
+		}
+		return domain.ProductDTO{}, err
+	}
+	err = s.checkAttributeSet(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.ProductDTO{}, err
+		}
+		return domain.ProductDTO{}, err
+	}
+
 	res := domain.ProductDTO{
 		ID:          currentPrd.ProductID,
 		Name:        currentPrd.Name,
 		Description: currentPrd.Description,
 		Price:       currentPrd.Price,
-		Image:       currentPrd.ImagePreview,
+		Image:       currentPrd.ImageURL(),
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		return domain.ProductDTO{}, err
 	}
+	s.indexer.ProductChanged(ctx, id)
 	return res, nil
 }
 
 // CreateProduct implements Service.
-func (s *service) CreateProduct(ctx context.Context, name, desc string, price float64) (domain.ProductDTO, error) {
-	newPrd, err := domain.NewProduct(name, desc, price)
+func (s *service) CreateProduct(ctx context.Context, name, desc string, price float64, brandId ulid.ULID, categoryIds []ulid.ULID, values []domain.AttributeInput) (domain.ProductDTO, error) {
+	newPrd, err := domain.NewProduct(name, desc, price, brandId)
+	if err != nil {
+		return domain.ProductDTO{}, err
+	}
+	assigned, err := s.assignValues(ctx, values)
 	if err != nil {
 		return domain.ProductDTO{}, err
 	}
@@ -209,27 +803,52 @@ func (s *service) CreateProduct(ctx context.Context, name, desc string, price fl
 		return domain.ProductDTO{}, err
 	}
 
+	err = s.saveCategoryAttribute(ctx, tx, newPrd.ProductID, categoryIds, assigned)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.ProductDTO{}, err
+		}
+		return domain.ProductDTO{}, err
+	}
+	err = s.checkAttributeSet(ctx, tx, newPrd.ProductID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.ProductDTO{}, err
+		}
+		return domain.ProductDTO{}, err
+	}
+
 	res := domain.ProductDTO{
 		ID:          newPrd.ProductID,
 		Name:        newPrd.Name,
 		Description: newPrd.Description,
 		Price:       newPrd.Price,
-		Image:       newPrd.ImagePreview,
+		Image:       newPrd.ImageURL(),
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		return domain.ProductDTO{}, err
 	}
+	s.indexer.ProductChanged(ctx, newPrd.ProductID)
 	return res, nil
 }
 
 // GetProductByID implements Service.
-func (s *service) GetProductByID(ctx context.Context, id ulid.ULID) (domain.ProductDetailDTO, error) {
+// Categories, attributes and images are always read, variants only when
+// include ask for them.
+func (s *service) GetProductByID(ctx context.Context, id ulid.ULID, locales []string, include domain.Includes) (domain.ProductDetailDTO, error) {
 	prd, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return domain.ProductDetailDTO{}, err
 	}
+	translated, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityProduct, []ulid.ULID{id}, locales)
+	if err != nil {
+		return domain.ProductDetailDTO{}, err
+	}
+	if tr, ok := translated[id]; ok {
+		tr.Apply(&prd.Name, &prd.Description)
+	}
 	category, err := s.categoryRelationrepo.GetByProductID(ctx, id)
 	if err != nil {
 		return domain.ProductDetailDTO{}, err
@@ -243,22 +862,106 @@ func (s *service) GetProductByID(ctx context.Context, id ulid.ULID) (domain.Prod
 		}
 		categories = append(categories, buf)
 	}
+	categoryIds := make([]ulid.ULID, len(categories))
+	for idx := range categories {
+		categoryIds[idx] = categories[idx].ID
+	}
+	translatedCategories, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityCategory, categoryIds, locales)
+	if err != nil {
+		return domain.ProductDetailDTO{}, err
+	}
+	for idx := range categories {
+		if tr, ok := translatedCategories[categories[idx].ID]; ok {
+			tr.Apply(&categories[idx].Name, &categories[idx].Description)
+		}
+	}
+	related, err := s.GetRelatedProducts(ctx, id, "", locales)
+	if err != nil {
+		return domain.ProductDetailDTO{}, err
+	}
+	tags, err := s.tagRepo.GetByProductID(ctx, id)
+	if err != nil {
+		return domain.ProductDetailDTO{}, err
+	}
+	var tagNames = make([]string, 0, len(tags))
+	for idx := range tags {
+		tagNames = append(tagNames, tags[idx].Name)
+	}
+	attributes, err := s.attributeRepo.GetByProductID(ctx, id)
+	if err != nil {
+		return domain.ProductDetailDTO{}, err
+	}
+	images, err := s.imageRepo.GetByEntityID(ctx, domain.EntityProduct, id)
+	if err != nil {
+		return domain.ProductDetailDTO{}, err
+	}
+	imageURL := prd.ImageURL()
+	if len(images) > 0 {
+		imageURL = domain.ProductImageURL(id)
+	}
+	summary, err := s.variantRepo.GetSummaryByMainProductID(ctx, id)
+	if err != nil {
+		return domain.ProductDetailDTO{}, err
+	}
+	var brandDTO *domain.BrandsDTO
+	if prd.Brand.BrandID != (ulid.ULID{}) {
+		brd, err := s.brandRepo.GetByID(ctx, prd.Brand.BrandID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return domain.ProductDetailDTO{}, err
+		}
+		if err == nil {
+			brandDTO = &domain.BrandsDTO{
+				ID:          brd.BrandID,
+				Name:        brd.Name,
+				Description: brd.Description,
+			}
+		}
+	}
 	res := domain.ProductDetailDTO{
 		ProductDTO: domain.ProductDTO{
 			ID:          id,
 			Name:        prd.Name,
 			Description: prd.Description,
 			Price:       prd.Price,
-			Image:       prd.ImagePreview,
+			Image:       imageURL,
 		},
-		Category:  categories,
-		Attribute: []domain.AttributesDTO{},
+		Category:       categories,
+		Attribute:      domain.NewAssignedAttributesDTO(attributes),
+		Related:        related,
+		Tags:           tagNames,
+		Brand:          brandDTO,
+		Shipping:       domain.NewShippingDTO(prd.Shipping),
+		Images:         domain.NewImagesDTO(images),
+		VariantSummary: &summary,
+	}
+	if include.Has(domain.IncludeVariants) {
+		data := []domain.ProductDetailDTO{res}
+		if err = s.expandVariants(ctx, data, []ulid.ULID{id}, locales); err != nil {
+			return domain.ProductDetailDTO{}, err
+		}
+		res = data[0]
 	}
 	return res, nil
 }
 
 // CreateProduct implements Service.
-func (s *service) UpdateImageProduct(ctx context.Context, id ulid.ULID, image []byte) (domain.ProductDTO, error) {
+func (s *service) UpdateImageProduct(ctx context.Context, id ulid.ULID, data []byte) (domain.ProductDTO, error) {
+	blob, err := s.blobs.Store(ctx, data)
+	if err != nil {
+		return domain.ProductDTO{}, err
+	}
+
+	res, err := s.editImage(ctx, id, blob)
+	if err != nil {
+		image.Discard(ctx, s.imageRepo, s.blobs, blob)
+		return domain.ProductDTO{}, err
+	}
+	s.indexer.ProductChanged(ctx, id)
+	return res, nil
+}
+
+// editImage set the stored blob as the product image.
+func (s *service) editImage(ctx context.Context, id ulid.ULID, blob domain.Blob) (domain.ProductDTO, error) {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return domain.ProductDTO{}, err
@@ -270,9 +973,9 @@ func (s *service) UpdateImageProduct(ctx context.Context, id ulid.ULID, image []
 		}
 		return domain.ProductDTO{}, err
 	}
-	currentPrd.ImagePreview = image
+	currentPrd.Image = blob
 
-	err = s.repo.EditWithTransaction(ctx, tx, currentPrd)
+	err = s.repo.EditImageWithTransaction(ctx, tx, currentPrd)
 	if err != nil {
 		if err := tx.Rollback(ctx); err != nil {
 			return domain.ProductDTO{}, err
@@ -285,7 +988,7 @@ func (s *service) UpdateImageProduct(ctx context.Context, id ulid.ULID, image []
 		Name:        currentPrd.Name,
 		Description: currentPrd.Description,
 		Price:       currentPrd.Price,
-		Image:       currentPrd.ImagePreview,
+		Image:       currentPrd.ImageURL(),
 	}
 
 	err = tx.Commit(ctx)
@@ -295,14 +998,82 @@ func (s *service) UpdateImageProduct(ctx context.Context, id ulid.ULID, image []
 	return res, nil
 }
 
+// GetImageProduct implements Service. The primary gallery image take over
+// the product preview.
+func (s *service) GetImageProduct(ctx context.Context, id ulid.ULID, rendition string, webp bool) (domain.ImageContent, error) {
+	prd, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.ImageContent{}, err
+	}
+	images, err := s.imageRepo.GetByEntityID(ctx, domain.EntityProduct, id)
+	if err != nil {
+		return domain.ImageContent{}, err
+	}
+	if primary, ok := domain.PrimaryImage(images); ok {
+		return s.blobs.Open(ctx, primary.Blob, primary.Data, rendition, webp)
+	}
+	return s.blobs.Open(ctx, prd.Image, prd.ImagePreview, rendition, webp)
+}
+
+// GetProductFacets implements Service. Only products are counted, as in
+// the product listing.
+func (s *service) GetProductFacets(ctx context.Context, filter domain.ListFilter) (domain.Facets, error) {
+	return s.facetRepo.Count(ctx, s.facetScope, filter)
+}
+
+// GetDuplicateProducts implements Service.
+func (s *service) GetDuplicateProducts(ctx context.Context, distance, limit int) ([]domain.DuplicateProductDTO, error) {
+	if distance < 0 || distance > domain.MaxImageDistance {
+		return nil, domain.ErrInvalidImageDistance
+	}
+	if limit <= 0 {
+		return nil, domain.ErrInvalidDuplicateLimit
+	}
+	dups, err := s.repo.GetDuplicates(ctx, distance, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.DuplicateProductDTO, 0, len(dups))
+	for idx := range dups {
+		res = append(res, domain.NewDuplicateProductDTO(dups[idx]))
+	}
+	return res, nil
+}
+
 func NewService(
 	repo Repo,
 	categoryRelationrepo product_category.Repo,
+	productRelationRepo product_relation.Repo,
+	tagRepo tag.Repo,
+	brandRepo brand.Repo,
+	translationRepo translation.Repo,
+	attributeRepo attribute.Repo,
+	categoryAttributeRepo category_attribute.Repo,
+	imageRepo image.Repo,
+	variantRepo variant.Repo,
+	variantSvc variant.Service,
+	facetRepo facet.Repo,
+	facetScope domain.FacetScope,
+	blobs *image.BlobStore,
+	indexer search.Indexer,
 	db *pgxpool.Pool,
 ) Service {
 	return &service{
-		repo:                 repo,
-		db:                   db,
-		categoryRelationrepo: categoryRelationrepo,
+		repo:                  repo,
+		db:                    db,
+		categoryRelationrepo:  categoryRelationrepo,
+		productRelationRepo:   productRelationRepo,
+		tagRepo:               tagRepo,
+		brandRepo:             brandRepo,
+		translationRepo:       translationRepo,
+		attributeRepo:         attributeRepo,
+		categoryAttributeRepo: categoryAttributeRepo,
+		imageRepo:             imageRepo,
+		variantRepo:           variantRepo,
+		variantSvc:            variantSvc,
+		facetRepo:             facetRepo,
+		facetScope:            facetScope,
+		blobs:                 blobs,
+		indexer:               indexer,
 	}
 }
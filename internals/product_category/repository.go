@@ -4,6 +4,7 @@ import (
 	"context"
 	"flukis/product/domain"
 	"flukis/product/utils/helper"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -19,16 +20,18 @@ type Repo interface {
 	GetByProductIDCategoryID(ctx context.Context, productId, categoryId ulid.ULID) (*domain.ProductCategory, error)
 	GetByProductIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) ([]domain.ProductCategory, error)
 	GetByProductID(ctx context.Context, id ulid.ULID) ([]domain.ProductCategory, error)
+	GetByProductIDs(ctx context.Context, ids []ulid.ULID) (map[ulid.ULID][]domain.ProductCategory, error)
 	SaveWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.ProductCategory) error
 	EditWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.ProductCategory) error
 	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.ProductCategory) error
-	GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.ProductCategory, string, error)
+	GetByCursor(ctx context.Context, req domain.PageRequest) ([]domain.ProductCategory, domain.Page, error)
 	DeleteCategoryWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.ProductCategory) error
 	DeleteProductWithTransaction(ctx context.Context, tx pgx.Tx, prd *domain.ProductCategory) error
 }
 
 type repo struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	cursors *helper.Cursors
 }
 
 // GetByID implements Repo.
@@ -209,6 +212,55 @@ func (r *repo) GetByProductID(ctx context.Context, id ulid.ULID) ([]domain.Produ
 	return relations, nil
 }
 
+// GetByProductIDs implements Repo. It return the categories of every
+// product in one query, keyed by product id. Only the category part is
+// read.
+func (r *repo) GetByProductIDs(ctx context.Context, ids []ulid.ULID) (map[ulid.ULID][]domain.ProductCategory, error) {
+	res := make(map[ulid.ULID][]domain.ProductCategory)
+	if len(ids) == 0 {
+		return res, nil
+	}
+	query := `
+		SELECT
+			pc.product_category_id,
+			pc.product_id,
+			c.category_id,
+			c.name AS category_name,
+			c.description AS category_description
+		FROM Product_Category pc
+		JOIN Category c ON pc.category_id = c.category_id
+		WHERE pc.product_id = ANY($1)
+			AND pc.deleted_at IS NULL
+			AND c.deleted_at IS NULL
+		ORDER BY
+			c.name
+	`
+	rawIds := make([][]byte, len(ids))
+	for i := range ids {
+		rawIds[i] = ids[i][:]
+	}
+	rows, err := r.db.Query(ctx, query, rawIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pc domain.ProductCategory
+		if err := rows.Scan(
+			&pc.ProductCategoryID,
+			&pc.Product.ProductID,
+			&pc.Category.CategoryID,
+			&pc.Category.Name,
+			&pc.Category.Description,
+		); err != nil {
+			return nil, err
+		}
+		res[pc.Product.ProductID] = append(res[pc.Product.ProductID], pc)
+	}
+	return res, rows.Err()
+}
+
 // GetByID implements Repo.
 func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) (*domain.ProductCategory, error) {
 	query := `
@@ -388,8 +440,20 @@ func (*repo) DeleteProductWithTransaction(ctx context.Context, tx pgx.Tx, prd *d
 	return nil
 }
 
-func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.ProductCategory, string, error) {
-	query := `
+// listScope bind the cursors of GetByCursor to this listing, they are
+// refused by the other listings.
+const listScope = "product_category"
+
+// GetByCursor implements Repo. Oldest assignment first, page after or
+// before cursor.
+func (r *repo) GetByCursor(ctx context.Context, req domain.PageRequest) ([]domain.ProductCategory, domain.Page, error) {
+	decodedCursor, after, err := r.cursors.DecodeSort(req.Cursor, domain.CreatedSort, listScope)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	compare, direction := domain.CreatedSort.Keyset(decodedCursor)
+	query := fmt.Sprintf(`
 		SELECT
 			pc.product_category_id,
 			p.product_id,
@@ -399,26 +463,22 @@ func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]dom
 			p.image_preview,
 			c.category_id,
 			c.name AS category_name,
-			c.description AS category_description
+			c.description AS category_description,
+			pc.created_at
 		FROM Product_Category pc
 		JOIN Product p ON pc.product_id = p.product_id
 		JOIN Category c ON pc.category_id = c.category_id
-		WHERE pc.product_category_id = $1
-		AND pc.deleted_at IS NULL
-			pc.created_at > $1 AND pc.deleted_at IS NULL
+		WHERE
+			($2::timestamp IS NULL OR (pc.created_at, pc.product_category_id) %s ($2, $3))
+			AND pc.deleted_at IS NULL
 		ORDER BY
-			pc.created_at
-		LIMIT $2
-	`
-	decodedCursor, err := helper.DecodeCursor(cursor)
-	if err != nil && cursor != "" {
-		log.Warn().Err(err).Msg("failed to decode cursor")
-		return nil, "", err
-	}
+			pc.created_at %s, pc.product_category_id %s
+		LIMIT $1 OFFSET $4
+	`, compare, direction, direction)
 
-	rows, err := r.db.Query(ctx, query, decodedCursor, limit)
+	rows, err := r.db.Query(ctx, query, req.Limit+1, after, decodedCursor.ID, req.Offset())
 	if err != nil {
-		return nil, "", err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
@@ -435,22 +495,39 @@ func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]dom
 			&product.Category.CategoryID,
 			&product.Category.Name,
 			&product.Category.Description,
+			&product.CreatedAt,
 		); err != nil {
-			return nil, "", err
+			return nil, domain.Page{}, err
 		}
 		products = append(products, product)
 	}
-
-	nextCursor := ""
-	if len(products) == limit {
-		nextCursor = helper.EncodeCursor(products[len(products)-1].CreatedAt)
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
 	}
 
-	return products, nextCursor, nil
+	var page domain.Page
+	if req.OffsetMode() {
+		products, page = helper.OffsetPage(products, req.Limit)
+	} else {
+		products, page = helper.CursorPage(r.cursors, listScope, products, req.Limit, decodedCursor, func(item domain.ProductCategory) domain.ListCursor {
+			return domain.NewCreatedCursor(item.CreatedAt, item.ProductCategoryID)
+		})
+	}
+	if err := helper.PageTotal(ctx, r.db, req, &page, `
+		SELECT pc.product_category_id
+		FROM Product_Category pc
+		JOIN Product p ON pc.product_id = p.product_id
+		JOIN Category c ON pc.category_id = c.category_id
+		WHERE pc.deleted_at IS NULL
+	`); err != nil {
+		return nil, domain.Page{}, err
+	}
+	return products, page, nil
 }
 
-func NewRepo(db *pgxpool.Pool) Repo {
+func NewRepo(db *pgxpool.Pool, cursors *helper.Cursors) Repo {
 	return &repo{
-		db: db,
+		db:      db,
+		cursors: cursors,
 	}
 }
@@ -0,0 +1,179 @@
+package product_relation
+
+import (
+	"context"
+	"errors"
+	"flukis/product/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+)
+
+type Repo interface {
+	GetByProductIDRelatedIDWithTransaction(ctx context.Context, tx pgx.Tx, productId, relatedId ulid.ULID, relType domain.RelationType) (*domain.ProductRelation, error)
+	GetByProductID(ctx context.Context, id ulid.ULID, relType domain.RelationType) ([]domain.ProductRelation, error)
+	SaveWithTransaction(ctx context.Context, tx pgx.Tx, rel *domain.ProductRelation) error
+	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, rel *domain.ProductRelation) error
+}
+
+type repo struct {
+	db *pgxpool.Pool
+}
+
+// GetByProductIDRelatedIDWithTransaction implements Repo.
+func (*repo) GetByProductIDRelatedIDWithTransaction(ctx context.Context, tx pgx.Tx, productId, relatedId ulid.ULID, relType domain.RelationType) (*domain.ProductRelation, error) {
+	query := `
+		SELECT
+			product_relation_id,
+			product_id,
+			related_product_id,
+			relation_type,
+			position,
+			bidirectional
+		FROM Product_Relation
+		WHERE product_id = $1
+			AND related_product_id = $2
+			AND relation_type = $3
+			AND deleted_at IS NULL
+	`
+	row := tx.QueryRow(
+		ctx,
+		query,
+		productId,
+		relatedId,
+		relType,
+	)
+	var rel domain.ProductRelation
+	if err := row.Scan(
+		&rel.ProductRelationID,
+		&rel.Product.ProductID,
+		&rel.RelatedProduct.ProductID,
+		&rel.Type,
+		&rel.Position,
+		&rel.Bidirectional,
+	); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// GetByProductID implements Repo. Empty relType return every type.
+func (r *repo) GetByProductID(ctx context.Context, id ulid.ULID, relType domain.RelationType) ([]domain.ProductRelation, error) {
+	query := `
+		SELECT
+			pr.product_relation_id,
+			pr.product_id,
+			pr.relation_type,
+			pr.position,
+			pr.bidirectional,
+			p.product_id,
+			p.name AS product_name,
+			p.description AS product_description,
+			p.price,
+			p.image_preview,
+			COALESCE(p.image_key, '')
+		FROM Product_Relation pr
+		JOIN Product p ON pr.related_product_id = p.product_id
+		WHERE pr.product_id = $1
+			AND ($2 = '' OR pr.relation_type = $2)
+			AND pr.deleted_at IS NULL
+			AND p.deleted_at IS NULL
+		ORDER BY
+			pr.relation_type, pr.position, pr.created_at
+	`
+	rows, err := r.db.Query(ctx, query, id, relType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relations []domain.ProductRelation
+	for rows.Next() {
+		var rel domain.ProductRelation
+		if err := rows.Scan(
+			&rel.ProductRelationID,
+			&rel.Product.ProductID,
+			&rel.Type,
+			&rel.Position,
+			&rel.Bidirectional,
+			&rel.RelatedProduct.ProductID,
+			&rel.RelatedProduct.Name,
+			&rel.RelatedProduct.Description,
+			&rel.RelatedProduct.Price,
+			&rel.RelatedProduct.ImagePreview,
+			&rel.RelatedProduct.Image.Key,
+		); err != nil {
+			return nil, err
+		}
+		relations = append(relations, rel)
+	}
+	return relations, rows.Err()
+}
+
+// SaveWithTransaction implements Repo. A product has one relation per
+// related product and type, saving again replace the position and
+// direction, rel.ProductRelationID is set to the stored one. It return
+// ErrRelationProduct when one of the product does not exist.
+func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, rel *domain.ProductRelation) error {
+	query := `
+		INSERT INTO Product_Relation
+			(product_relation_id, product_id, related_product_id, relation_type, position, bidirectional, created_at)
+		SELECT
+			$1, $2, $3, $4, $5, $6, $7
+		WHERE (
+			SELECT COUNT(*)
+			FROM Product
+			WHERE product_id IN ($2, $3) AND deleted_at IS NULL
+		) = 2
+		ON CONFLICT (product_id, related_product_id, relation_type) DO UPDATE SET
+			position = EXCLUDED.position,
+			bidirectional = EXCLUDED.bidirectional,
+			updated_at = EXCLUDED.created_at,
+			deleted_at = NULL
+		RETURNING product_relation_id
+	`
+	if err := tx.QueryRow(
+		ctx,
+		query,
+		&rel.ProductRelationID,
+		&rel.Product.ProductID,
+		&rel.RelatedProduct.ProductID,
+		&rel.Type,
+		&rel.Position,
+		&rel.Bidirectional,
+		&rel.CreatedAt,
+	).Scan(&rel.ProductRelationID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrRelationProduct
+		}
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, rel *domain.ProductRelation) error {
+	query := `
+		UPDATE Product_Relation SET
+			deleted_at = $1
+		WHERE
+			product_relation_id = $2 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		&rel.ProductRelationID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewRepo(db *pgxpool.Pool) Repo {
+	return &repo{
+		db: db,
+	}
+}
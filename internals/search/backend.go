@@ -0,0 +1,61 @@
+package search
+
+import (
+	"context"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+// Backend answer product and variant search, handlers only see it through
+// Service so another engine can be plugged in.
+type Backend interface {
+	Search(ctx context.Context, q string, filter domain.ListFilter, limit int, cursor string) ([]domain.SearchHit, domain.Page, error)
+	// Replace drop every document of the products and index docs in their
+	// place, docs may be empty when the products are gone.
+	Replace(ctx context.Context, productIds []ulid.ULID, docs []domain.SearchDocument) error
+}
+
+type postgresBackend struct {
+	repo Repo
+}
+
+// Search implements Backend.
+func (b *postgresBackend) Search(ctx context.Context, q string, filter domain.ListFilter, limit int, cursor string) ([]domain.SearchHit, domain.Page, error) {
+	return b.repo.Search(ctx, q, filter, limit, cursor)
+}
+
+// Replace implements Backend. Nothing to do, search_vector is kept by
+// trigger in the same transaction as the change.
+func (*postgresBackend) Replace(ctx context.Context, productIds []ulid.ULID, docs []domain.SearchDocument) error {
+	return nil
+}
+
+func NewPostgresBackend(repo Repo) Backend {
+	return &postgresBackend{
+		repo: repo,
+	}
+}
+
+// NewBackend return the backend named kind and the indexer feeding it. The
+// memory backend is filled from the database before it is returned.
+func NewBackend(ctx context.Context, kind string, repo Repo, cursors *helper.Cursors) (Backend, Indexer, error) {
+	switch kind {
+	case "", BackendPostgres:
+		return NewPostgresBackend(repo), NewNopIndexer(), nil
+	case BackendMemory:
+		backend := NewMemoryBackend(cursors)
+		indexer := NewIndexer(repo, backend)
+		if err := indexer.Reindex(ctx); err != nil {
+			return nil, nil, err
+		}
+		return backend, indexer, nil
+	}
+	return nil, nil, domain.ErrUnknownSearchBackend
+}
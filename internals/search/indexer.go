@@ -0,0 +1,98 @@
+package search
+
+import (
+	"context"
+	"flukis/product/domain"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// Indexer is told by the product, variant and category services after a
+// change is committed and push the affected products to the backend. The
+// change is already saved, so failure is logged and not returned.
+type Indexer interface {
+	ProductChanged(ctx context.Context, ids ...ulid.ULID)
+	VariantChanged(ctx context.Context, ids ...ulid.ULID)
+	CategoryChanged(ctx context.Context, ids ...ulid.ULID)
+	Reindex(ctx context.Context) error
+}
+
+type indexer struct {
+	repo    Repo
+	backend Backend
+}
+
+// ProductChanged implements Indexer.
+func (i *indexer) ProductChanged(ctx context.Context, ids ...ulid.ULID) {
+	if err := i.index(ctx, ids); err != nil {
+		log.Error().Err(err).Msg("failed to index changed products")
+	}
+}
+
+// VariantChanged implements Indexer. The whole main product is indexed
+// again.
+func (i *indexer) VariantChanged(ctx context.Context, ids ...ulid.ULID) {
+	productIds, err := i.repo.GetProductIDsByVariants(ctx, ids)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find products of changed variants")
+		return
+	}
+	i.ProductChanged(ctx, productIds...)
+}
+
+// CategoryChanged implements Indexer. Every product of the categories is
+// indexed again since they carry the category names.
+func (i *indexer) CategoryChanged(ctx context.Context, ids ...ulid.ULID) {
+	productIds, err := i.repo.GetProductIDsByCategories(ctx, ids)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find products of changed categories")
+		return
+	}
+	i.ProductChanged(ctx, productIds...)
+}
+
+// Reindex implements Indexer. It load every product and variant.
+func (i *indexer) Reindex(ctx context.Context) error {
+	docs, err := i.repo.GetDocuments(ctx, nil)
+	if err != nil {
+		return err
+	}
+	productIds := make([]ulid.ULID, 0, len(docs))
+	for _, doc := range docs {
+		if doc.EntityType == domain.EntityProduct {
+			productIds = append(productIds, doc.ProductID)
+		}
+	}
+	return i.backend.Replace(ctx, productIds, docs)
+}
+
+func (i *indexer) index(ctx context.Context, productIds []ulid.ULID) error {
+	if len(productIds) == 0 {
+		return nil
+	}
+	docs, err := i.repo.GetDocuments(ctx, productIds)
+	if err != nil {
+		return err
+	}
+	return i.backend.Replace(ctx, productIds, docs)
+}
+
+func NewIndexer(repo Repo, backend Backend) Indexer {
+	return &indexer{
+		repo:    repo,
+		backend: backend,
+	}
+}
+
+// nopIndexer is used with backend that keep its index by itself.
+type nopIndexer struct{}
+
+func (nopIndexer) ProductChanged(ctx context.Context, ids ...ulid.ULID)  {}
+func (nopIndexer) VariantChanged(ctx context.Context, ids ...ulid.ULID)  {}
+func (nopIndexer) CategoryChanged(ctx context.Context, ids ...ulid.ULID) {}
+func (nopIndexer) Reindex(ctx context.Context) error                     { return nil }
+
+func NewNopIndexer() Indexer {
+	return nopIndexer{}
+}
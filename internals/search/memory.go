@@ -0,0 +1,321 @@
+package search
+
+import (
+	"context"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// field weights, same as the default weights of ts_rank_cd for A, B and C
+const (
+	weightName     = 1.0
+	weightRelated  = 0.4
+	weightDescribe = 0.2
+)
+
+// snippetWords is the number of description words kept around the first
+// match.
+const snippetWords = 20
+
+type memoryDoc struct {
+	doc   domain.SearchDocument
+	terms map[string]float64
+}
+
+// memoryBackend is an inverted index kept in process, for tests and small
+// catalog. Words are matched exactly after lowercasing, there is no
+// stemming, synonym or search rule. A "-word" exclude the documents having
+// it.
+type memoryBackend struct {
+	mu        sync.RWMutex
+	docs      map[ulid.ULID]*memoryDoc
+	byProduct map[ulid.ULID][]ulid.ULID
+	postings  map[string]map[ulid.ULID]float64
+	cursors   *helper.Cursors
+}
+
+// Search implements Backend. Every word of q must match, the score is the
+// sum of the field weights of the matched words.
+func (b *memoryBackend) Search(ctx context.Context, q string, filter domain.ListFilter, limit int, cursor string) ([]domain.SearchHit, domain.Page, error) {
+	scope := domain.SearchScope(q, filter)
+	decodedCursor, err := b.cursors.Decode(cursor, domain.SearchSort, scope)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	var (
+		from   bool
+		fromId ulid.ULID
+		rank   float64
+	)
+	if cursor != "" {
+		rank, err = decodedCursor.SearchRank()
+		if err != nil {
+			return nil, domain.Page{}, err
+		}
+		from = true
+		fromId = decodedCursor.ID
+	}
+	// a page before the cursor is read in reverse, best match last
+	before := decodedCursor.Before
+
+	include, exclude := parseMemoryQuery(q)
+	if len(include) == 0 {
+		return nil, domain.Page{}, nil
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// walk the shortest posting list, the others are looked up
+	shortest := include[0]
+	for _, term := range include[1:] {
+		if len(b.postings[term]) < len(b.postings[shortest]) {
+			shortest = term
+		}
+	}
+
+	var hits []domain.SearchHit
+	for id := range b.postings[shortest] {
+		d := b.docs[id]
+		score, ok := d.score(include, exclude)
+		if !ok || !d.doc.Matches(filter) {
+			continue
+		}
+		if from && !before && (score > rank || (score == rank && id.Compare(fromId) >= 0)) {
+			continue
+		}
+		if from && before && (score < rank || (score == rank && id.Compare(fromId) <= 0)) {
+			continue
+		}
+		hits = append(hits, domain.SearchHit{
+			EntityType:    d.doc.EntityType,
+			ID:            d.doc.ID,
+			ProductID:     d.doc.ProductID,
+			Name:          d.doc.Name,
+			Description:   d.doc.Description,
+			Price:         d.doc.Price,
+			HasImage:      d.doc.HasImage,
+			Score:         score,
+			Rank:          score,
+			NameHighlight: d.doc.Name,
+			Snippet:       d.doc.Description,
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return (hits[i].Rank > hits[j].Rank) != before
+		}
+		return (hits[i].ID.Compare(hits[j].ID) > 0) != before
+	})
+	if len(hits) > limit+1 {
+		hits = hits[:limit+1]
+	}
+
+	terms := make(map[string]bool, len(include))
+	for _, term := range include {
+		terms[term] = true
+	}
+	for i := range hits {
+		hits[i].NameHighlight = highlight(hits[i].Name, terms, 0)
+		hits[i].Snippet = highlight(hits[i].Description, terms, snippetWords)
+	}
+
+	hits, page := helper.CursorPage(b.cursors, scope, hits, limit, decodedCursor, domain.NewSearchCursor)
+	return hits, page, nil
+}
+
+// Replace implements Backend.
+func (b *memoryBackend) Replace(ctx context.Context, productIds []ulid.ULID, docs []domain.SearchDocument) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, productId := range productIds {
+		for _, id := range append([]ulid.ULID(nil), b.byProduct[productId]...) {
+			b.remove(id)
+		}
+	}
+	for _, doc := range docs {
+		b.remove(doc.ID)
+		d := &memoryDoc{
+			doc:   doc,
+			terms: documentTerms(doc),
+		}
+		b.docs[doc.ID] = d
+		b.byProduct[doc.ProductID] = append(b.byProduct[doc.ProductID], doc.ID)
+		for term, weight := range d.terms {
+			if b.postings[term] == nil {
+				b.postings[term] = make(map[ulid.ULID]float64)
+			}
+			b.postings[term][doc.ID] = weight
+		}
+	}
+	return nil
+}
+
+// remove drop the document from the postings and from its product, a
+// variant moved to another main product is found under the old one.
+func (b *memoryBackend) remove(id ulid.ULID) {
+	d, ok := b.docs[id]
+	if !ok {
+		return
+	}
+	ids := b.byProduct[d.doc.ProductID]
+	for i := range ids {
+		if ids[i] == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(b.byProduct, d.doc.ProductID)
+	} else {
+		b.byProduct[d.doc.ProductID] = ids
+	}
+	for term := range d.terms {
+		delete(b.postings[term], id)
+		if len(b.postings[term]) == 0 {
+			delete(b.postings, term)
+		}
+	}
+	delete(b.docs, id)
+}
+
+// score return the summed weight of include, false when a word is missing
+// or an excluded word is present.
+func (d *memoryDoc) score(include, exclude []string) (float64, bool) {
+	for _, term := range exclude {
+		if _, ok := d.terms[term]; ok {
+			return 0, false
+		}
+	}
+	var score float64
+	for _, term := range include {
+		weight, ok := d.terms[term]
+		if !ok {
+			return 0, false
+		}
+		score += weight
+	}
+	return score, true
+}
+
+// documentTerms weight the words of the document like the search_vector
+// of the database: own name, then main product name and categories, then
+// description. Variant put its categories with the description.
+func documentTerms(doc domain.SearchDocument) map[string]float64 {
+	terms := make(map[string]float64)
+	add := func(text string, weight float64) {
+		for _, term := range tokenize(text) {
+			terms[term] += weight
+		}
+	}
+	add(doc.Name, weightName)
+	categories := strings.Join(doc.CategoryNames, " ")
+	if doc.EntityType == domain.EntityVariant {
+		add(doc.ProductName, weightRelated)
+		add(categories, weightDescribe)
+	} else {
+		add(categories, weightRelated)
+	}
+	add(doc.Description, weightDescribe)
+	return terms
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// parseMemoryQuery split q into required and excluded words, quotes are
+// ignored.
+func parseMemoryQuery(q string) (include, exclude []string) {
+	for _, field := range strings.Fields(q) {
+		negate := strings.HasPrefix(field, "-")
+		for _, term := range tokenize(field) {
+			if negate {
+				exclude = append(exclude, term)
+			} else {
+				include = append(include, term)
+			}
+		}
+	}
+	return include, exclude
+}
+
+// highlight HTML escape text and wrap the words found in terms with
+// <mark>. When maxWords is set only maxWords words starting a little
+// before the first match are kept.
+func highlight(text string, terms map[string]bool, maxWords int) string {
+	words := strings.Fields(text)
+	first := -1
+	for i, word := range words {
+		words[i] = html.EscapeString(word)
+		for _, term := range tokenize(word) {
+			if terms[term] {
+				words[i] = markWord(word, terms)
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+	if maxWords > 0 && len(words) > maxWords {
+		start := 0
+		if first > maxWords/4 {
+			start = first - maxWords/4
+		}
+		end := start + maxWords
+		if end > len(words) {
+			end = len(words)
+			start = end - maxWords
+		}
+		words = words[start:end]
+	}
+	return strings.Join(words, " ")
+}
+
+// markWord escape word and wrap its matching letter runs, punctuation
+// around stay outside the mark.
+func markWord(word string, terms map[string]bool) string {
+	var sb strings.Builder
+	runes := []rune(word)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			sb.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+			j++
+		}
+		token := string(runes[i:j])
+		if terms[strings.ToLower(token)] {
+			sb.WriteString("<mark>" + token + "</mark>")
+		} else {
+			sb.WriteString(token)
+		}
+		i = j
+	}
+	return sb.String()
+}
+
+func NewMemoryBackend(cursors *helper.Cursors) Backend {
+	return &memoryBackend{
+		cursors:   cursors,
+		docs:      make(map[ulid.ULID]*memoryDoc),
+		byProduct: make(map[ulid.ULID][]ulid.ULID),
+		postings:  make(map[string]map[ulid.ULID]float64),
+	}
+}
@@ -0,0 +1,356 @@
+package search_test
+
+import (
+	"context"
+	"flukis/product/domain"
+	"flukis/product/internals/search"
+	"flukis/product/utils/helper"
+	"fmt"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+// catalog is a Repo serving search documents from memory, like the
+// database would after a change is committed.
+type catalog struct {
+	search.Repo
+	docs       []domain.SearchDocument
+	categories map[ulid.ULID][]ulid.ULID
+}
+
+func (c *catalog) GetDocuments(ctx context.Context, productIds []ulid.ULID) ([]domain.SearchDocument, error) {
+	var res []domain.SearchDocument
+	for _, doc := range c.docs {
+		if productIds == nil || containsID(productIds, doc.ProductID) {
+			res = append(res, doc)
+		}
+	}
+	return res, nil
+}
+
+func (c *catalog) GetProductIDsByVariants(ctx context.Context, variantIds []ulid.ULID) ([]ulid.ULID, error) {
+	var res []ulid.ULID
+	for _, doc := range c.docs {
+		if doc.EntityType == domain.EntityVariant && containsID(variantIds, doc.ID) && !containsID(res, doc.ProductID) {
+			res = append(res, doc.ProductID)
+		}
+	}
+	return res, nil
+}
+
+func (c *catalog) GetProductIDsByCategories(ctx context.Context, categoryIds []ulid.ULID) ([]ulid.ULID, error) {
+	var res []ulid.ULID
+	for _, id := range categoryIds {
+		res = append(res, c.categories[id]...)
+	}
+	return res, nil
+}
+
+// set replace the document with the same id, or add it.
+func (c *catalog) set(doc domain.SearchDocument) {
+	for i := range c.docs {
+		if c.docs[i].ID == doc.ID {
+			c.docs[i] = doc
+			return
+		}
+	}
+	c.docs = append(c.docs, doc)
+}
+
+func containsID(ids []ulid.ULID, id ulid.ULID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func id(n int) ulid.ULID {
+	return ulid.MustParse(fmt.Sprintf("01J00000000000000000000%03d", n))
+}
+
+func product(n int, name, desc string, price float64) domain.SearchDocument {
+	return domain.SearchDocument{
+		EntityType:  domain.EntityProduct,
+		ID:          id(n),
+		ProductID:   id(n),
+		Name:        name,
+		Description: desc,
+		Price:       price,
+	}
+}
+
+func variant(n, main int, name, productName string, price float64) domain.SearchDocument {
+	return domain.SearchDocument{
+		EntityType:  domain.EntityVariant,
+		ID:          id(n),
+		ProductID:   id(main),
+		Name:        name,
+		ProductName: productName,
+		Price:       price,
+	}
+}
+
+func newTestBackend(t *testing.T, docs ...domain.SearchDocument) (search.Backend, search.Indexer, *catalog) {
+	t.Helper()
+	repo := &catalog{docs: docs, categories: make(map[ulid.ULID][]ulid.ULID)}
+	backend := search.NewMemoryBackend(helper.NewCursors([]byte("secret")))
+	indexer := search.NewIndexer(repo, backend)
+	if err := indexer.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return backend, indexer, repo
+}
+
+func hitIDs(hits []domain.SearchHit) []ulid.ULID {
+	res := make([]ulid.ULID, len(hits))
+	for i := range hits {
+		res[i] = hits[i].ID
+	}
+	return res
+}
+
+func sameIDs(a, b []ulid.ULID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemorySearch(t *testing.T) {
+	shoes := id(900)
+	color := id(901)
+	boot := product(1, "Leather Boot", "warm winter shoe", 120)
+	boot.CategoryIDs = []ulid.ULID{shoes}
+	boot.CategoryNames = []string{"Shoes"}
+	boot.Tags = []string{"winter"}
+	boot.Attributes = map[ulid.ULID]string{color: "brown"}
+	sandal := product(2, "Sandal", "light summer shoe", 40)
+	sandal.CategoryIDs = []ulid.ULID{shoes}
+	sandal.CategoryNames = []string{"Shoes"}
+	sandal.Attributes = map[ulid.ULID]string{color: "black"}
+	blackBoot := variant(3, 1, "Black", "Leather Boot", 130)
+	blackBoot.Attributes = map[ulid.ULID]string{color: "black"}
+	shoeRack := product(4, "Shoe rack", "holds <b>ten</b> pairs", 25)
+
+	backend, _, _ := newTestBackend(t, boot, sandal, blackBoot, shoeRack)
+
+	tests := []struct {
+		name   string
+		q      string
+		filter domain.ListFilter
+		want   []ulid.ULID
+	}{
+		{name: "name first then description", q: "shoe", want: []ulid.ULID{id(4), id(2), id(1)}},
+		{name: "case insensitive", q: "LEATHER", want: []ulid.ULID{id(1), id(3)}},
+		{name: "every word required", q: "boot warm", want: []ulid.ULID{id(1)}},
+		{name: "excluded word", q: "shoe -summer", want: []ulid.ULID{id(4), id(1)}},
+		{name: "excluded word only in variant", q: "boot -black", want: []ulid.ULID{id(1)}},
+		{name: "no match", q: "hat", want: nil},
+		{name: "only excluded words", q: "-shoe", want: nil},
+		{name: "category names are searched", q: "shoes", want: []ulid.ULID{id(2), id(1)}},
+		{name: "price filter", q: "shoe", filter: domain.ListFilter{MaxPrice: null.FloatFrom(50)}, want: []ulid.ULID{id(4), id(2)}},
+		{name: "category filter", q: "shoe", filter: domain.ListFilter{CategoryIDs: []ulid.ULID{shoes}}, want: []ulid.ULID{id(2), id(1)}},
+		{name: "tag filter", q: "shoe", filter: domain.ListFilter{Tags: domain.NewTagFilter([]string{"winter"}, "")}, want: []ulid.ULID{id(1)}},
+		{
+			name:   "attribute filter",
+			q:      "leather",
+			filter: domain.ListFilter{Attributes: []domain.AttributeFilter{{ID: color, Values: []string{"black"}}}},
+			want:   []ulid.ULID{id(3)},
+		},
+		{name: "name filter", q: "shoe", filter: domain.ListFilter{NameContains: "RACK"}, want: []ulid.ULID{id(4)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, page, err := backend.Search(context.Background(), tt.q, tt.filter, 10, "")
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got := hitIDs(hits); !sameIDs(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+			if page.HasMore || page.NextCursor != "" || page.PrevCursor != "" {
+				t.Errorf("page = %+v, want a single page", page)
+			}
+		})
+	}
+}
+
+func TestMemorySearchHighlight(t *testing.T) {
+	backend, _, _ := newTestBackend(t,
+		product(1, "<b>Red</b> & blue shoe", "a red shoe, "+"very red.", 10),
+	)
+	hits, _, err := backend.Search(context.Background(), "red", domain.ListFilter{}, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("hits = %v, want 1", hitIDs(hits))
+	}
+	if want := "&lt;b&gt;<mark>Red</mark>&lt;/b&gt; &amp; blue shoe"; hits[0].NameHighlight != want {
+		t.Errorf("name highlight = %q, want %q", hits[0].NameHighlight, want)
+	}
+	if want := "a <mark>red</mark> shoe, very <mark>red</mark>."; hits[0].Snippet != want {
+		t.Errorf("snippet = %q, want %q", hits[0].Snippet, want)
+	}
+}
+
+func TestMemorySearchCursor(t *testing.T) {
+	// equal scores, so the pages are ordered by id only
+	var docs []domain.SearchDocument
+	for n := 1; n <= 7; n++ {
+		docs = append(docs, product(n, "lamp", "", 10))
+	}
+	docs = append(docs, product(8, "table", "", 10))
+	backend, _, _ := newTestBackend(t, docs...)
+	ctx := context.Background()
+
+	var pages [][]ulid.ULID
+	var cursors []domain.Page
+	cursor := ""
+	for {
+		hits, page, err := backend.Search(ctx, "lamp", domain.ListFilter{}, 3, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, hitIDs(hits))
+		cursors = append(cursors, page)
+		if !page.HasMore {
+			break
+		}
+		if len(pages) > 5 {
+			t.Fatal("search never reach the last page")
+		}
+		cursor = page.NextCursor
+	}
+
+	want := [][]ulid.ULID{
+		{id(7), id(6), id(5)},
+		{id(4), id(3), id(2)},
+		{id(1)},
+	}
+	if len(pages) != len(want) {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+	for i := range want {
+		if !sameIDs(pages[i], want[i]) {
+			t.Errorf("page %d = %v, want %v", i, pages[i], want[i])
+		}
+	}
+	if cursors[0].PrevCursor != "" {
+		t.Errorf("first page prev cursor = %q, want empty", cursors[0].PrevCursor)
+	}
+
+	// walking back from the last page give the same pages
+	for i := len(want) - 1; i > 0; i-- {
+		hits, page, err := backend.Search(ctx, "lamp", domain.ListFilter{}, 3, cursors[i].PrevCursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hitIDs(hits); !sameIDs(got, want[i-1]) {
+			t.Errorf("page before %d = %v, want %v", i, got, want[i-1])
+		}
+		if !page.HasMore || page.NextCursor == "" {
+			t.Errorf("page before %d = %+v, want more after it", i, page)
+		}
+		if (i-1 == 0) != (page.PrevCursor == "") {
+			t.Errorf("page before %d prev cursor = %q", i, page.PrevCursor)
+		}
+	}
+
+	// a cursor is only valid for the query and filters it was made for
+	if _, _, err := backend.Search(ctx, "table", domain.ListFilter{}, 3, cursors[0].NextCursor); err == nil {
+		t.Error("cursor of another query accepted")
+	}
+	filter := domain.ListFilter{MaxPrice: null.FloatFrom(5)}
+	if _, _, err := backend.Search(ctx, "lamp", filter, 3, cursors[0].NextCursor); err == nil {
+		t.Error("cursor of other filters accepted")
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	ctx := context.Background()
+	search := func(t *testing.T, backend search.Backend, q string) []ulid.ULID {
+		t.Helper()
+		hits, _, err := backend.Search(ctx, q, domain.ListFilter{}, 10, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hitIDs(hits)
+	}
+
+	t.Run("product changed", func(t *testing.T) {
+		backend, indexer, repo := newTestBackend(t, product(1, "old name", "", 1), variant(2, 1, "blue", "old name", 1))
+		repo.set(product(1, "new name", "", 1))
+		repo.set(variant(2, 1, "blue", "new name", 1))
+		indexer.ProductChanged(ctx, id(1))
+
+		if got := search(t, backend, "old"); len(got) != 0 {
+			t.Errorf("old name still found: %v", got)
+		}
+		if got := search(t, backend, "new"); !sameIDs(got, []ulid.ULID{id(1), id(2)}) {
+			t.Errorf("new name hits = %v, want product and variant", got)
+		}
+	})
+
+	t.Run("product deleted", func(t *testing.T) {
+		backend, indexer, repo := newTestBackend(t, product(1, "lamp", "", 1), variant(2, 1, "lamp", "lamp", 1))
+		repo.docs = nil
+		indexer.ProductChanged(ctx, id(1))
+
+		if got := search(t, backend, "lamp"); len(got) != 0 {
+			t.Errorf("deleted product still found: %v", got)
+		}
+	})
+
+	t.Run("variant moved to another product", func(t *testing.T) {
+		backend, indexer, repo := newTestBackend(t,
+			product(1, "chair", "", 1),
+			product(2, "desk", "", 1),
+			variant(3, 1, "oak", "chair", 1),
+		)
+		repo.set(variant(3, 2, "oak", "desk", 1))
+		indexer.VariantChanged(ctx, id(3))
+
+		if got := search(t, backend, "oak desk"); !sameIDs(got, []ulid.ULID{id(3)}) {
+			t.Errorf("moved variant hits = %v, want it under desk", got)
+		}
+		if got := search(t, backend, "oak chair"); len(got) != 0 {
+			t.Errorf("moved variant still under chair: %v", got)
+		}
+
+		// the old product changing later must not drop the moved variant
+		indexer.ProductChanged(ctx, id(1))
+		if got := search(t, backend, "oak"); !sameIDs(got, []ulid.ULID{id(3)}) {
+			t.Errorf("oak hits = %v, want the moved variant", got)
+		}
+	})
+
+	t.Run("category renamed", func(t *testing.T) {
+		category := id(900)
+		lamp := product(1, "lamp", "", 1)
+		lamp.CategoryIDs = []ulid.ULID{category}
+		lamp.CategoryNames = []string{"lighting"}
+		backend, indexer, repo := newTestBackend(t, lamp, product(2, "desk", "", 1))
+		repo.categories[category] = []ulid.ULID{id(1)}
+
+		lamp.CategoryNames = []string{"lights"}
+		repo.set(lamp)
+		indexer.CategoryChanged(ctx, category)
+
+		if got := search(t, backend, "lighting"); len(got) != 0 {
+			t.Errorf("old category name still found: %v", got)
+		}
+		if got := search(t, backend, "lights"); !sameIDs(got, []ulid.ULID{id(1)}) {
+			t.Errorf("new category name hits = %v, want the lamp", got)
+		}
+	})
+}
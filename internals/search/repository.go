@@ -0,0 +1,862 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/guregu/null.v4"
+)
+
+type Repo interface {
+	Search(ctx context.Context, q string, filter domain.ListFilter, limit int, cursor string) ([]domain.SearchHit, domain.Page, error)
+	Suggest(ctx context.Context, q string, threshold float64, limit int) ([]domain.Suggestion, error)
+	GetSynonyms(ctx context.Context) ([]domain.Synonym, error)
+	GetSynonymByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) (*domain.Synonym, error)
+	SaveSynonymWithTransaction(ctx context.Context, tx pgx.Tx, syn *domain.Synonym) error
+	EditSynonymWithTransaction(ctx context.Context, tx pgx.Tx, syn *domain.Synonym) error
+	DeleteSynonymWithTransaction(ctx context.Context, tx pgx.Tx, syn *domain.Synonym) error
+	GetRules(ctx context.Context, query string) ([]domain.SearchRule, error)
+	GetRuleByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) (*domain.SearchRule, error)
+	SaveRuleWithTransaction(ctx context.Context, tx pgx.Tx, rule *domain.SearchRule) error
+	DeleteRuleWithTransaction(ctx context.Context, tx pgx.Tx, rule *domain.SearchRule) error
+	GetDocuments(ctx context.Context, productIds []ulid.ULID) ([]domain.SearchDocument, error)
+	GetProductIDsByVariants(ctx context.Context, variantIds []ulid.ULID) ([]ulid.ULID, error)
+	GetProductIDsByCategories(ctx context.Context, categoryIds []ulid.ULID) ([]ulid.ULID, error)
+}
+
+type repo struct {
+	db      *pgxpool.Pool
+	cursors *helper.Cursors
+}
+
+// Search implements Repo. Product and variant are matched against their
+// search_vector, maintained by trigger from name, description, category
+// names and, for variant, the main product name. Synonyms are expanded by
+// search_query. Boosted product is ranked above every match and buried one
+// below, rank keep the cursor working across them. Boosted product is
+// fetched apart from the matched ones so the match use the search index.
+// Filter apply like the variant listing, category, brand and missing
+// attribute come from the main product. Highlight is only computed for the
+// returned page.
+func (r *repo) Search(ctx context.Context, q string, filter domain.ListFilter, limit int, cursor string) ([]domain.SearchHit, domain.Page, error) {
+	query := `
+		WITH q AS (
+			SELECT search_query($1) AS query
+		), rules AS (
+			SELECT product_id, action, position
+			FROM Search_Rule
+			WHERE query = $5 AND deleted_at IS NULL
+		), matched AS (
+			SELECT p.product_id
+			FROM Product p, q
+			WHERE p.deleted_at IS NULL AND p.search_vector @@ q.query
+			UNION ALL
+			SELECT p.product_id
+			FROM rules r
+			JOIN Product p ON p.product_id = r.product_id, q
+			WHERE r.action = 'boost'
+				AND p.deleted_at IS NULL
+				AND (p.search_vector @@ q.query) IS NOT TRUE
+		), hits AS (
+			SELECT
+				'product' AS entity_type,
+				p.product_id AS id,
+				p.product_id,
+				p.name,
+				COALESCE(p.description, '') AS description,
+				COALESCE(p.price, 0)::float8 AS price,
+				(
+					p.image_key IS NOT NULL
+					OR p.image_preview IS NOT NULL
+					OR EXISTS (
+						SELECT 1
+						FROM Image i
+						WHERE i.entity_type = 'product'
+							AND i.entity_id = p.product_id
+							AND i.deleted_at IS NULL
+					)
+				) AS has_image,
+				ts_rank_cd(p.search_vector, q.query)::float8 AS score,
+				CASE r.action
+					WHEN 'boost' THEN 1000000::float8 - r.position
+					WHEN 'bury' THEN ts_rank_cd(p.search_vector, q.query)::float8 - 1000000
+					ELSE ts_rank_cd(p.search_vector, q.query)
+				END::float8 AS rank
+			FROM
+				matched m
+			JOIN
+				Product p ON p.product_id = m.product_id
+			CROSS JOIN
+				q
+			LEFT JOIN
+				rules r ON r.product_id = p.product_id
+			WHERE
+				%s
+			UNION ALL
+			SELECT
+				'variant',
+				v.variant_id,
+				v.main_product_id,
+				v.name,
+				COALESCE(v.description, ''),
+				COALESCE(v.price, 0)::float8,
+				(
+					p.image_key IS NOT NULL
+					OR p.image_preview IS NOT NULL
+					OR EXISTS (
+						SELECT 1
+						FROM Image i
+						WHERE i.deleted_at IS NULL
+							AND (
+								(i.entity_type = 'variant' AND i.entity_id = v.variant_id)
+								OR (i.entity_type = 'product' AND i.entity_id = p.product_id)
+							)
+					)
+				),
+				ts_rank_cd(v.search_vector, q.query)::float8,
+				CASE r.action
+					WHEN 'bury' THEN ts_rank_cd(v.search_vector, q.query)::float8 - 1000000
+					ELSE ts_rank_cd(v.search_vector, q.query)
+				END::float8
+			FROM
+				Variant v
+			JOIN
+				Product p ON p.product_id = v.main_product_id
+			CROSS JOIN
+				q
+			LEFT JOIN
+				rules r ON r.product_id = p.product_id
+			WHERE
+				v.deleted_at IS NULL
+				AND p.deleted_at IS NULL
+				AND v.search_vector @@ q.query
+				AND %s
+		), page AS (
+			SELECT *
+			FROM hits
+			WHERE $2::float8 IS NULL OR (rank, id) %s ($2, $3)
+			ORDER BY rank %s, id %s
+			LIMIT $4
+		)
+		SELECT
+			page.entity_type,
+			page.id,
+			page.product_id,
+			page.name,
+			page.description,
+			page.price,
+			page.has_image,
+			page.score,
+			page.rank,
+			ts_headline('english', translate(page.name, chr(2) || chr(3), ''), q.query, 'HighlightAll=true, StartSel=' || chr(2) || ', StopSel=' || chr(3)),
+			ts_headline('english', translate(page.description, chr(2) || chr(3), ''), q.query, 'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=' || chr(2) || ', StopSel=' || chr(3))
+		FROM
+			page, q
+		ORDER BY
+			page.rank %s, page.id %s
+	`
+	scope := domain.SearchScope(q, filter)
+	decodedCursor, err := r.cursors.Decode(cursor, domain.SearchSort, scope)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	var afterRank null.Float
+	var afterId []byte
+	if cursor != "" {
+		rank, err := decodedCursor.SearchRank()
+		if err != nil {
+			return nil, domain.Page{}, err
+		}
+		afterRank = null.FloatFrom(rank)
+		afterId = decodedCursor.ID[:]
+	}
+	// a page before the cursor is read in reverse
+	compare, direction := "<", "DESC"
+	if decodedCursor.Before {
+		compare, direction = ">", "ASC"
+	}
+	args := []any{q, afterRank, afterId, limit + 1, domain.NormalizeSearchTerm(q)}
+	productWhere, args := helper.ProductFilter(filter, args)
+	variantWhere, args := helper.VariantFilter(filter, args)
+	query = fmt.Sprintf(query, productWhere, variantWhere, compare, direction, direction, direction, direction)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, domain.Page{}, err
+	}
+	defer rows.Close()
+
+	var hits []domain.SearchHit
+	for rows.Next() {
+		var hit domain.SearchHit
+		if err := rows.Scan(
+			&hit.EntityType,
+			&hit.ID,
+			&hit.ProductID,
+			&hit.Name,
+			&hit.Description,
+			&hit.Price,
+			&hit.HasImage,
+			&hit.Score,
+			&hit.Rank,
+			&hit.NameHighlight,
+			&hit.Snippet,
+		); err != nil {
+			return nil, domain.Page{}, err
+		}
+		hit.NameHighlight = domain.EscapeHighlight(hit.NameHighlight)
+		hit.Snippet = domain.EscapeHighlight(hit.Snippet)
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
+	}
+
+	hits, page := helper.CursorPage(r.cursors, scope, hits, limit, decodedCursor, domain.NewSearchCursor)
+	return hits, page, nil
+}
+
+// Suggest implements Repo. Text starting with q, or having a word starting
+// with q, score one above the fuzzy match so prefix come first. Fuzzy
+// match use the pg_trgm word similarity with threshold, each source is
+// capped at limit so the trigram index keep the query short.
+func (r *repo) Suggest(ctx context.Context, q string, threshold float64, limit int) ([]domain.Suggestion, error) {
+	query := `
+		SELECT kind, id, label, attribute_name, score
+		FROM (
+			(
+				SELECT
+					'product' AS kind,
+					p.product_id AS id,
+					p.name::text AS label,
+					'' AS attribute_name,
+					(lower(p.name) LIKE $2 OR lower(p.name) LIKE $3)::int + word_similarity($1, lower(p.name)) AS score
+				FROM
+					Product p
+				WHERE
+					p.deleted_at IS NULL
+					AND (lower(p.name) LIKE $2 OR lower(p.name) LIKE $3 OR $1 <% lower(p.name))
+				ORDER BY
+					score DESC
+				LIMIT $4
+			)
+			UNION ALL
+			(
+				SELECT
+					'category',
+					c.category_id,
+					c.name::text,
+					'',
+					(lower(c.name) LIKE $2 OR lower(c.name) LIKE $3)::int + word_similarity($1, lower(c.name)) AS score
+				FROM
+					Category c
+				WHERE
+					c.deleted_at IS NULL
+					AND (lower(c.name) LIKE $2 OR lower(c.name) LIKE $3 OR $1 <% lower(c.name))
+				ORDER BY
+					score DESC
+				LIMIT $4
+			)
+			UNION ALL
+			(
+				SELECT
+					'attribute',
+					a.attribute_id,
+					v.value,
+					a.name::text,
+					(lower(v.value) LIKE $2 OR lower(v.value) LIKE $3)::int + word_similarity($1, lower(v.value)) AS score
+				FROM (
+					SELECT pa.attribute_id, pa.value
+					FROM Product_Attribute pa
+					WHERE pa.deleted_at IS NULL
+						AND (lower(pa.value) LIKE $2 OR lower(pa.value) LIKE $3 OR $1 <% lower(pa.value))
+					UNION
+					SELECT va.attribute_id, va.value
+					FROM Variant_Attribute va
+					WHERE va.deleted_at IS NULL
+						AND (lower(va.value) LIKE $2 OR lower(va.value) LIKE $3 OR $1 <% lower(va.value))
+				) AS v
+				JOIN
+					Attribute a ON a.attribute_id = v.attribute_id AND a.deleted_at IS NULL
+				ORDER BY
+					score DESC
+				LIMIT $4
+			)
+		) AS s
+		ORDER BY
+			score DESC, length(label), label
+		LIMIT $4
+	`
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the <% operator read its threshold from the session, keep it local
+	// to this transaction
+	_, err = tx.Exec(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)", strconv.FormatFloat(threshold, 'f', -1, 64))
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	prefix, wordPrefix := domain.SuggestPatterns(q)
+	suggestions, err := scanSuggestions(tx.Query(ctx, query, q, prefix, wordPrefix, limit))
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+func scanSuggestions(rows pgx.Rows, err error) ([]domain.Suggestion, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []domain.Suggestion
+	for rows.Next() {
+		var suggestion domain.Suggestion
+		if err := rows.Scan(
+			&suggestion.Type,
+			&suggestion.ID,
+			&suggestion.Text,
+			&suggestion.AttributeName,
+			&suggestion.Score,
+		); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// GetSynonyms implements Repo.
+func (r *repo) GetSynonyms(ctx context.Context) ([]domain.Synonym, error) {
+	query := `
+		SELECT
+			synonym_id,
+			terms
+		FROM
+			Search_Synonym
+		WHERE
+			deleted_at IS NULL
+		ORDER BY
+			created_at
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var synonyms []domain.Synonym
+	for rows.Next() {
+		var syn domain.Synonym
+		if err := rows.Scan(
+			&syn.SynonymID,
+			&syn.Terms,
+		); err != nil {
+			return nil, err
+		}
+		synonyms = append(synonyms, syn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return synonyms, nil
+}
+
+// GetSynonymByIDWithTransaction implements Repo.
+func (*repo) GetSynonymByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) (*domain.Synonym, error) {
+	query := `
+		SELECT
+			synonym_id,
+			terms
+		FROM
+			Search_Synonym
+		WHERE
+			synonym_id = $1 AND deleted_at IS NULL
+	`
+	var syn domain.Synonym
+	if err := tx.QueryRow(ctx, query, id).Scan(
+		&syn.SynonymID,
+		&syn.Terms,
+	); err != nil {
+		return nil, err
+	}
+	return &syn, nil
+}
+
+// SaveSynonymWithTransaction implements Repo.
+func (*repo) SaveSynonymWithTransaction(ctx context.Context, tx pgx.Tx, syn *domain.Synonym) error {
+	query := `
+		INSERT INTO Search_Synonym
+			(synonym_id, terms, created_at)
+		VALUES
+			($1, $2, $3)
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&syn.SynonymID,
+		&syn.Terms,
+		&syn.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EditSynonymWithTransaction implements Repo.
+func (*repo) EditSynonymWithTransaction(ctx context.Context, tx pgx.Tx, syn *domain.Synonym) error {
+	query := `
+		UPDATE Search_Synonym SET
+			terms = $1,
+			updated_at = $2
+		WHERE
+			synonym_id = $3 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&syn.Terms,
+		currentTime,
+		&syn.SynonymID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteSynonymWithTransaction implements Repo.
+func (*repo) DeleteSynonymWithTransaction(ctx context.Context, tx pgx.Tx, syn *domain.Synonym) error {
+	query := `
+		UPDATE Search_Synonym SET
+			deleted_at = $1
+		WHERE
+			synonym_id = $2 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		&syn.SynonymID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetRules implements Repo. Empty query return the rules of every query.
+func (r *repo) GetRules(ctx context.Context, q string) ([]domain.SearchRule, error) {
+	query := `
+		SELECT
+			rule_id,
+			query,
+			product_id,
+			action,
+			position
+		FROM
+			Search_Rule
+		WHERE
+			deleted_at IS NULL
+			AND ($1::text = '' OR query = $1)
+		ORDER BY
+			query, action, position, rule_id
+	`
+	rows, err := r.db.Query(ctx, query, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []domain.SearchRule
+	for rows.Next() {
+		var rule domain.SearchRule
+		if err := rows.Scan(
+			&rule.RuleID,
+			&rule.Query,
+			&rule.ProductID,
+			&rule.Action,
+			&rule.Position,
+		); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRuleByIDWithTransaction implements Repo.
+func (*repo) GetRuleByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID) (*domain.SearchRule, error) {
+	query := `
+		SELECT
+			rule_id,
+			query,
+			product_id,
+			action,
+			position
+		FROM
+			Search_Rule
+		WHERE
+			rule_id = $1 AND deleted_at IS NULL
+	`
+	var rule domain.SearchRule
+	if err := tx.QueryRow(ctx, query, id).Scan(
+		&rule.RuleID,
+		&rule.Query,
+		&rule.ProductID,
+		&rule.Action,
+		&rule.Position,
+	); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveRuleWithTransaction implements Repo. A query has one rule per
+// product, saving again replace the action and position, rule.RuleID is set
+// to the stored one. It return ErrSearchRuleProduct when the product does
+// not exist.
+func (*repo) SaveRuleWithTransaction(ctx context.Context, tx pgx.Tx, rule *domain.SearchRule) error {
+	query := `
+		INSERT INTO Search_Rule
+			(rule_id, query, product_id, action, position, created_at)
+		SELECT
+			$1, $2, $3, $4, $5, $6
+		WHERE EXISTS (
+			SELECT 1
+			FROM Product
+			WHERE product_id = $3 AND deleted_at IS NULL
+		)
+		ON CONFLICT (query, product_id) DO UPDATE SET
+			action = EXCLUDED.action,
+			position = EXCLUDED.position,
+			updated_at = EXCLUDED.created_at,
+			deleted_at = NULL
+		RETURNING rule_id
+	`
+	if err := tx.QueryRow(
+		ctx,
+		query,
+		&rule.RuleID,
+		&rule.Query,
+		&rule.ProductID,
+		&rule.Action,
+		&rule.Position,
+		&rule.CreatedAt,
+	).Scan(&rule.RuleID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrSearchRuleProduct
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteRuleWithTransaction implements Repo.
+func (*repo) DeleteRuleWithTransaction(ctx context.Context, tx pgx.Tx, rule *domain.SearchRule) error {
+	query := `
+		UPDATE Search_Rule SET
+			deleted_at = $1
+		WHERE
+			rule_id = $2 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		&rule.RuleID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetDocuments implements Repo. It return the live products of productIds
+// and their live variants, every product when productIds is empty.
+func (r *repo) GetDocuments(ctx context.Context, productIds []ulid.ULID) ([]domain.SearchDocument, error) {
+	query := `
+		WITH products AS (
+			SELECT *
+			FROM Product p
+			WHERE p.deleted_at IS NULL
+				AND (COALESCE(cardinality($1::bytea[]), 0) = 0 OR p.product_id = ANY($1))
+		), categories AS (
+			SELECT
+				pc.product_id,
+				array_agg(c.category_id ORDER BY c.name, c.category_id) AS ids,
+				array_agg(c.name ORDER BY c.name, c.category_id) AS names
+			FROM Product_Category pc
+			JOIN products p ON p.product_id = pc.product_id
+			JOIN Category c ON c.category_id = pc.category_id AND c.deleted_at IS NULL
+			WHERE pc.deleted_at IS NULL
+			GROUP BY pc.product_id
+		), attrs AS (
+			SELECT
+				'product' AS entity_type,
+				pa.product_id AS id,
+				pa.attribute_id,
+				pa.value
+			FROM Product_Attribute pa
+			JOIN products p ON p.product_id = pa.product_id
+			WHERE pa.deleted_at IS NULL
+			UNION ALL
+			SELECT 'variant', va.variant_id, va.attribute_id, va.value
+			FROM Variant_Attribute va
+			JOIN Variant v ON v.variant_id = va.variant_id
+			JOIN products p ON p.product_id = v.main_product_id
+			WHERE va.deleted_at IS NULL AND v.deleted_at IS NULL
+			UNION ALL
+			SELECT 'variant', v.variant_id, pa.attribute_id, pa.value
+			FROM Variant v
+			JOIN products p ON p.product_id = v.main_product_id
+			JOIN Product_Attribute pa ON pa.product_id = p.product_id AND pa.deleted_at IS NULL
+			WHERE v.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1
+					FROM Variant_Attribute va
+					WHERE va.variant_id = v.variant_id
+						AND va.attribute_id = pa.attribute_id
+						AND va.deleted_at IS NULL
+				)
+		), attr_lists AS (
+			SELECT
+				entity_type,
+				id,
+				array_agg(attribute_id ORDER BY attribute_id) AS ids,
+				array_agg(value ORDER BY attribute_id) AS "values"
+			FROM attrs
+			GROUP BY entity_type, id
+		)
+		SELECT
+			'product',
+			p.product_id,
+			p.product_id,
+			p.name,
+			p.name,
+			COALESCE(p.description, ''),
+			COALESCE(p.price, 0)::float8,
+			(
+				p.image_key IS NOT NULL
+				OR p.image_preview IS NOT NULL
+				OR EXISTS (
+					SELECT 1
+					FROM Image i
+					WHERE i.entity_type = 'product'
+						AND i.entity_id = p.product_id
+						AND i.deleted_at IS NULL
+				)
+			),
+			p.brand_id,
+			COALESCE(c.ids, '{}'),
+			COALESCE(c.names, '{}'),
+			ARRAY(
+				SELECT t.name
+				FROM Product_Tag xt
+				JOIN Tag t ON xt.tag_id = t.tag_id
+				WHERE xt.product_id = p.product_id AND xt.deleted_at IS NULL
+			),
+			COALESCE(a.ids, '{}'),
+			COALESCE(a."values", '{}'),
+			p.created_at,
+			COALESCE(p.updated_at, p.created_at)
+		FROM
+			products p
+		LEFT JOIN
+			categories c ON c.product_id = p.product_id
+		LEFT JOIN
+			attr_lists a ON a.entity_type = 'product' AND a.id = p.product_id
+		UNION ALL
+		SELECT
+			'variant',
+			v.variant_id,
+			v.main_product_id,
+			v.name,
+			p.name,
+			COALESCE(v.description, ''),
+			COALESCE(v.price, 0)::float8,
+			(
+				p.image_key IS NOT NULL
+				OR p.image_preview IS NOT NULL
+				OR EXISTS (
+					SELECT 1
+					FROM Image i
+					WHERE i.deleted_at IS NULL
+						AND (
+							(i.entity_type = 'variant' AND i.entity_id = v.variant_id)
+							OR (i.entity_type = 'product' AND i.entity_id = p.product_id)
+						)
+				)
+			),
+			p.brand_id,
+			COALESCE(c.ids, '{}'),
+			COALESCE(c.names, '{}'),
+			ARRAY(
+				SELECT t.name
+				FROM Variant_Tag xt
+				JOIN Tag t ON xt.tag_id = t.tag_id
+				WHERE xt.variant_id = v.variant_id AND xt.deleted_at IS NULL
+			),
+			COALESCE(a.ids, '{}'),
+			COALESCE(a."values", '{}'),
+			v.created_at,
+			COALESCE(v.updated_at, v.created_at)
+		FROM
+			Variant v
+		JOIN
+			products p ON p.product_id = v.main_product_id
+		LEFT JOIN
+			categories c ON c.product_id = p.product_id
+		LEFT JOIN
+			attr_lists a ON a.entity_type = 'variant' AND a.id = v.variant_id
+		WHERE
+			v.deleted_at IS NULL
+	`
+	rows, err := r.db.Query(ctx, query, rawIDs(productIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []domain.SearchDocument
+	for rows.Next() {
+		var (
+			doc         domain.SearchDocument
+			categoryIds [][]byte
+			attrIds     [][]byte
+			attrValues  []string
+		)
+		if err := rows.Scan(
+			&doc.EntityType,
+			&doc.ID,
+			&doc.ProductID,
+			&doc.Name,
+			&doc.ProductName,
+			&doc.Description,
+			&doc.Price,
+			&doc.HasImage,
+			&doc.BrandID,
+			&categoryIds,
+			&doc.CategoryNames,
+			&doc.Tags,
+			&attrIds,
+			&attrValues,
+			&doc.CreatedAt,
+			&doc.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		doc.CategoryIDs = make([]ulid.ULID, len(categoryIds))
+		for i := range categoryIds {
+			copy(doc.CategoryIDs[i][:], categoryIds[i])
+		}
+		doc.Attributes = make(map[ulid.ULID]string, len(attrIds))
+		for i := range attrIds {
+			var id ulid.ULID
+			copy(id[:], attrIds[i])
+			doc.Attributes[id] = attrValues[i]
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// GetProductIDsByVariants implements Repo. Deleted variant still give its
+// main product, so the removal reach the index.
+func (r *repo) GetProductIDsByVariants(ctx context.Context, variantIds []ulid.ULID) ([]ulid.ULID, error) {
+	query := `
+		SELECT DISTINCT
+			main_product_id
+		FROM
+			Variant
+		WHERE
+			variant_id = ANY($1)
+	`
+	rows, err := r.db.Query(ctx, query, rawIDs(variantIds))
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+// GetProductIDsByCategories implements Repo.
+func (r *repo) GetProductIDsByCategories(ctx context.Context, categoryIds []ulid.ULID) ([]ulid.ULID, error) {
+	query := `
+		SELECT DISTINCT
+			product_id
+		FROM
+			Product_Category
+		WHERE
+			category_id = ANY($1) AND deleted_at IS NULL
+	`
+	rows, err := r.db.Query(ctx, query, rawIDs(categoryIds))
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+func rawIDs(ids []ulid.ULID) [][]byte {
+	res := make([][]byte, len(ids))
+	for i := range ids {
+		res[i] = ids[i][:]
+	}
+	return res
+}
+
+func scanIDs(rows pgx.Rows) ([]ulid.ULID, error) {
+	defer rows.Close()
+	var ids []ulid.ULID
+	for rows.Next() {
+		var id ulid.ULID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func NewRepo(db *pgxpool.Pool, cursors *helper.Cursors) Repo {
+	return &repo{
+		db:      db,
+		cursors: cursors,
+	}
+}
@@ -0,0 +1,330 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/resp"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultSearchLimit = 20
+
+type Router struct {
+	service Service
+}
+
+func NewRouter(
+	service Service,
+) *Router {
+	return &Router{
+		service: service,
+	}
+}
+
+func (r *Router) Routes() *chi.Mux {
+	route := chi.NewMux()
+
+	route.Get("/", r.SearchHandler)
+	route.Get("/suggest", r.SuggestHandler)
+	route.Get("/synonym", r.GetSynonymsHandler)
+	route.Post("/synonym", r.CreateSynonymHandler)
+	route.Patch("/synonym/{id}", r.UpdateSynonymHandler)
+	route.Delete("/synonym/{id}", r.DeleteSynonymHandler)
+	route.Get("/rule", r.GetSearchRulesHandler)
+	route.Post("/rule", r.SaveSearchRuleHandler)
+	route.Delete("/rule/{id}", r.DeleteSearchRuleHandler)
+
+	return route
+}
+
+// SearchHandler take the query from "q" and the listing filters. Name and
+// description snippet of each hit are HTML escaped and their matched words
+// wrapped in <mark>.
+func (r *Router) SearchHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	limitInt := defaultSearchLimit
+	if limitStr := req.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limitInt, err = strconv.Atoi(limitStr)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	cursor := req.URL.Query().Get("cursor")
+	filter, err := domain.NewListFilter(req.URL.Query())
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	q := req.URL.Query().Get("q")
+	res, length, page, err := r.service.Search(ctx, q, filter, limitInt, cursor)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+
+	var metaResp struct {
+		domain.PageMeta
+		Facets *domain.Facets `json:"facets,omitempty"`
+	}
+	metaResp.PageMeta = domain.NewPageMeta(domain.PageRequest{Limit: limitInt}, page, length)
+
+	// facets do not change between pages, only the first page carry them
+	if cursor == "" {
+		facets, err := r.service.GetFacets(ctx, q, filter)
+		if err != nil {
+			if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+		metaResp.Facets = &facets
+	}
+
+	if err = resp.WriteResponse(w, "search success", http.StatusOK, res, metaResp); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+// SuggestHandler answer the typeahead of "q" with a few product names,
+// category names and attribute values, prefix match first.
+func (r *Router) SuggestHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	limitInt := domain.DefaultSuggestLimit
+	if limitStr := req.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limitInt, err = strconv.Atoi(limitStr)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	res, err := r.service.Suggest(ctx, req.URL.Query().Get("q"), limitInt)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get search suggestion success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) GetSynonymsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	res, err := r.service.GetSynonyms(ctx)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get synonyms success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) CreateSynonymHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	var input struct {
+		Terms []string `json:"terms"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.CreateSynonym(ctx, input.Terms)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "create synonym success", http.StatusCreated, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) UpdateSynonymHandler(w http.ResponseWriter, req *http.Request) {
+	synonymId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(synonymId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Terms []string `json:"terms"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.UpdateSynonym(ctx, id, input.Terms)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "update synonym success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) DeleteSynonymHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	synonymId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(synonymId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	err = r.service.DeleteSynonym(ctx, id)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "delete synonym success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+// GetSearchRulesHandler list the rules, only those of "query" when given.
+func (r *Router) GetSearchRulesHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	res, err := r.service.GetSearchRules(ctx, req.URL.Query().Get("query"))
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get search rules success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) SaveSearchRuleHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	var input struct {
+		Query     string                  `json:"query"`
+		ProductID ulid.ULID               `json:"product_id"`
+		Action    domain.SearchRuleAction `json:"action"`
+		Position  int                     `json:"position"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.SaveSearchRule(ctx, input.Query, input.ProductID, input.Action, input.Position)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "save search rule success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) DeleteSearchRuleHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	ruleId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(ruleId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	err = r.service.DeleteSearchRule(ctx, id)
+	if err != nil {
+		if err = resp.WriteError(w, errorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "delete search rule success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func errorStatus(err error) int {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, domain.ErrEmptySearch) ||
+		errors.Is(err, domain.ErrInvalidSynonym) ||
+		errors.Is(err, domain.ErrInvalidSearchRule) ||
+		errors.Is(err, domain.ErrInvalidSearchRuleAction) ||
+		errors.Is(err, domain.ErrSearchRuleProduct) ||
+		errors.Is(err, domain.ErrInvalidSearchLimit) ||
+		errors.Is(err, domain.ErrInvalidSuggestLimit) ||
+		errors.Is(err, domain.ErrInvalidFilter) ||
+		errors.Is(err, domain.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
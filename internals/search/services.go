@@ -0,0 +1,292 @@
+package search
+
+import (
+	"context"
+	"flukis/product/domain"
+	"flukis/product/internals/facet"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type Service interface {
+	Search(ctx context.Context, q string, filter domain.ListFilter, limit int, cursor string) (res []domain.SearchHitDTO, length int, page domain.Page, err error)
+	GetFacets(ctx context.Context, q string, filter domain.ListFilter) (domain.Facets, error)
+	Suggest(ctx context.Context, q string, limit int) ([]domain.SuggestionDTO, error)
+	GetSynonyms(ctx context.Context) ([]domain.SynonymDTO, error)
+	CreateSynonym(ctx context.Context, terms []string) (domain.SynonymDTO, error)
+	UpdateSynonym(ctx context.Context, id ulid.ULID, terms []string) (domain.SynonymDTO, error)
+	DeleteSynonym(ctx context.Context, id ulid.ULID) error
+	GetSearchRules(ctx context.Context, query string) ([]domain.SearchRuleDTO, error)
+	SaveSearchRule(ctx context.Context, query string, productId ulid.ULID, action domain.SearchRuleAction, position int) (domain.SearchRuleDTO, error)
+	DeleteSearchRule(ctx context.Context, id ulid.ULID) error
+}
+
+type service struct {
+	repo       Repo
+	backend    Backend
+	facetRepo  facet.Repo
+	facetScope domain.FacetScope
+	suggest    domain.SuggestOptions
+	db         *pgxpool.Pool
+}
+
+// Search implements Service. Hits come from the configured backend.
+func (s *service) Search(ctx context.Context, q string, filter domain.ListFilter, limit int, cursor string) (res []domain.SearchHitDTO, length int, page domain.Page, err error) {
+	q, err = domain.NormalizeSearch(q)
+	if err != nil {
+		return []domain.SearchHitDTO{}, 0, domain.Page{}, err
+	}
+	if limit <= 0 || limit > domain.MaxSearchLimit {
+		return []domain.SearchHitDTO{}, 0, domain.Page{}, domain.ErrInvalidSearchLimit
+	}
+	hits, page, err := s.backend.Search(ctx, q, filter, limit, cursor)
+	if err != nil {
+		return []domain.SearchHitDTO{}, 0, domain.Page{}, err
+	}
+	var data = make([]domain.SearchHitDTO, len(hits))
+	for i := range hits {
+		data[i] = domain.NewSearchHitDTO(hits[i])
+	}
+	return data, len(data), page, nil
+}
+
+// GetFacets implements Service. Products and variants matching q are
+// counted.
+func (s *service) GetFacets(ctx context.Context, q string, filter domain.ListFilter) (domain.Facets, error) {
+	q, err := domain.NormalizeSearch(q)
+	if err != nil {
+		return domain.Facets{}, err
+	}
+	scope := s.facetScope
+	scope.Query = q
+	scope.Variants = true
+	return s.facetRepo.Count(ctx, scope, filter)
+}
+
+// Suggest implements Service. Suggestion that does not fit in the timeout
+// is dropped, typeahead answer empty rather than late.
+func (s *service) Suggest(ctx context.Context, q string, limit int) ([]domain.SuggestionDTO, error) {
+	q, err := domain.NormalizeSuggest(q)
+	if err != nil {
+		return []domain.SuggestionDTO{}, err
+	}
+	if limit <= 0 || limit > domain.MaxSuggestLimit {
+		return []domain.SuggestionDTO{}, domain.ErrInvalidSuggestLimit
+	}
+	if s.suggest.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.suggest.Timeout)
+		defer cancel()
+	}
+	suggestions, err := s.repo.Suggest(ctx, q, s.suggest.Threshold, limit)
+	if err != nil {
+		if pgconn.Timeout(err) {
+			log.Warn().Err(err).Str("q", q).Msg("search suggestion timed out")
+			return []domain.SuggestionDTO{}, nil
+		}
+		return []domain.SuggestionDTO{}, err
+	}
+	var data = make([]domain.SuggestionDTO, len(suggestions))
+	for i := range suggestions {
+		data[i] = domain.NewSuggestionDTO(suggestions[i])
+	}
+	return data, nil
+}
+
+// GetSynonyms implements Service.
+func (s *service) GetSynonyms(ctx context.Context) ([]domain.SynonymDTO, error) {
+	synonyms, err := s.repo.GetSynonyms(ctx)
+	if err != nil {
+		return []domain.SynonymDTO{}, err
+	}
+	var data = make([]domain.SynonymDTO, len(synonyms))
+	for i := range synonyms {
+		data[i] = domain.NewSynonymDTO(synonyms[i])
+	}
+	return data, nil
+}
+
+// CreateSynonym implements Service.
+func (s *service) CreateSynonym(ctx context.Context, terms []string) (domain.SynonymDTO, error) {
+	newSynonym, err := domain.NewSynonym(terms)
+	if err != nil {
+		return domain.SynonymDTO{}, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.SynonymDTO{}, err
+	}
+
+	err = s.repo.SaveSynonymWithTransaction(ctx, tx, &newSynonym)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.SynonymDTO{}, err
+		}
+		return domain.SynonymDTO{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.SynonymDTO{}, err
+	}
+	return domain.NewSynonymDTO(newSynonym), nil
+}
+
+// UpdateSynonym implements Service. The terms replace the whole set.
+func (s *service) UpdateSynonym(ctx context.Context, id ulid.ULID, terms []string) (domain.SynonymDTO, error) {
+	terms, err := domain.SynonymTerms(terms)
+	if err != nil {
+		return domain.SynonymDTO{}, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.SynonymDTO{}, err
+	}
+
+	currSynonym, err := s.repo.GetSynonymByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.SynonymDTO{}, err
+		}
+		return domain.SynonymDTO{}, err
+	}
+	currSynonym.Terms = terms
+
+	err = s.repo.EditSynonymWithTransaction(ctx, tx, currSynonym)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.SynonymDTO{}, err
+		}
+		return domain.SynonymDTO{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.SynonymDTO{}, err
+	}
+	return domain.NewSynonymDTO(*currSynonym), nil
+}
+
+// DeleteSynonym implements Service.
+func (s *service) DeleteSynonym(ctx context.Context, id ulid.ULID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	currSynonym, err := s.repo.GetSynonymByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = s.repo.DeleteSynonymWithTransaction(ctx, tx, currSynonym)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetSearchRules implements Service. Empty query return every rule.
+func (s *service) GetSearchRules(ctx context.Context, query string) ([]domain.SearchRuleDTO, error) {
+	rules, err := s.repo.GetRules(ctx, domain.NormalizeSearchTerm(query))
+	if err != nil {
+		return []domain.SearchRuleDTO{}, err
+	}
+	var data = make([]domain.SearchRuleDTO, len(rules))
+	for i := range rules {
+		data[i] = domain.NewSearchRuleDTO(rules[i])
+	}
+	return data, nil
+}
+
+// SaveSearchRule implements Service. Saving a rule for a query and product
+// that already have one replace its action and position.
+func (s *service) SaveSearchRule(ctx context.Context, query string, productId ulid.ULID, action domain.SearchRuleAction, position int) (domain.SearchRuleDTO, error) {
+	newRule, err := domain.NewSearchRule(query, productId, action, position)
+	if err != nil {
+		return domain.SearchRuleDTO{}, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.SearchRuleDTO{}, err
+	}
+
+	err = s.repo.SaveRuleWithTransaction(ctx, tx, &newRule)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.SearchRuleDTO{}, err
+		}
+		return domain.SearchRuleDTO{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.SearchRuleDTO{}, err
+	}
+	return domain.NewSearchRuleDTO(newRule), nil
+}
+
+// DeleteSearchRule implements Service.
+func (s *service) DeleteSearchRule(ctx context.Context, id ulid.ULID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	currRule, err := s.repo.GetRuleByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = s.repo.DeleteRuleWithTransaction(ctx, tx, currRule)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewService(
+	repo Repo,
+	backend Backend,
+	facetRepo facet.Repo,
+	facetScope domain.FacetScope,
+	suggest domain.SuggestOptions,
+	db *pgxpool.Pool,
+) Service {
+	return &service{
+		repo:       repo,
+		backend:    backend,
+		facetRepo:  facetRepo,
+		facetScope: facetScope,
+		suggest:    suggest,
+		db:         db,
+	}
+}
@@ -0,0 +1,241 @@
+package tag
+
+import (
+	"context"
+	"flukis/product/domain"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+)
+
+type Repo interface {
+	SaveWithTransaction(ctx context.Context, tx pgx.Tx, tag *domain.Tag) error
+	GetByPrefix(ctx context.Context, prefix string, limit int) ([]domain.Tag, error)
+	GetByProductID(ctx context.Context, id ulid.ULID) ([]domain.Tag, error)
+	GetByVariantID(ctx context.Context, id ulid.ULID) ([]domain.Tag, error)
+	SaveProductTagWithTransaction(ctx context.Context, tx pgx.Tx, productId ulid.ULID, tag *domain.Tag) error
+	DeleteProductTagWithTransaction(ctx context.Context, tx pgx.Tx, productId ulid.ULID, name string) error
+	SaveVariantTagWithTransaction(ctx context.Context, tx pgx.Tx, variantId ulid.ULID, tag *domain.Tag) error
+	DeleteVariantTagWithTransaction(ctx context.Context, tx pgx.Tx, variantId ulid.ULID, name string) error
+}
+
+type repo struct {
+	db *pgxpool.Pool
+}
+
+// SaveWithTransaction implements Repo. Tag with same name is reused,
+// tag.TagID is set to the stored one.
+func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, tag *domain.Tag) error {
+	query := `
+		INSERT INTO Tag
+			(tag_id, name, created_at)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET
+			deleted_at = NULL
+		RETURNING tag_id
+	`
+	row := tx.QueryRow(
+		ctx,
+		query,
+		&tag.TagID,
+		&tag.Name,
+		&tag.CreatedAt,
+	)
+	return row.Scan(&tag.TagID)
+}
+
+// GetByPrefix implements Repo. Most used tag come first.
+func (r *repo) GetByPrefix(ctx context.Context, prefix string, limit int) ([]domain.Tag, error) {
+	query := `
+		SELECT
+			t.tag_id,
+			t.name
+		FROM Tag t
+		WHERE t.name LIKE $1 || '%' ESCAPE '\'
+			AND t.deleted_at IS NULL
+		ORDER BY
+			(
+				SELECT COUNT(*)
+				FROM Product_Tag pt
+				WHERE pt.tag_id = t.tag_id AND pt.deleted_at IS NULL
+			) + (
+				SELECT COUNT(*)
+				FROM Variant_Tag vt
+				WHERE vt.tag_id = t.tag_id AND vt.deleted_at IS NULL
+			) DESC,
+			t.name
+		LIMIT $2
+	`
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	rows, err := r.db.Query(ctx, query, escaper.Replace(domain.NormalizeTag(prefix)), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []domain.Tag
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.TagID, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetByProductID implements Repo.
+func (r *repo) GetByProductID(ctx context.Context, id ulid.ULID) ([]domain.Tag, error) {
+	query := `
+		SELECT
+			t.tag_id,
+			t.name
+		FROM Product_Tag pt
+		JOIN Tag t ON pt.tag_id = t.tag_id
+		WHERE pt.product_id = $1
+			AND pt.deleted_at IS NULL
+			AND t.deleted_at IS NULL
+		ORDER BY
+			t.name
+	`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []domain.Tag
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.TagID, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetByVariantID implements Repo.
+func (r *repo) GetByVariantID(ctx context.Context, id ulid.ULID) ([]domain.Tag, error) {
+	query := `
+		SELECT
+			t.tag_id,
+			t.name
+		FROM Variant_Tag vt
+		JOIN Tag t ON vt.tag_id = t.tag_id
+		WHERE vt.variant_id = $1
+			AND vt.deleted_at IS NULL
+			AND t.deleted_at IS NULL
+		ORDER BY
+			t.name
+	`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []domain.Tag
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.TagID, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (*repo) SaveProductTagWithTransaction(ctx context.Context, tx pgx.Tx, productId ulid.ULID, tag *domain.Tag) error {
+	query := `
+		INSERT INTO Product_Tag
+			(product_id, tag_id)
+		VALUES
+			($1, $2)
+		ON CONFLICT (product_id, tag_id) DO UPDATE SET
+			deleted_at = NULL
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		productId,
+		&tag.TagID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteProductTagWithTransaction(ctx context.Context, tx pgx.Tx, productId ulid.ULID, name string) error {
+	query := `
+		UPDATE Product_Tag SET
+			deleted_at = $1
+		WHERE
+			product_id = $2
+			AND tag_id = (SELECT tag_id FROM Tag WHERE name = $3)
+			AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		productId,
+		domain.NormalizeTag(name),
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) SaveVariantTagWithTransaction(ctx context.Context, tx pgx.Tx, variantId ulid.ULID, tag *domain.Tag) error {
+	query := `
+		INSERT INTO Variant_Tag
+			(variant_id, tag_id)
+		VALUES
+			($1, $2)
+		ON CONFLICT (variant_id, tag_id) DO UPDATE SET
+			deleted_at = NULL
+	`
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		variantId,
+		&tag.TagID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteVariantTagWithTransaction(ctx context.Context, tx pgx.Tx, variantId ulid.ULID, name string) error {
+	query := `
+		UPDATE Variant_Tag SET
+			deleted_at = $1
+		WHERE
+			variant_id = $2
+			AND tag_id = (SELECT tag_id FROM Tag WHERE name = $3)
+			AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		variantId,
+		domain.NormalizeTag(name),
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewRepo(db *pgxpool.Pool) Repo {
+	return &repo{
+		db: db,
+	}
+}
@@ -0,0 +1,66 @@
+package tag
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/resp"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultSuggestLimit = 10
+
+type Router struct {
+	service Service
+}
+
+func NewRouter(
+	service Service,
+) *Router {
+	return &Router{
+		service: service,
+	}
+}
+
+func (r *Router) Routes() *chi.Mux {
+	route := chi.NewMux()
+
+	route.Get("/", r.SuggestTagHandler)
+
+	return route
+}
+
+func (r *Router) SuggestTagHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	limitInt := defaultSuggestLimit
+	if limitStr := req.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limitInt, err = strconv.Atoi(limitStr)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	res, err := r.service.SuggestTag(ctx, req.URL.Query().Get("q"), limitInt)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidSuggestLimit) {
+			status = http.StatusBadRequest
+		}
+		if err = resp.WriteError(w, status, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get tag suggestion success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
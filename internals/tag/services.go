@@ -0,0 +1,39 @@
+package tag
+
+import (
+	"context"
+	"flukis/product/domain"
+)
+
+type Service interface {
+	SuggestTag(ctx context.Context, prefix string, limit int) ([]domain.TagsDTO, error)
+}
+
+type service struct {
+	repo Repo
+}
+
+// SuggestTag implements Service. limit is between 1 and MaxSuggestLimit.
+func (s *service) SuggestTag(ctx context.Context, prefix string, limit int) ([]domain.TagsDTO, error) {
+	if limit <= 0 || limit > domain.MaxSuggestLimit {
+		return []domain.TagsDTO{}, domain.ErrInvalidSuggestLimit
+	}
+	tags, err := s.repo.GetByPrefix(ctx, prefix, limit)
+	if err != nil {
+		return []domain.TagsDTO{}, err
+	}
+	var data = make([]domain.TagsDTO, len(tags))
+	for i := range tags {
+		data[i].ID = tags[i].TagID
+		data[i].Name = tags[i].Name
+	}
+	return data, nil
+}
+
+func NewService(
+	repo Repo,
+) Service {
+	return &service{
+		repo: repo,
+	}
+}
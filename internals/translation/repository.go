@@ -0,0 +1,224 @@
+package translation
+
+import (
+	"context"
+	"errors"
+	"flukis/product/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+)
+
+type Repo interface {
+	GetByEntityIDLocaleWithTransaction(ctx context.Context, tx pgx.Tx, entityType domain.EntityType, id ulid.ULID, locale string) (*domain.Translation, error)
+	GetByEntityID(ctx context.Context, entityType domain.EntityType, id ulid.ULID) ([]domain.Translation, error)
+	GetByEntityIDs(ctx context.Context, entityType domain.EntityType, ids []ulid.ULID, locales []string) (map[ulid.ULID]domain.Translation, error)
+	SaveWithTransaction(ctx context.Context, tx pgx.Tx, tr *domain.Translation) error
+	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, tr *domain.Translation) error
+}
+
+type repo struct {
+	db *pgxpool.Pool
+}
+
+// GetByEntityIDLocaleWithTransaction implements Repo.
+func (*repo) GetByEntityIDLocaleWithTransaction(ctx context.Context, tx pgx.Tx, entityType domain.EntityType, id ulid.ULID, locale string) (*domain.Translation, error) {
+	query := `
+		SELECT
+			translation_id,
+			entity_type,
+			entity_id,
+			locale,
+			name,
+			description
+		FROM Translation
+		WHERE entity_type = $1
+			AND entity_id = $2
+			AND locale = $3
+			AND deleted_at IS NULL
+	`
+	row := tx.QueryRow(
+		ctx,
+		query,
+		entityType,
+		id,
+		locale,
+	)
+	var tr domain.Translation
+	if err := row.Scan(
+		&tr.TranslationID,
+		&tr.EntityType,
+		&tr.EntityID,
+		&tr.Locale,
+		&tr.Name,
+		&tr.Description,
+	); err != nil {
+		return nil, err
+	}
+	return &tr, nil
+}
+
+// GetByEntityID implements Repo.
+func (r *repo) GetByEntityID(ctx context.Context, entityType domain.EntityType, id ulid.ULID) ([]domain.Translation, error) {
+	query := `
+		SELECT
+			translation_id,
+			entity_type,
+			entity_id,
+			locale,
+			name,
+			description
+		FROM Translation
+		WHERE entity_type = $1
+			AND entity_id = $2
+			AND deleted_at IS NULL
+		ORDER BY
+			locale
+	`
+	rows, err := r.db.Query(ctx, query, entityType, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var translations []domain.Translation
+	for rows.Next() {
+		var tr domain.Translation
+		if err := rows.Scan(
+			&tr.TranslationID,
+			&tr.EntityType,
+			&tr.EntityID,
+			&tr.Locale,
+			&tr.Name,
+			&tr.Description,
+		); err != nil {
+			return nil, err
+		}
+		translations = append(translations, tr)
+	}
+	return translations, rows.Err()
+}
+
+// GetByEntityIDs implements Repo. For each entity it return the
+// translation of the first locale in locales that exist.
+func (r *repo) GetByEntityIDs(ctx context.Context, entityType domain.EntityType, ids []ulid.ULID, locales []string) (map[ulid.ULID]domain.Translation, error) {
+	res := make(map[ulid.ULID]domain.Translation)
+	if len(ids) == 0 || len(locales) == 0 {
+		return res, nil
+	}
+	query := `
+		SELECT DISTINCT ON (entity_id)
+			translation_id,
+			entity_type,
+			entity_id,
+			locale,
+			name,
+			description
+		FROM Translation
+		WHERE entity_type = $1
+			AND entity_id = ANY($2)
+			AND locale = ANY($3)
+			AND deleted_at IS NULL
+		ORDER BY
+			entity_id, array_position($3, locale)
+	`
+	rawIds := make([][]byte, len(ids))
+	for i := range ids {
+		rawIds[i] = ids[i][:]
+	}
+	rows, err := r.db.Query(ctx, query, entityType, rawIds, locales)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tr domain.Translation
+		if err := rows.Scan(
+			&tr.TranslationID,
+			&tr.EntityType,
+			&tr.EntityID,
+			&tr.Locale,
+			&tr.Name,
+			&tr.Description,
+		); err != nil {
+			return nil, err
+		}
+		res[tr.EntityID] = tr
+	}
+	return res, rows.Err()
+}
+
+// SaveWithTransaction implements Repo. Existing translation of the same
+// locale is replaced, tr.TranslationID is set to the stored one. It return
+// ErrTranslationEntity when the entity does not exist.
+func (*repo) SaveWithTransaction(ctx context.Context, tx pgx.Tx, tr *domain.Translation) error {
+	query := `
+		INSERT INTO Translation
+			(translation_id, entity_type, entity_id, locale, name, description, created_at)
+		SELECT
+			$1, $2, $3, $4, $5, $6, $7
+		WHERE CASE $2
+			WHEN 'product' THEN EXISTS (
+				SELECT 1 FROM Product WHERE product_id = $3 AND deleted_at IS NULL
+			)
+			WHEN 'variant' THEN EXISTS (
+				SELECT 1 FROM Variant WHERE variant_id = $3 AND deleted_at IS NULL
+			)
+			WHEN 'category' THEN EXISTS (
+				SELECT 1 FROM Category WHERE category_id = $3 AND deleted_at IS NULL
+			)
+			ELSE FALSE
+		END
+		ON CONFLICT (entity_type, entity_id, locale) DO UPDATE SET
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			updated_at = EXCLUDED.created_at,
+			deleted_at = NULL
+		RETURNING translation_id
+	`
+	if err := tx.QueryRow(
+		ctx,
+		query,
+		&tr.TranslationID,
+		&tr.EntityType,
+		&tr.EntityID,
+		&tr.Locale,
+		&tr.Name,
+		&tr.Description,
+		&tr.CreatedAt,
+	).Scan(&tr.TranslationID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrTranslationEntity
+		}
+		return err
+	}
+	return nil
+}
+
+func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, tr *domain.Translation) error {
+	query := `
+		UPDATE Translation SET
+			deleted_at = $1
+		WHERE
+			translation_id = $2 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		currentTime,
+		&tr.TranslationID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewRepo(db *pgxpool.Pool) Repo {
+	return &repo{
+		db: db,
+	}
+}
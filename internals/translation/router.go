@@ -0,0 +1,136 @@
+package translation
+
+import (
+	"encoding/json"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/resp"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type Router struct {
+	service Service
+}
+
+func NewRouter(
+	service Service,
+) *Router {
+	return &Router{
+		service: service,
+	}
+}
+
+func (r *Router) Routes() *chi.Mux {
+	route := chi.NewMux()
+
+	route.Get("/{type}/{id}", r.GetTranslationsHandler)
+	route.Put("/{type}/{id}/{locale}", r.SaveTranslationHandler)
+	route.Delete("/{type}/{id}/{locale}", r.DeleteTranslationHandler)
+
+	return route
+}
+
+func (r *Router) GetTranslationsHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	entityType := domain.EntityType(chi.URLParam(req, "type"))
+	res, err := r.service.GetTranslations(ctx, entityType, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidEntityType) {
+			status = http.StatusBadRequest
+		}
+		if err = resp.WriteError(w, status, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get translations success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) SaveTranslationHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"desc"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	entityType := domain.EntityType(chi.URLParam(req, "type"))
+	locale := chi.URLParam(req, "locale")
+	res, err := r.service.SaveTranslation(ctx, entityType, id, locale, input.Name, input.Description)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidEntityType) || errors.Is(err, domain.ErrInvalidLocale) {
+			status = http.StatusBadRequest
+		}
+		if errors.Is(err, domain.ErrTranslationEntity) {
+			status = http.StatusNotFound
+		}
+		if err = resp.WriteError(w, status, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "save translation success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) DeleteTranslationHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	entityType := domain.EntityType(chi.URLParam(req, "type"))
+	locale := chi.URLParam(req, "locale")
+	err = r.service.DeleteTranslation(ctx, entityType, id, locale)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "delete translation success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
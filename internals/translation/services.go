@@ -0,0 +1,111 @@
+package translation
+
+import (
+	"context"
+	"flukis/product/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oklog/ulid/v2"
+)
+
+type Service interface {
+	GetTranslations(ctx context.Context, entityType domain.EntityType, id ulid.ULID) ([]domain.TranslationDTO, error)
+	SaveTranslation(ctx context.Context, entityType domain.EntityType, id ulid.ULID, locale, name, desc string) (domain.TranslationDTO, error)
+	DeleteTranslation(ctx context.Context, entityType domain.EntityType, id ulid.ULID, locale string) error
+}
+
+type service struct {
+	repo Repo
+	db   *pgxpool.Pool
+}
+
+// GetTranslations implements Service.
+func (s *service) GetTranslations(ctx context.Context, entityType domain.EntityType, id ulid.ULID) ([]domain.TranslationDTO, error) {
+	if !entityType.Valid() {
+		return []domain.TranslationDTO{}, domain.ErrInvalidEntityType
+	}
+	translations, err := s.repo.GetByEntityID(ctx, entityType, id)
+	if err != nil {
+		return []domain.TranslationDTO{}, err
+	}
+	var data = make([]domain.TranslationDTO, len(translations))
+	for i := range translations {
+		data[i].Locale = translations[i].Locale
+		data[i].Name = translations[i].Name
+		data[i].Description = translations[i].Description
+	}
+	return data, nil
+}
+
+// SaveTranslation implements Service.
+func (s *service) SaveTranslation(ctx context.Context, entityType domain.EntityType, id ulid.ULID, locale, name, desc string) (domain.TranslationDTO, error) {
+	newTr, err := domain.NewTranslation(entityType, id, locale, name, desc)
+	if err != nil {
+		return domain.TranslationDTO{}, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.TranslationDTO{}, err
+	}
+
+	err = s.repo.SaveWithTransaction(ctx, tx, &newTr)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.TranslationDTO{}, err
+		}
+		return domain.TranslationDTO{}, err
+	}
+
+	res := domain.TranslationDTO{
+		Locale:      newTr.Locale,
+		Name:        newTr.Name,
+		Description: newTr.Description,
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.TranslationDTO{}, err
+	}
+	return res, nil
+}
+
+// DeleteTranslation implements Service.
+func (s *service) DeleteTranslation(ctx context.Context, entityType domain.EntityType, id ulid.ULID, locale string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	currTr, err := s.repo.GetByEntityIDLocaleWithTransaction(ctx, tx, entityType, id, domain.NormalizeLocale(locale))
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = s.repo.DeleteWithTransaction(ctx, tx, currTr)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewService(
+	repo Repo,
+	db *pgxpool.Pool,
+) Service {
+	return &service{
+		repo: repo,
+		db:   db,
+	}
+}
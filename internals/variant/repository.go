@@ -4,6 +4,8 @@ import (
 	"context"
 	"flukis/product/domain"
 	"flukis/product/utils/helper"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -17,12 +19,16 @@ type Repo interface {
 	GetByID(ctx context.Context, id ulid.ULID) (*domain.Variant, error)
 	SaveWithTransaction(ctx context.Context, tx pgx.Tx, vrn *domain.Variant) error
 	EditWithTransaction(ctx context.Context, tx pgx.Tx, vrn *domain.Variant) error
+	EditShippingWithTransaction(ctx context.Context, tx pgx.Tx, vrn *domain.Variant) error
 	DeleteWithTransaction(ctx context.Context, tx pgx.Tx, vrn *domain.Variant) error
-	GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.Variant, string, error)
+	GetByCursor(ctx context.Context, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort) ([]domain.Variant, domain.Page, error)
+	GetByMainProductIDs(ctx context.Context, ids []ulid.ULID) (map[ulid.ULID][]domain.Variant, error)
+	GetSummaryByMainProductID(ctx context.Context, id ulid.ULID) (domain.VariantSummary, error)
 }
 
 type repo struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	cursors *helper.Cursors
 }
 
 // GetByID implements Repo.
@@ -37,7 +43,22 @@ func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID
 			p.name AS product_name,
 			p.description AS product_description,
 			p.price AS product_price,
-			p.image_preview
+			p.image_preview,
+			COALESCE(p.image_key, ''),
+			v.weight,
+			v.weight_unit,
+			v.length,
+			v.width,
+			v.height,
+			v.dimension_unit,
+			v.shipping_class,
+			p.weight,
+			p.weight_unit,
+			p.length,
+			p.width,
+			p.height,
+			p.dimension_unit,
+			p.shipping_class
 		FROM
 			Variant AS v
 		LEFT JOIN
@@ -63,6 +84,21 @@ func (*repo) GetByIDWithTransaction(ctx context.Context, tx pgx.Tx, id ulid.ULID
 		&mainProduct.Description,
 		&mainProduct.Price,
 		&mainProduct.ImagePreview,
+		&mainProduct.Image.Key,
+		&variant.Shipping.Weight,
+		&variant.Shipping.WeightUnit,
+		&variant.Shipping.Length,
+		&variant.Shipping.Width,
+		&variant.Shipping.Height,
+		&variant.Shipping.DimensionUnit,
+		&variant.Shipping.ShippingClass,
+		&mainProduct.Shipping.Weight,
+		&mainProduct.Shipping.WeightUnit,
+		&mainProduct.Shipping.Length,
+		&mainProduct.Shipping.Width,
+		&mainProduct.Shipping.Height,
+		&mainProduct.Shipping.DimensionUnit,
+		&mainProduct.Shipping.ShippingClass,
 	); err != nil {
 		return nil, err
 	}
@@ -83,7 +119,23 @@ func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Variant, erro
 			p.name AS product_name,
 			p.description AS product_description,
 			p.price AS product_price,
-			p.image_preview
+			p.image_preview,
+			COALESCE(p.image_key, ''),
+			COALESCE(p.image_hash, ''),
+			v.weight,
+			v.weight_unit,
+			v.length,
+			v.width,
+			v.height,
+			v.dimension_unit,
+			v.shipping_class,
+			p.weight,
+			p.weight_unit,
+			p.length,
+			p.width,
+			p.height,
+			p.dimension_unit,
+			p.shipping_class
 		FROM
 			Variant AS v
 		LEFT JOIN
@@ -109,6 +161,22 @@ func (r *repo) GetByID(ctx context.Context, id ulid.ULID) (*domain.Variant, erro
 		&mainProduct.Description,
 		&mainProduct.Price,
 		&mainProduct.ImagePreview,
+		&mainProduct.Image.Key,
+		&mainProduct.Image.Hash,
+		&variant.Shipping.Weight,
+		&variant.Shipping.WeightUnit,
+		&variant.Shipping.Length,
+		&variant.Shipping.Width,
+		&variant.Shipping.Height,
+		&variant.Shipping.DimensionUnit,
+		&variant.Shipping.ShippingClass,
+		&mainProduct.Shipping.Weight,
+		&mainProduct.Shipping.WeightUnit,
+		&mainProduct.Shipping.Length,
+		&mainProduct.Shipping.Width,
+		&mainProduct.Shipping.Height,
+		&mainProduct.Shipping.DimensionUnit,
+		&mainProduct.Shipping.ShippingClass,
 	); err != nil {
 		return nil, err
 	}
@@ -164,6 +232,39 @@ func (*repo) EditWithTransaction(ctx context.Context, tx pgx.Tx, vrn *domain.Var
 	return nil
 }
 
+func (*repo) EditShippingWithTransaction(ctx context.Context, tx pgx.Tx, vrn *domain.Variant) error {
+	query := `
+		UPDATE Variant SET
+			weight = $1,
+			weight_unit = $2,
+			length = $3,
+			width = $4,
+			height = $5,
+			dimension_unit = $6,
+			shipping_class = $7,
+			updated_at = $8
+		WHERE
+			variant_id = $9 AND deleted_at IS NULL
+	`
+	currentTime := time.Now()
+	if _, err := tx.Exec(
+		ctx,
+		query,
+		&vrn.Shipping.Weight,
+		&vrn.Shipping.WeightUnit,
+		&vrn.Shipping.Length,
+		&vrn.Shipping.Width,
+		&vrn.Shipping.Height,
+		&vrn.Shipping.DimensionUnit,
+		&vrn.Shipping.ShippingClass,
+		currentTime,
+		&vrn.VariantID,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, vrn *domain.Variant) error {
 	query := `
 		UPDATE Variant SET
@@ -183,35 +284,52 @@ func (*repo) DeleteWithTransaction(ctx context.Context, tx pgx.Tx, vrn *domain.V
 	return nil
 }
 
-func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]domain.Variant, string, error) {
-	query := `
+// GetByCursor implements Repo. Page after, or before, cursor in the given
+// order, the cursor carry the sort value and id of the variant it point
+// at. In offset mode the page is read by number instead. Category filter
+// match the category of the main product, attribute filter match the
+// variant value or, when the variant does not set it, the main product
+// value.
+func (r *repo) GetByCursor(ctx context.Context, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort) ([]domain.Variant, domain.Page, error) {
+	decodedCursor, after, err := r.cursors.DecodeSort(req.Cursor, sort, filter.Scope())
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to decode cursor")
+		return nil, domain.Page{}, err
+	}
+	sortColumn := variantSortColumns[sort.Field]
+	compare, direction := sort.Keyset(decodedCursor)
+
+	from, filterArgs := variantListFrom(filter)
+	args := slices.Clone(filterArgs)
+	keyset := ""
+	if req.Cursor != "" {
+		args = append(args, after, decodedCursor.ID)
+		keyset = fmt.Sprintf("AND (%s, v.variant_id) %s ($%d, $%d)", sortColumn, compare, len(args)-1, len(args))
+	}
+	limit, args := helper.PageLimit(req, args)
+
+	query := fmt.Sprintf(`
 		SELECT
 			v.variant_id,
 			v.name AS variant_name,
 			v.description AS variant_description,
 			v.price AS variant_price,
+			v.created_at,
+			COALESCE(v.updated_at, v.created_at),
 			p.product_id,
 			p.name AS product_name,
-			p.image_preview
-		FROM
-			Variant AS v
-		LEFT JOIN
-			Product AS p ON v.main_product_id = p.product_id
-		WHERE
-			v.created_at > $1 AND v.deleted_at IS NULL AND p.deleted_at is NULL
+			p.image_preview,
+			COALESCE(p.image_key, '')
+		%s
+			%s
 		ORDER BY
-			v.created_at
-		LIMIT $2
-	`
-	decodedCursor, err := helper.DecodeCursor(cursor)
-	if err != nil && cursor != "" {
-		log.Warn().Err(err).Msg("failed to decode cursor")
-		return nil, "", err
-	}
+			%s %s, v.variant_id %s
+		%s
+	`, from, keyset, sortColumn, direction, direction, limit)
 
-	rows, err := r.db.Query(ctx, query, decodedCursor, limit)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, "", err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
@@ -224,26 +342,184 @@ func (r *repo) GetByCursor(ctx context.Context, limit int, cursor string) ([]dom
 			&variant.Name,
 			&variant.Description,
 			&variant.Price,
+			&variant.CreatedAt,
+			&variant.UpdatedAt,
 			&product.ProductID,
 			&product.Name,
 			&product.ImagePreview,
+			&product.Image.Key,
 		); err != nil {
-			return nil, "", err
+			return nil, domain.Page{}, err
 		}
 		variant.MainProduct = product
 		variants = append(variants, variant)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
+	}
+
+	var page domain.Page
+	if req.OffsetMode() {
+		variants, page = helper.OffsetPage(variants, req.Limit)
+	} else {
+		variants, page = helper.CursorPage(r.cursors, filter.Scope(), variants, req.Limit, decodedCursor, func(v domain.Variant) domain.ListCursor {
+			return domain.ListCursor{
+				Sort:  sort.String(),
+				Value: sort.SortValue(v.Name, v.Price, v.CreatedAt, v.UpdatedAt),
+				ID:    v.VariantID,
+			}
+		})
+	}
+	if err := helper.PageTotal(ctx, r.db, req, &page, "SELECT v.variant_id "+from, filterArgs...); err != nil {
+		return nil, domain.Page{}, err
+	}
+	return variants, page, nil
+}
+
+// variantListFrom select the variants passing filter, it is shared by
+// the listing and its count.
+func variantListFrom(filter domain.ListFilter) (string, []any) {
+	var mainId *ulid.ULID
+	if filter.MainID != (ulid.ULID{}) {
+		mainId = &filter.MainID
+	}
+	where, args := helper.VariantFilter(filter, []any{mainId})
+	return `
+	FROM
+		Variant AS v
+	LEFT JOIN
+		Product AS p ON v.main_product_id = p.product_id
+	WHERE
+		v.deleted_at IS NULL AND p.deleted_at is NULL
+		AND ($1::bytea IS NULL OR v.main_product_id = $1)
+		AND ` + where, args
+}
+
+// GetByMainProductIDs implements Repo. It return the variants of every
+// product in one query, keyed by main product id, oldest first.
+func (r *repo) GetByMainProductIDs(ctx context.Context, ids []ulid.ULID) (map[ulid.ULID][]domain.Variant, error) {
+	res := make(map[ulid.ULID][]domain.Variant)
+	if len(ids) == 0 {
+		return res, nil
+	}
+	query := `
+		SELECT
+			v.variant_id,
+			v.name AS variant_name,
+			v.description AS variant_description,
+			v.price AS variant_price,
+			v.created_at,
+			COALESCE(v.updated_at, v.created_at),
+			p.product_id,
+			p.name AS product_name,
+			p.image_preview,
+			COALESCE(p.image_key, '')
+		FROM
+			Variant AS v
+		JOIN
+			Product AS p ON v.main_product_id = p.product_id
+		WHERE
+			v.main_product_id = ANY($1) AND v.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY
+			v.created_at, v.variant_id
+	`
+	rawIds := make([][]byte, len(ids))
+	for i := range ids {
+		rawIds[i] = ids[i][:]
+	}
+	rows, err := r.db.Query(ctx, query, rawIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var variant domain.Variant
+		if err := rows.Scan(
+			&variant.VariantID,
+			&variant.Name,
+			&variant.Description,
+			&variant.Price,
+			&variant.CreatedAt,
+			&variant.UpdatedAt,
+			&variant.MainProduct.ProductID,
+			&variant.MainProduct.Name,
+			&variant.MainProduct.ImagePreview,
+			&variant.MainProduct.Image.Key,
+		); err != nil {
+			return nil, err
+		}
+		res[variant.MainProduct.ProductID] = append(res[variant.MainProduct.ProductID], variant)
+	}
+	return res, rows.Err()
+}
+
+// GetSummaryByMainProductID implements Repo. Axes are ordered by
+// attribute name and their values alphabetically.
+func (r *repo) GetSummaryByMainProductID(ctx context.Context, id ulid.ULID) (domain.VariantSummary, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			MIN(price)::float8,
+			MAX(price)::float8
+		FROM Variant
+		WHERE main_product_id = $1 AND deleted_at IS NULL
+	`
+	res := domain.VariantSummary{
+		Axes: []domain.VariantAxis{},
+	}
+	if err := r.db.QueryRow(ctx, query, id).Scan(&res.Count, &res.MinPrice, &res.MaxPrice); err != nil {
+		return domain.VariantSummary{}, err
+	}
+	if res.Count == 0 {
+		return res, nil
+	}
 
-	nextCursor := ""
-	if len(variants) == limit {
-		nextCursor = helper.EncodeCursor(variants[len(variants)-1].CreatedAt)
+	query = `
+		SELECT DISTINCT
+			a.attribute_id,
+			a.name,
+			va.value
+		FROM Variant_Attribute va
+		JOIN Variant v ON va.variant_id = v.variant_id
+		JOIN Attribute a ON va.attribute_id = a.attribute_id
+		WHERE v.main_product_id = $1
+			AND v.deleted_at IS NULL
+			AND va.deleted_at IS NULL
+			AND a.deleted_at IS NULL
+	`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return domain.VariantSummary{}, err
 	}
+	defer rows.Close()
+
+	var values []domain.VariantAxisValue
+	for rows.Next() {
+		var value domain.VariantAxisValue
+		if err := rows.Scan(&value.ID, &value.Name, &value.Value); err != nil {
+			return domain.VariantSummary{}, err
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.VariantSummary{}, err
+	}
+	res.Axes = domain.NewVariantAxes(values)
+	return res, nil
+}
 
-	return variants, nextCursor, nil
+// variantSortColumns map sort field to the ordered column.
+var variantSortColumns = map[domain.SortField]string{
+	domain.SortCreated: "v.created_at",
+	domain.SortUpdated: "COALESCE(v.updated_at, v.created_at)",
+	domain.SortPrice:   "COALESCE(v.price, 0)",
+	domain.SortName:    "v.name",
 }
 
-func NewRepo(db *pgxpool.Pool) Repo {
+func NewRepo(db *pgxpool.Pool, cursors *helper.Cursors) Repo {
 	return &repo{
-		db: db,
+		db:      db,
+		cursors: cursors,
 	}
 }
@@ -2,11 +2,14 @@ package variant
 
 import (
 	"encoding/json"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
 	"flukis/product/utils/resp"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/oklog/ulid/v2"
 	"github.com/rs/zerolog/log"
 )
@@ -28,9 +31,14 @@ func (r *Router) Routes() *chi.Mux {
 
 	route.Post("/", r.CreateVariantHandler)
 	route.Get("/{id}", r.GetVariantOneByIDHandler)
+	route.Get("/{id}/image", r.GetVariantImageHandler)
+	route.Get("/{id}/image/{rendition}", r.GetVariantImageHandler)
 	route.Get("/", r.GetVariantsHandler)
 	route.Patch("/{id}", r.UpdateDataVariantHandler)
 	route.Delete("/{id}", r.DeleteVariantHandler)
+	route.Patch("/tag/{id}", r.UpdateTagVariantHandler)
+	route.Patch("/shipping/{id}", r.UpdateShippingVariantHandler)
+	route.Patch("/attribute/{id}", r.UpdateAttributeVariantHandler)
 
 	return route
 }
@@ -68,8 +76,16 @@ func (r *Router) GetVariantOneByIDHandler(w http.ResponseWriter, req *http.Reque
 		}
 		return
 	}
+	opts, err := domain.NewReadOptions(req.URL.Query(), domain.VariantIncludes)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
 	ctx := req.Context()
-	res, err := r.service.GetVariantByID(ctx, id)
+	res, err := r.service.GetVariantByID(ctx, id, helper.ParseLocales(req), opts.Include)
 	if err != nil {
 		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
 			log.Error().Err(err)
@@ -77,7 +93,15 @@ func (r *Router) GetVariantOneByIDHandler(w http.ResponseWriter, req *http.Reque
 		}
 		return
 	}
-	if err = resp.WriteResponse(w, "get one variant success", http.StatusOK, res, nil); err != nil {
+	data, err := resp.SelectFields(res, opts.Fields)
+	if err != nil {
+		if err = resp.WriteError(w, fieldsErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "get one variant success", http.StatusOK, data, nil); err != nil {
 		log.Error().Err(err)
 		return
 	}
@@ -85,8 +109,7 @@ func (r *Router) GetVariantOneByIDHandler(w http.ResponseWriter, req *http.Reque
 
 func (r *Router) GetVariantsHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
-	limitStr := req.URL.Query().Get("limit")
-	limitInt, err := strconv.Atoi(limitStr)
+	pageReq, err := domain.NewPageRequest(req.URL.Query())
 	if err != nil {
 		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
 			log.Error().Err(err)
@@ -94,27 +117,50 @@ func (r *Router) GetVariantsHandler(w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
-	cursor := req.URL.Query().Get("cursor")
-	res, length, next, err := r.service.GetVariantsByCursor(ctx, limitInt, cursor)
+	filter, err := domain.NewListFilter(req.URL.Query())
 	if err != nil {
-		if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
 			log.Error().Err(err)
 			return
 		}
 		return
 	}
-
-	var metaResp struct {
-		Limit    int    `json:"limit"`
-		ThisPage int    `json:"total_this_page"`
-		Next     string `json:"next_cursor"`
+	sort, err := domain.NewListSort(req.URL.Query().Get("sort"), req.URL.Query().Get("order"))
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	opts, err := domain.NewReadOptions(req.URL.Query(), domain.VariantIncludes)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	res, length, page, err := r.service.GetVariantsByCursor(ctx, pageReq, filter, sort, helper.ParseLocales(req), opts.Include)
+	if err != nil {
+		if err = resp.WriteError(w, listErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
 	}
 
-	metaResp.Limit = limitInt
-	metaResp.Next = next
-	metaResp.ThisPage = length
+	data, err := resp.SelectFields(res, opts.Fields)
+	if err != nil {
+		if err = resp.WriteError(w, fieldsErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	metaResp := domain.NewPageMeta(pageReq, page, length)
 
-	if err = resp.WriteResponse(w, "get all variants success", http.StatusOK, res, metaResp); err != nil {
+	if err = resp.WriteResponse(w, "get all variants success", http.StatusOK, data, metaResp); err != nil {
 		log.Error().Err(err)
 		return
 	}
@@ -201,3 +247,194 @@ func (r *Router) CreateVariantHandler(w http.ResponseWriter, req *http.Request)
 		return
 	}
 }
+
+func (r *Router) UpdateTagVariantHandler(w http.ResponseWriter, req *http.Request) {
+	variantId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(variantId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Added   []string `json:"added"`
+		Removed []string `json:"removed"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	if len(input.Added) > 0 {
+		err = r.service.UpdateTagVariant(ctx, id, input.Added)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrEmptyTag) {
+				status = http.StatusBadRequest
+			}
+			if err = resp.WriteError(w, status, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if len(input.Removed) > 0 {
+		err = r.service.DeleteTagVariantBatch(ctx, id, input.Removed)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if err = resp.WriteResponse(w, "update tag to variant success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) UpdateShippingVariantHandler(w http.ResponseWriter, req *http.Request) {
+	variantId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(variantId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input domain.ShippingDTO
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	res, err := r.service.UpdateShippingVariant(ctx, id, input)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidShipping) {
+			status = http.StatusBadRequest
+		}
+		if err = resp.WriteError(w, status, err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	if err = resp.WriteResponse(w, "update variant shipping success", http.StatusOK, res, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+func (r *Router) UpdateAttributeVariantHandler(w http.ResponseWriter, req *http.Request) {
+	variantId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(variantId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	var input struct {
+		Added   []domain.AttributeInput `json:"added"`
+		Removed []ulid.ULID             `json:"removed"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	if len(input.Added) > 0 {
+		err = r.service.UpdateAttributeVariant(ctx, id, input.Added)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrInvalidAttributeValue) {
+				status = http.StatusBadRequest
+			}
+			if err = resp.WriteError(w, status, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if len(input.Removed) > 0 {
+		err = r.service.DeleteAttributeVariantBatch(ctx, id, input.Removed)
+		if err != nil {
+			if err = resp.WriteError(w, http.StatusInternalServerError, err); err != nil {
+				log.Error().Err(err)
+				return
+			}
+			return
+		}
+	}
+	if err = resp.WriteResponse(w, "update attribute to variant success", http.StatusOK, nil, nil); err != nil {
+		log.Error().Err(err)
+		return
+	}
+}
+
+// GetVariantImageHandler stream the variant image, the "rendition" path param choose
+// one of the generated sizes and default to the preview.
+func (r *Router) GetVariantImageHandler(w http.ResponseWriter, req *http.Request) {
+	entityId := chi.URLParam(req, "id")
+	id, err := ulid.Parse(entityId)
+	if err != nil {
+		if err = resp.WriteError(w, http.StatusBadRequest, err); err != nil {
+			return
+		}
+		return
+	}
+	ctx := req.Context()
+	res, err := r.service.GetImageVariant(ctx, id, chi.URLParam(req, "rendition"), helper.AcceptsWebP(req))
+	if err != nil {
+		if err = resp.WriteError(w, imageErrorStatus(err), err); err != nil {
+			log.Error().Err(err)
+			return
+		}
+		return
+	}
+	helper.ServeImage(w, req, res.Data, res.ContentType, res.ETag)
+}
+
+func fieldsErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidFields) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func listErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func imageErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrImageNotFound) || errors.Is(err, pgx.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, domain.ErrInvalidRendition) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -3,22 +3,242 @@ package variant
 import (
 	"context"
 	"flukis/product/domain"
+	"flukis/product/internals/attribute"
+	"flukis/product/internals/image"
+	"flukis/product/internals/product_category"
+	"flukis/product/internals/search"
+	"flukis/product/internals/tag"
+	"flukis/product/internals/translation"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/oklog/ulid/v2"
 )
 
 type Service interface {
-	GetVariantByID(ctx context.Context, id ulid.ULID) (domain.VariantDTO, error)
+	GetVariantByID(ctx context.Context, id ulid.ULID, locales []string, include domain.Includes) (domain.VariantDetailDTO, error)
 	CreateVariant(ctx context.Context, name, desc string, price float64, mainId ulid.ULID) (domain.VariantDTO, error)
 	UpdateDataVariant(ctx context.Context, id ulid.ULID, name, desc string, price float64, mainId ulid.ULID) (domain.VariantDTO, error)
-	GetVariantsByCursor(ctx context.Context, limit int, cursor string) (res []domain.VariantDetailDTO, length int, nextCursor string, err error)
+	GetVariantsByCursor(ctx context.Context, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort, locales []string, include domain.Includes) (res []domain.VariantDetailDTO, length int, page domain.Page, err error)
 	DeleteVariant(ctx context.Context, id ulid.ULID) error
+	UpdateTagVariant(ctx context.Context, id ulid.ULID, tags []string) error
+	DeleteTagVariantBatch(ctx context.Context, id ulid.ULID, tags []string) error
+	UpdateShippingVariant(ctx context.Context, id ulid.ULID, input domain.ShippingDTO) (domain.VariantDTO, error)
+	UpdateAttributeVariant(ctx context.Context, id ulid.ULID, values []domain.AttributeInput) error
+	DeleteAttributeVariantBatch(ctx context.Context, id ulid.ULID, attributeIds []ulid.ULID) error
+	GetImageVariant(ctx context.Context, id ulid.ULID, rendition string, webp bool) (domain.ImageContent, error)
 }
 
 type service struct {
-	repo Repo
-	db   *pgxpool.Pool
+	repo                 Repo
+	tagRepo              tag.Repo
+	translationRepo      translation.Repo
+	attributeRepo        attribute.Repo
+	imageRepo            image.Repo
+	categoryRelationRepo product_category.Repo
+	blobs                *image.BlobStore
+	indexer              search.Indexer
+	db                   *pgxpool.Pool
+}
+
+func (s *service) UpdateAttributeVariant(ctx context.Context, id ulid.ULID, values []domain.AttributeInput) error {
+	assigned := make([]domain.AssignedAttribute, len(values))
+	for idx := range values {
+		attr, err := attribute.AssignValue(ctx, s.attributeRepo, values[idx].ID, values[idx].Value)
+		if err != nil {
+			return err
+		}
+		assigned[idx] = attr
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range assigned {
+		err = s.attributeRepo.SaveVariantValueWithTransaction(ctx, tx, id, &assigned[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	s.indexer.VariantChanged(ctx, id)
+	return nil
+}
+
+func (s *service) DeleteAttributeVariantBatch(ctx context.Context, id ulid.ULID, attributeIds []ulid.ULID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range attributeIds {
+		err = s.attributeRepo.DeleteVariantValueWithTransaction(ctx, tx, id, attributeIds[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	s.indexer.VariantChanged(ctx, id)
+	return nil
+}
+
+// localize replace variant and main product content with the best
+// translation for locales.
+func (s *service) localize(ctx context.Context, variants []domain.Variant, locales []string) error {
+	variantIds := make([]ulid.ULID, len(variants))
+	productIds := make([]ulid.ULID, len(variants))
+	for i := range variants {
+		variantIds[i] = variants[i].VariantID
+		productIds[i] = variants[i].MainProduct.ProductID
+	}
+	translatedVariants, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityVariant, variantIds, locales)
+	if err != nil {
+		return err
+	}
+	translatedProducts, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityProduct, productIds, locales)
+	if err != nil {
+		return err
+	}
+	for i := range variants {
+		if tr, ok := translatedVariants[variants[i].VariantID]; ok {
+			tr.Apply(&variants[i].Name, &variants[i].Description)
+		}
+		if tr, ok := translatedProducts[variants[i].MainProduct.ProductID]; ok {
+			tr.Apply(&variants[i].MainProduct.Name, &variants[i].MainProduct.Description)
+		}
+	}
+	return nil
+}
+
+func (s *service) UpdateShippingVariant(ctx context.Context, id ulid.ULID, input domain.ShippingDTO) (domain.VariantDTO, error) {
+	shipping, err := domain.NewShipping(
+		input.Weight,
+		input.Length,
+		input.Width,
+		input.Height,
+		input.WeightUnit,
+		input.DimensionUnit,
+		input.ShippingClass,
+	)
+	if err != nil {
+		return domain.VariantDTO{}, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.VariantDTO{}, err
+	}
+
+	currentPrd, err := s.repo.GetByIDWithTransaction(ctx, tx, id)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.VariantDTO{}, err
+		}
+		return domain.VariantDTO{}, err
+	}
+	currentPrd.Shipping = shipping
+
+	err = s.repo.EditShippingWithTransaction(ctx, tx, currentPrd)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return domain.VariantDTO{}, err
+		}
+		return domain.VariantDTO{}, err
+	}
+
+	res := domain.VariantDTO{
+		ID:              currentPrd.VariantID,
+		Name:            currentPrd.Name,
+		Description:     currentPrd.Description,
+		Price:           currentPrd.Price,
+		Image:           currentPrd.ImageURL(),
+		MainProductID:   currentPrd.MainProduct.ProductID,
+		MainProductName: currentPrd.MainProduct.Name,
+		Shipping:        domain.NewShippingDTO(currentPrd.Shipping.Inherit(currentPrd.MainProduct.Shipping)),
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.VariantDTO{}, err
+	}
+	return res, nil
+}
+
+func (s *service) UpdateTagVariant(ctx context.Context, id ulid.ULID, tags []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range tags {
+		newTag, err := domain.NewTag(tags[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		err = s.tagRepo.SaveWithTransaction(ctx, tx, &newTag)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+		err = s.tagRepo.SaveVariantTagWithTransaction(ctx, tx, id, &newTag)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	s.indexer.VariantChanged(ctx, id)
+	return nil
+}
+
+func (s *service) DeleteTagVariantBatch(ctx context.Context, id ulid.ULID, tags []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := range tags {
+		err = s.tagRepo.DeleteVariantTagWithTransaction(ctx, tx, id, tags[idx])
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	s.indexer.VariantChanged(ctx, id)
+	return nil
 }
 
 // DeleteVariant implements Service.
@@ -48,29 +268,133 @@ func (s *service) DeleteVariant(ctx context.Context, id ulid.ULID) error {
 	if err != nil {
 		return err
 	}
+	s.indexer.VariantChanged(ctx, id)
 	return nil
 }
 
-func (s *service) GetVariantsByCursor(ctx context.Context, limit int, cursor string) (res []domain.VariantDetailDTO, length int, nextCursor string, err error) {
-	prd, nextCursor, err := s.repo.GetByCursor(ctx, limit, cursor)
+// GetVariantsByCursor implements Service. Variants only carry their own
+// fields, relations in include are loaded for the whole page at once.
+func (s *service) GetVariantsByCursor(ctx context.Context, req domain.PageRequest, filter domain.ListFilter, sort domain.ListSort, locales []string, include domain.Includes) (res []domain.VariantDetailDTO, length int, page domain.Page, err error) {
+	prd, page, err := s.repo.GetByCursor(ctx, req, filter, sort)
 	if err != nil {
-		return []domain.VariantDetailDTO{}, 0, "", err
+		return []domain.VariantDetailDTO{}, 0, domain.Page{}, err
+	}
+	if err = s.localize(ctx, prd, locales); err != nil {
+		return []domain.VariantDetailDTO{}, 0, domain.Page{}, err
 	}
 	dataLen := len(prd)
 	if dataLen == 0 {
-		return []domain.VariantDetailDTO{}, 0, "", nil
+		return []domain.VariantDetailDTO{}, 0, page, nil
 	}
 	var data = make([]domain.VariantDetailDTO, dataLen)
 	for i := range prd {
-		data[i].ID = prd[i].VariantID
-		data[i].Name = prd[i].Name
-		data[i].Description = prd[i].Description
-		data[i].Price = prd[i].Price
-		data[i].Image = prd[i].MainProduct.ImagePreview
-		data[i].MainProductID = prd[i].MainProduct.ProductID
-		data[i].MainProductName = prd[i].MainProduct.Name
+		data[i].VariantDTO = domain.NewVariantDTO(prd[i])
+		if include.Has(domain.IncludeImages) {
+			data[i].Image = prd[i].ImageURL()
+		}
+	}
+	if include.Has(domain.IncludeCategories) {
+		if err = s.expandCategories(ctx, data, locales); err != nil {
+			return []domain.VariantDetailDTO{}, 0, domain.Page{}, err
+		}
+	}
+	if include.Has(domain.IncludeAttributes) {
+		if err = s.expandAttributes(ctx, data); err != nil {
+			return []domain.VariantDetailDTO{}, 0, domain.Page{}, err
+		}
+	}
+	if include.Has(domain.IncludeImages) {
+		if err = s.expandImages(ctx, data); err != nil {
+			return []domain.VariantDetailDTO{}, 0, domain.Page{}, err
+		}
+	}
+	return data, dataLen, page, nil
+}
+
+// expandCategories set the translated categories of the main product of
+// every variant of data.
+func (s *service) expandCategories(ctx context.Context, data []domain.VariantDetailDTO, locales []string) error {
+	mainIds := make([]ulid.ULID, len(data))
+	for i := range data {
+		mainIds[i] = data[i].MainProductID
+	}
+	relations, err := s.categoryRelationRepo.GetByProductIDs(ctx, mainIds)
+	if err != nil {
+		return err
+	}
+	var categoryIds []ulid.ULID
+	for _, rels := range relations {
+		for idx := range rels {
+			categoryIds = append(categoryIds, rels[idx].Category.CategoryID)
+		}
+	}
+	translated, err := s.translationRepo.GetByEntityIDs(ctx, domain.EntityCategory, categoryIds, locales)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		rels := relations[data[i].MainProductID]
+		categories := make([]domain.CategoriesDTO, len(rels))
+		for idx := range rels {
+			categories[idx] = domain.CategoriesDTO{
+				ID:          rels[idx].Category.CategoryID,
+				Name:        rels[idx].Category.Name,
+				Description: rels[idx].Category.Description,
+			}
+			if tr, ok := translated[categories[idx].ID]; ok {
+				tr.Apply(&categories[idx].Name, &categories[idx].Description)
+			}
+		}
+		data[i].Category = categories
+	}
+	return nil
+}
+
+// expandAttributes set the values the variants set themselves, like the
+// variant detail.
+func (s *service) expandAttributes(ctx context.Context, data []domain.VariantDetailDTO) error {
+	ids := make([]ulid.ULID, len(data))
+	for i := range data {
+		ids[i] = data[i].ID
+	}
+	attributes, err := s.attributeRepo.GetByVariantIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		data[i].Attributes = domain.NewAssignedAttributesDTO(attributes[data[i].ID])
+	}
+	return nil
+}
+
+// expandImages set the gallery of every variant, variant without gallery
+// show the one of its main product.
+func (s *service) expandImages(ctx context.Context, data []domain.VariantDetailDTO) error {
+	ids := make([]ulid.ULID, len(data))
+	mainIds := make([]ulid.ULID, len(data))
+	for i := range data {
+		ids[i] = data[i].ID
+		mainIds[i] = data[i].MainProductID
+	}
+	variantImages, err := s.imageRepo.GetByEntityIDs(ctx, domain.EntityVariant, ids)
+	if err != nil {
+		return err
+	}
+	productImages, err := s.imageRepo.GetByEntityIDs(ctx, domain.EntityProduct, mainIds)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		gallery := variantImages[data[i].ID]
+		if len(gallery) == 0 {
+			gallery = productImages[data[i].MainProductID]
+		}
+		if len(gallery) > 0 {
+			data[i].Images = domain.NewImagesDTO(gallery)
+			data[i].Image = domain.VariantImageURL(data[i].ID)
+		}
 	}
-	return data, dataLen, nextCursor, nil
+	return nil
 }
 
 // CreateVariant implements Service.
@@ -106,7 +430,7 @@ func (s *service) UpdateDataVariant(ctx context.Context, id ulid.ULID, name, des
 		Name:            currentPrd.Name,
 		Description:     currentPrd.Description,
 		Price:           currentPrd.Price,
-		Image:           currentPrd.MainProduct.ImagePreview,
+		Image:           currentPrd.ImageURL(),
 		MainProductID:   currentPrd.MainProduct.ProductID,
 		MainProductName: currentPrd.MainProduct.Name,
 	}
@@ -115,6 +439,7 @@ func (s *service) UpdateDataVariant(ctx context.Context, id ulid.ULID, name, des
 	if err != nil {
 		return domain.VariantDTO{}, err
 	}
+	s.indexer.VariantChanged(ctx, id)
 	return res, nil
 }
 
@@ -143,7 +468,7 @@ func (s *service) CreateVariant(ctx context.Context, name, desc string, price fl
 		Name:            newPrd.Name,
 		Description:     newPrd.Description,
 		Price:           newPrd.Price,
-		Image:           newPrd.MainProduct.ImagePreview,
+		Image:           newPrd.ImageURL(),
 		MainProductID:   newPrd.MainProduct.ProductID,
 		MainProductName: newPrd.MainProduct.Name,
 	}
@@ -152,33 +477,117 @@ func (s *service) CreateVariant(ctx context.Context, name, desc string, price fl
 	if err != nil {
 		return domain.VariantDTO{}, err
 	}
+	s.indexer.VariantChanged(ctx, newPrd.VariantID)
 	return res, nil
 }
 
 // GetVariantByID implements Service.
-func (s *service) GetVariantByID(ctx context.Context, id ulid.ULID) (domain.VariantDTO, error) {
+// Tags, attributes and images are always read, the categories of the main
+// product only when include ask for them.
+func (s *service) GetVariantByID(ctx context.Context, id ulid.ULID, locales []string, include domain.Includes) (domain.VariantDetailDTO, error) {
 	prd, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return domain.VariantDTO{}, err
+		return domain.VariantDetailDTO{}, err
 	}
-	res := domain.VariantDTO{
-		ID:              prd.VariantID,
-		Name:            prd.Name,
-		Description:     prd.Description,
-		Price:           prd.Price,
-		Image:           prd.MainProduct.ImagePreview,
-		MainProductID:   prd.MainProduct.ProductID,
-		MainProductName: prd.MainProduct.Name,
+	localized := []domain.Variant{*prd}
+	if err = s.localize(ctx, localized, locales); err != nil {
+		return domain.VariantDetailDTO{}, err
+	}
+	prd = &localized[0]
+	tags, err := s.tagRepo.GetByVariantID(ctx, id)
+	if err != nil {
+		return domain.VariantDetailDTO{}, err
+	}
+	var tagNames = make([]string, 0, len(tags))
+	for idx := range tags {
+		tagNames = append(tagNames, tags[idx].Name)
+	}
+	attributes, err := s.attributeRepo.GetByVariantID(ctx, id)
+	if err != nil {
+		return domain.VariantDetailDTO{}, err
+	}
+	images, err := s.imageRepo.GetByEntityID(ctx, domain.EntityVariant, id)
+	if err != nil {
+		return domain.VariantDetailDTO{}, err
+	}
+	if len(images) == 0 {
+		images, err = s.imageRepo.GetByEntityID(ctx, domain.EntityProduct, prd.MainProduct.ProductID)
+		if err != nil {
+			return domain.VariantDetailDTO{}, err
+		}
+	}
+	imageURL := prd.ImageURL()
+	if len(images) > 0 {
+		imageURL = domain.VariantImageURL(id)
+	}
+	res := domain.VariantDetailDTO{
+		VariantDTO: domain.VariantDTO{
+			ID:              prd.VariantID,
+			Name:            prd.Name,
+			Description:     prd.Description,
+			Price:           prd.Price,
+			Image:           imageURL,
+			MainProductID:   prd.MainProduct.ProductID,
+			MainProductName: prd.MainProduct.Name,
+			Tags:            tagNames,
+			Attributes:      domain.NewAssignedAttributesDTO(attributes),
+			Shipping:        domain.NewShippingDTO(prd.Shipping.Inherit(prd.MainProduct.Shipping)),
+			Images:          domain.NewImagesDTO(images),
+		},
+	}
+	if include.Has(domain.IncludeCategories) {
+		data := []domain.VariantDetailDTO{res}
+		if err = s.expandCategories(ctx, data, locales); err != nil {
+			return domain.VariantDetailDTO{}, err
+		}
+		res = data[0]
 	}
 	return res, nil
 }
 
+// GetImageVariant implements Service. Variant without gallery use the
+// image of its main product.
+func (s *service) GetImageVariant(ctx context.Context, id ulid.ULID, rendition string, webp bool) (domain.ImageContent, error) {
+	prd, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.ImageContent{}, err
+	}
+	images, err := s.imageRepo.GetByEntityID(ctx, domain.EntityVariant, id)
+	if err != nil {
+		return domain.ImageContent{}, err
+	}
+	if len(images) == 0 {
+		images, err = s.imageRepo.GetByEntityID(ctx, domain.EntityProduct, prd.MainProduct.ProductID)
+		if err != nil {
+			return domain.ImageContent{}, err
+		}
+	}
+	if primary, ok := domain.PrimaryImage(images); ok {
+		return s.blobs.Open(ctx, primary.Blob, primary.Data, rendition, webp)
+	}
+	return s.blobs.Open(ctx, prd.MainProduct.Image, prd.MainProduct.ImagePreview, rendition, webp)
+}
+
 func NewService(
 	repo Repo,
+	tagRepo tag.Repo,
+	translationRepo translation.Repo,
+	attributeRepo attribute.Repo,
+	imageRepo image.Repo,
+	categoryRelationRepo product_category.Repo,
+	blobs *image.BlobStore,
+	indexer search.Indexer,
 	db *pgxpool.Pool,
 ) Service {
 	return &service{
-		repo: repo,
-		db:   db,
+		repo:                 repo,
+		tagRepo:              tagRepo,
+		translationRepo:      translationRepo,
+		attributeRepo:        attributeRepo,
+		imageRepo:            imageRepo,
+		categoryRelationRepo: categoryRelationRepo,
+		blobs:                blobs,
+		indexer:              indexer,
+		db:                   db,
 	}
 }
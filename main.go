@@ -5,10 +5,19 @@ import (
 	"flukis/product/cmd"
 	"flukis/product/config"
 	"flukis/product/internals/attribute"
+	"flukis/product/internals/brand"
 	"flukis/product/internals/category"
+	"flukis/product/internals/category_attribute"
+	"flukis/product/internals/facet"
+	"flukis/product/internals/image"
 	"flukis/product/internals/product"
 	"flukis/product/internals/product_category"
+	"flukis/product/internals/product_relation"
+	"flukis/product/internals/search"
+	"flukis/product/internals/tag"
+	"flukis/product/internals/translation"
 	"flukis/product/internals/variant"
+	"flukis/product/utils/helper"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -37,39 +46,131 @@ func main() {
 		log.Fatal().Err(err).Msg("failed to load file .env")
 	}
 
+	// blob storage
+	blobStorage, err := cfg.Storage.Open()
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to open blob storage")
+	}
+	imageBlobs := image.NewBlobStore(blobStorage, cfg.Image.Renditions())
+
+	// listing cursors
+	if cfg.Pagination.CursorSecret == "" {
+		log.Warn().Msg("CURSOR_SECRET is not set, cursors will not survive a restart")
+	}
+	cursors := helper.NewCursors(cfg.Pagination.CursorKey())
+
+	// translation
+	translationRepo := translation.NewRepo(pool)
+	translationSvc := translation.NewService(
+		translationRepo,
+		pool,
+	)
+	translationRouter := translation.NewRouter(translationSvc)
+
 	// attr
-	attributeRepo := attribute.NewRepo(pool)
+	attributeRepo := attribute.NewRepo(pool, cursors)
 	attributeSvc := attribute.NewService(
 		attributeRepo,
 		pool,
 	)
 	attributeRouter := attribute.NewRouter(attributeSvc)
 
+	// search backend, told of catalog changes by the services below
+	searchRepo := search.NewRepo(pool, cursors)
+	searchBackend, searchIndexer, err := search.NewBackend(ctx, cfg.Search.Backend, searchRepo, cursors)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to open search backend")
+	}
+
 	// attr
-	categoryRepo := category.NewRepo(pool)
+	categoryAttributeRepo := category_attribute.NewRepo(pool)
+	categoryRepo := category.NewRepo(pool, cursors)
 	categorySvc := category.NewService(
 		categoryRepo,
+		translationRepo,
+		categoryAttributeRepo,
+		searchIndexer,
 		pool,
 	)
 	categoryRouter := category.NewRouter(categorySvc)
 
+	// brand
+	brandRepo := brand.NewRepo(pool, cursors)
+	brandSvc := brand.NewService(
+		brandRepo,
+		pool,
+	)
+	brandRouter := brand.NewRouter(brandSvc)
+
+	// tag
+	tagRepo := tag.NewRepo(pool)
+	tagSvc := tag.NewService(
+		tagRepo,
+	)
+	tagRouter := tag.NewRouter(tagSvc)
+
+	// facet
+	facetRepo := facet.NewRepo(pool)
+
+	// image
+	imageRepo := image.NewRepo(pool)
+	imageSvc := image.NewService(
+		imageRepo,
+		imageBlobs,
+		searchIndexer,
+		pool,
+	)
+	imageRouter := image.NewRouter(imageSvc, cfg.Image.Rules())
+
 	// attr
-	productVariantRepo := variant.NewRepo(pool)
+	productCategory := product_category.NewRepo(pool, cursors)
+	productVariantRepo := variant.NewRepo(pool, cursors)
 	productVariantSvc := variant.NewService(
 		productVariantRepo,
+		tagRepo,
+		translationRepo,
+		attributeRepo,
+		imageRepo,
+		productCategory,
+		imageBlobs,
+		searchIndexer,
 		pool,
 	)
 	productVariantRouter := variant.NewRouter(productVariantSvc)
 
 	// attr
-	productCategory := product_category.NewRepo(pool)
-	productRepo := product.NewRepo(pool)
+	productRelation := product_relation.NewRepo(pool)
+	productRepo := product.NewRepo(pool, cursors)
 	productSvc := product.NewService(
 		productRepo,
 		productCategory,
+		productRelation,
+		tagRepo,
+		brandRepo,
+		translationRepo,
+		attributeRepo,
+		categoryAttributeRepo,
+		imageRepo,
+		productVariantRepo,
+		productVariantSvc,
+		facetRepo,
+		cfg.Search.FacetScope(),
+		imageBlobs,
+		searchIndexer,
+		pool,
+	)
+	productRouter := product.NewRouter(productSvc, cfg.Image.Rules(), int(cfg.Image.DuplicateDistance))
+
+	// search
+	searchSvc := search.NewService(
+		searchRepo,
+		searchBackend,
+		facetRepo,
+		cfg.Search.FacetScope(),
+		cfg.Search.SuggestOptions(),
 		pool,
 	)
-	productRouter := product.NewRouter(productSvc)
+	searchRouter := search.NewRouter(searchSvc)
 
 	// Create router.
 	r := chi.NewRouter()
@@ -86,8 +187,13 @@ func main() {
 
 	r.Mount("/attribute", attributeRouter.Routes())
 	r.Mount("/category", categoryRouter.Routes())
+	r.Mount("/image", imageRouter.Routes())
+	r.Mount("/brand", brandRouter.Routes())
 	r.Mount("/product", productRouter.Routes())
+	r.Mount("/search", searchRouter.Routes())
 	r.Mount("/variant", productVariantRouter.Routes())
+	r.Mount("/tag", tagRouter.Routes())
+	r.Mount("/translation", translationRouter.Routes())
 
 	// Run server instance.
 	log.Info().Msg("starting up server...")
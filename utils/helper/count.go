@@ -0,0 +1,86 @@
+package helper
+
+import (
+	"context"
+	"encoding/json"
+	"flukis/product/domain"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gopkg.in/guregu/null.v4"
+)
+
+// exactCountBelow is the estimated size under which a cursor listing is
+// still counted exactly, above it counting cost about as much as reading
+// the whole listing.
+const exactCountBelow = 10000
+
+// PageLimit append the limit, and the offset in offset mode, of req to args
+// and return the clause using them. One more item than the page is read to
+// know whether the listing go on.
+func PageLimit(req domain.PageRequest, args []any) (string, []any) {
+	args = append(args, req.Limit+1)
+	clause := fmt.Sprintf("LIMIT $%d", len(args))
+	if req.OffsetMode() {
+		args = append(args, req.Offset())
+		clause += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return clause, args
+}
+
+// OffsetPage trim the limit+1 items read for a numbered page.
+func OffsetPage[T any](items []T, limit int) ([]T, domain.Page) {
+	var page domain.Page
+	if len(items) > limit {
+		items = items[:limit]
+		page.HasMore = true
+	}
+	return items, page
+}
+
+// PageTotal set the total of page when req ask for it. query select the
+// whole listing without order nor limit. Offset mode is counted exactly,
+// cursor mode get the planner estimate when the listing is large.
+func PageTotal(ctx context.Context, db *pgxpool.Pool, req domain.PageRequest, page *domain.Page, query string, args ...any) error {
+	if !req.OffsetMode() && !req.WithTotal {
+		return nil
+	}
+	if !req.OffsetMode() {
+		estimate, err := estimateRows(ctx, db, query, args...)
+		if err != nil {
+			return err
+		}
+		if estimate >= exactCountBelow {
+			page.Total = null.IntFrom(estimate)
+			page.TotalEstimated = true
+			return nil
+		}
+	}
+	var total int64
+	if err := db.QueryRow(ctx, "SELECT COUNT(*) FROM ("+query+") AS listing", args...).Scan(&total); err != nil {
+		return err
+	}
+	page.Total = null.IntFrom(total)
+	return nil
+}
+
+// estimateRows return the number of rows the planner expect from query,
+// it is read from the table statistics and cost nothing to get.
+func estimateRows(ctx context.Context, db *pgxpool.Pool, query string, args ...any) (int64, error) {
+	var raw []byte
+	if err := db.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&raw); err != nil {
+		return 0, err
+	}
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil {
+		return 0, err
+	}
+	if len(plans) == 0 {
+		return 0, nil
+	}
+	return int64(plans[0].Plan.Rows), nil
+}
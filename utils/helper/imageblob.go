@@ -4,47 +4,218 @@ import (
 	"bytes"
 	"errors"
 	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "image/jpeg"
-	_ "image/png"
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
 
-	"github.com/rs/zerolog/log"
+const (
+	jpegQuality       = 85
+	imageCacheControl = "public, max-age=300"
 )
 
-var (
-	imgHeigh = 400
-	imgWidth = 600
+// name of the generated renditions
+const (
+	RenditionPreview   = "preview"
+	RenditionThumbnail = "thumbnail"
+	RenditionLarge     = "large"
+	RenditionOriginal  = "original"
 )
 
-func UploadImageHandler(req *http.Request) (res []byte, err error) {
+// Rendition is one generated size of an uploaded image. Crop rendition
+// fill the whole box and cut the overflow, fit rendition keep the whole
+// image inside the box and is never upscaled.
+type Rendition struct {
+	Name   string
+	Width  int
+	Height int
+	Crop   bool
+}
+
+// multipart overhead allowed on top of the image size limit
+const formOverhead = 1 << 20
+
+// UploadImageHandler read the "image" file of a multipart request and
+// validate it against rules, see ValidateImage.
+func UploadImageHandler(w http.ResponseWriter, req *http.Request, rules ImageRules) ([]byte, error) {
+	if rules.MaxBytes > 0 {
+		req.Body = http.MaxBytesReader(w, req.Body, rules.MaxBytes+formOverhead)
+	}
 	imageFile, _, err := req.FormFile("image")
 	if err != nil {
-		return
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, imageError(http.StatusRequestEntityTooLarge, ImageCodeTooLarge, "image must be at most %d bytes", rules.MaxBytes)
+		}
+		return nil, imageError(http.StatusBadRequest, ImageCodeMissing, "image file is required: %v", err)
 	}
 	defer imageFile.Close()
 
 	var imageBytes bytes.Buffer
-	_, err = io.Copy(&imageBytes, imageFile)
-	if err != nil {
-		return
+	reader := io.Reader(imageFile)
+	if rules.MaxBytes > 0 {
+		reader = io.LimitReader(imageFile, rules.MaxBytes+1)
 	}
+	if _, err = io.Copy(&imageBytes, reader); err != nil {
+		return nil, err
+	}
+	return ValidateImage(imageBytes.Bytes(), rules)
+}
 
-	m, _, err := image.Decode(bytes.NewReader(imageBytes.Bytes()))
+// ServeImage write image bytes with caching header. Conditional and range
+// request are answered by http.ServeContent.
+func ServeImage(w http.ResponseWriter, req *http.Request, data []byte, contentType, etag string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", imageCacheControl)
+	w.Header().Set("ETag", `"`+etag+`"`)
+	w.Header().Set("Vary", "Accept")
+	http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(data))
+}
+
+// RenditionImage is one rendition encoded in the format of its source,
+// and as WebP when that is smaller.
+type RenditionImage struct {
+	Rendition Rendition
+	Data      []byte
+	WebP      []byte
+}
+
+// DecodeImage decode data and return the image with its format name. JPEG
+// is turned upright according to its EXIF orientation.
+func DecodeImage(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		log.Error().Err(err)
+		return nil, "", err
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, format, nil
+}
+
+// ResizeImage return every rendition of src decoded from format. Source
+// with transparency or a palette is encoded as PNG, anything else as JPEG.
+func ResizeImage(src image.Image, format string, renditions []Rendition) ([]RenditionImage, error) {
+	lossless := format == "png" || format == "gif" || !opaque(src)
+
+	res := make([]RenditionImage, 0, len(renditions))
+	for _, r := range renditions {
+		dst := scale(src, r)
+
+		var (
+			buf bytes.Buffer
+			err error
+		)
+		if lossless {
+			err = png.Encode(&buf, dst)
+		} else {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// the WebP encoder is lossless, for photo it is often bigger than
+		// the JPEG, so it is only kept when it save bandwidth and the
+		// JPEG/PNG is served otherwise
+		var webpBuf bytes.Buffer
+		if err = nativewebp.Encode(&webpBuf, dst, nil); err != nil {
+			return nil, err
+		}
+		var webpData []byte
+		if webpBuf.Len() < buf.Len() {
+			webpData = webpBuf.Bytes()
+		}
+
+		res = append(res, RenditionImage{
+			Rendition: r,
+			Data:      buf.Bytes(),
+			WebP:      webpData,
+		})
 	}
+	return res, nil
+}
 
-	bounds := m.Bounds()
-	w := bounds.Dx()
-	h := bounds.Dy()
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
+
+// AcceptsWebP report whether the Accept header of req allow image/webp,
+// that is list it with a quality above 0.
+func AcceptsWebP(req *http.Request) bool {
+	for _, accept := range req.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			params := strings.Split(part, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), "image/webp") {
+				continue
+			}
+			q := 1.0
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(param, "=")
+				if !strings.EqualFold(strings.TrimSpace(name), "q") {
+					continue
+				}
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+			if q > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scale(src image.Image, r Rendition) image.Image {
+	b := src.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if srcW == 0 || srcH == 0 || r.Width <= 0 || r.Height <= 0 {
+		return src
+	}
+
+	if !r.Crop {
+		w, h := srcW, srcH
+		if w > r.Width || h > r.Height {
+			// scale by the smaller ratio so both side fit the box
+			if srcW*r.Height > srcH*r.Width {
+				w, h = r.Width, max(1, srcH*r.Width/srcW)
+			} else {
+				w, h = max(1, srcW*r.Height/srcH), r.Height
+			}
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+		return dst
+	}
 
-	if w != imgWidth || h != imgHeigh {
-		err = errors.New("image dimension must 600x400")
-		return
+	// take the biggest centered part of source with the target aspect
+	// ratio, then scale it to the box
+	cropW, cropH := srcW, srcH
+	if srcW*r.Height > srcH*r.Width {
+		cropW = srcH * r.Width / r.Height
+	} else {
+		cropH = srcW * r.Height / r.Width
 	}
+	x0 := b.Min.X + (srcW-cropW)/2
+	y0 := b.Min.Y + (srcH-cropH)/2
+	crop := image.Rect(x0, y0, x0+cropW, y0+cropH)
 
-	res = imageBytes.Bytes()
-	return
+	dst := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	return dst
 }
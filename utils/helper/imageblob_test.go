@@ -0,0 +1,38 @@
+package helper_test
+
+import (
+	"flukis/product/utils/helper"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptsWebP(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept []string
+		want   bool
+	}{
+		{name: "no header", want: false},
+		{name: "listed", accept: []string{"image/avif,image/webp,*/*"}, want: true},
+		{name: "case insensitive", accept: []string{"Image/WebP"}, want: true},
+		{name: "positive quality", accept: []string{"image/webp;q=0.5"}, want: true},
+		{name: "quality with spaces", accept: []string{"image/webp ; Q = 0.001"}, want: true},
+		{name: "zero quality", accept: []string{"image/webp;q=0"}, want: false},
+		{name: "zero quality written long", accept: []string{"image/webp; q=0.000"}, want: false},
+		{name: "zero quality among other params", accept: []string{"image/webp;level=1;q=0.0"}, want: false},
+		{name: "malformed quality", accept: []string{"image/webp;q=high"}, want: false},
+		{name: "not listed", accept: []string{"image/png,image/*;q=0.8"}, want: false},
+		{name: "in a later header", accept: []string{"text/html", "image/webp"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/image", nil)
+			for _, v := range tt.accept {
+				req.Header.Add("Accept", v)
+			}
+			if got := helper.AcceptsWebP(req); got != tt.want {
+				t.Errorf("AcceptsWebP(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
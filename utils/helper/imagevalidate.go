@@ -0,0 +1,255 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flukis/product/utils/resp"
+	"fmt"
+	"image"
+	"net/http"
+)
+
+// machine readable code of rejected upload
+const (
+	ImageCodeMissing     = "image_missing"
+	ImageCodeTooLarge    = "image_too_large"
+	ImageCodeUnsupported = "image_unsupported_format"
+	ImageCodeNotAllowed  = "image_format_not_allowed"
+	ImageCodeTooManyPix  = "image_too_many_pixels"
+	ImageCodeAspectRatio = "image_aspect_ratio"
+	ImageCodeCorrupt     = "image_corrupt"
+)
+
+// ImageRules is what an uploaded image must satisfy. Zero value of a
+// limit disable the check.
+type ImageRules struct {
+	MaxBytes       int64
+	MaxPixels      int64
+	Formats        []string
+	MinAspectRatio float64
+	MaxAspectRatio float64
+}
+
+// ImageError is a rejected upload, Status is the http status to answer
+// with and Code tell the client which rule failed.
+type ImageError struct {
+	Status int
+	Code   string
+	Msg    string
+}
+
+func (e *ImageError) Error() string {
+	return e.Msg
+}
+
+func imageError(status int, code, format string, args ...any) *ImageError {
+	return &ImageError{
+		Status: status,
+		Code:   code,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// WriteImageError answer with the status and code of an ImageError, any
+// other error is a bad request.
+func WriteImageError(w http.ResponseWriter, err error) error {
+	var imgErr *ImageError
+	if errors.As(err, &imgErr) {
+		return resp.WriteErrorCode(w, imgErr.Status, imgErr.Code, imgErr)
+	}
+	return resp.WriteError(w, http.StatusBadRequest, err)
+}
+
+// magic bytes of supported format
+var imageSignatures = []struct {
+	format string
+	match  func([]byte) bool
+}{
+	{"jpeg", func(b []byte) bool { return bytes.HasPrefix(b, []byte("\xff\xd8\xff")) }},
+	{"png", func(b []byte) bool { return bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) }},
+	{"gif", func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte("GIF87a")) || bytes.HasPrefix(b, []byte("GIF89a"))
+	}},
+	{"webp", func(b []byte) bool {
+		return len(b) >= 12 && bytes.Equal(b[:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP"))
+	}},
+}
+
+// SniffImageFormat return the format named by the magic bytes of data, or
+// empty when it is not a known image.
+func SniffImageFormat(data []byte) string {
+	for _, sig := range imageSignatures {
+		if sig.match(data) {
+			return sig.format
+		}
+	}
+	return ""
+}
+
+// ValidateImage check data against rules without trusting the decoder
+// first: magic bytes, size and pixel count are checked before the image is
+// fully decoded. It return the data with metadata stripped.
+func ValidateImage(data []byte, rules ImageRules) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, imageError(http.StatusBadRequest, ImageCodeMissing, "image is empty")
+	}
+	if rules.MaxBytes > 0 && int64(len(data)) > rules.MaxBytes {
+		return nil, imageError(http.StatusRequestEntityTooLarge, ImageCodeTooLarge, "image must be at most %d bytes", rules.MaxBytes)
+	}
+
+	format := SniffImageFormat(data)
+	if format == "" {
+		return nil, imageError(http.StatusUnsupportedMediaType, ImageCodeUnsupported, "file is not a supported image")
+	}
+	if !allowedFormat(rules.Formats, format) {
+		return nil, imageError(http.StatusUnsupportedMediaType, ImageCodeNotAllowed, "image format %s is not allowed", format)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, imageError(http.StatusUnprocessableEntity, ImageCodeCorrupt, "invalid image: %v", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, imageError(http.StatusUnprocessableEntity, ImageCodeCorrupt, "invalid image: empty dimension")
+	}
+	if format == "jpeg" && jpegOrientation(data) >= 5 {
+		// shown rotated by a quarter turn
+		cfg.Width, cfg.Height = cfg.Height, cfg.Width
+	}
+	pixels := int64(cfg.Width) * int64(cfg.Height)
+	if rules.MaxPixels > 0 && pixels > rules.MaxPixels {
+		return nil, imageError(http.StatusUnprocessableEntity, ImageCodeTooManyPix, "image must be at most %d pixels, got %dx%d", rules.MaxPixels, cfg.Width, cfg.Height)
+	}
+	ratio := float64(cfg.Width) / float64(cfg.Height)
+	if (rules.MinAspectRatio > 0 && ratio < rules.MinAspectRatio) ||
+		(rules.MaxAspectRatio > 0 && ratio > rules.MaxAspectRatio) {
+		return nil, imageError(http.StatusUnprocessableEntity, ImageCodeAspectRatio, "image aspect ratio must be between %g and %g, got %dx%d", rules.MinAspectRatio, rules.MaxAspectRatio, cfg.Width, cfg.Height)
+	}
+
+	// header look fine, now make sure the whole image decode
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		return nil, imageError(http.StatusUnprocessableEntity, ImageCodeCorrupt, "invalid image: %v", err)
+	}
+	return StripMetadata(data, format), nil
+}
+
+func allowedFormat(formats []string, format string) bool {
+	if len(formats) == 0 {
+		return true
+	}
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// StripMetadata remove EXIF, XMP and text metadata without re-encoding the
+// pixels. Format without known metadata layout is returned as is.
+func StripMetadata(data []byte, format string) []byte {
+	switch format {
+	case "jpeg":
+		return stripJPEG(data)
+	case "png":
+		return stripPNG(data)
+	case "webp":
+		return stripWebP(data)
+	}
+	return data
+}
+
+// stripJPEG drop APP1 (EXIF and XMP) and APP13 (IPTC) segments. The EXIF
+// orientation is written back alone so rotated photo still display
+// upright. Everything from the start of scan marker is copied untouched.
+func stripJPEG(data []byte) []byte {
+	if len(data) < 2 {
+		return data
+	}
+	orientation := jpegOrientation(data)
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return data
+		}
+		marker := data[pos+1]
+		if marker == 0xda {
+			return append(out, data[pos:]...)
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return data
+		}
+		if marker == 0xe1 && orientation > 1 && bytes.HasPrefix(data[pos+4:end], []byte(exifHeader)) {
+			out = append(out, orientationSegment(orientation)...)
+			orientation = 1
+		}
+		if marker != 0xe1 && marker != 0xed {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return data
+}
+
+// stripPNG drop eXIf and the text chunks.
+func stripPNG(data []byte) []byte {
+	const headerLen = 8
+	if len(data) < headerLen {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:headerLen]...)
+	pos := headerLen
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return data
+		}
+		switch string(data[pos+4 : pos+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt":
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out
+}
+
+// stripWebP drop EXIF and XMP chunks of extended WebP and clear their flag
+// in the VP8X header.
+func stripWebP(data []byte) []byte {
+	const headerLen = 12
+	if len(data) < headerLen {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:headerLen]...)
+	pos := headerLen
+	for pos+8 <= len(data) {
+		length := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + length + length%2
+		if length < 0 || end > len(data) {
+			return data
+		}
+		switch string(data[pos : pos+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, data[pos:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out
+}
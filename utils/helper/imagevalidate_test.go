@@ -0,0 +1,236 @@
+package helper_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flukis/product/utils/helper"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withPNGText insert a tEXt chunk after the IHDR chunk.
+func withPNGText(data []byte, text string) []byte {
+	const ihdrEnd = 8 + 12 + 13
+	body := append([]byte("tEXt"), text...)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, body...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(body))
+
+	res := append([]byte{}, data[:ihdrEnd]...)
+	res = append(res, chunk...)
+	return append(res, data[ihdrEnd:]...)
+}
+
+// withJPEGSegment insert an APPn segment right after the SOI marker.
+func withJPEGSegment(data []byte, marker byte, payload []byte) []byte {
+	segment := []byte{0xff, marker}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(2+len(payload)))
+	segment = append(segment, payload...)
+
+	res := append([]byte{}, data[:2]...)
+	res = append(res, segment...)
+	return append(res, data[2:]...)
+}
+
+// exifPayload is a little endian EXIF holding the orientation and a
+// camera make.
+func exifPayload(orientation uint16) []byte {
+	tiff := []byte("II\x2a\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	// Make, ASCII, 4 bytes inline
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x010f)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 4)
+	tiff = append(tiff, "Cam\x00"...)
+	// Orientation, SHORT
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	return append([]byte("Exif\x00\x00"), tiff...)
+}
+
+func TestValidateImage(t *testing.T) {
+	photo := encodeJPEG(t, 40, 20)
+	rotated := withJPEGSegment(photo, 0xe1, exifPayload(6))
+	rules := helper.ImageRules{
+		MaxBytes:       1 << 20,
+		MaxPixels:      1000,
+		Formats:        []string{"jpeg", "png"},
+		MinAspectRatio: 0.5,
+		MaxAspectRatio: 2,
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		rules      helper.ImageRules
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "valid png", data: encodePNG(t, 20, 20), rules: rules},
+		{name: "valid jpeg", data: photo, rules: rules},
+		{name: "empty", data: nil, rules: rules, wantStatus: http.StatusBadRequest, wantCode: helper.ImageCodeMissing},
+		{name: "too large", data: photo, rules: helper.ImageRules{MaxBytes: 10}, wantStatus: http.StatusRequestEntityTooLarge, wantCode: helper.ImageCodeTooLarge},
+		{name: "not an image", data: []byte("hello world"), rules: rules, wantStatus: http.StatusUnsupportedMediaType, wantCode: helper.ImageCodeUnsupported},
+		{name: "format not allowed", data: []byte("GIF89a\x01\x00\x01\x00"), rules: rules, wantStatus: http.StatusUnsupportedMediaType, wantCode: helper.ImageCodeNotAllowed},
+		{name: "too many pixels", data: encodePNG(t, 40, 30), rules: rules, wantStatus: http.StatusUnprocessableEntity, wantCode: helper.ImageCodeTooManyPix},
+		{name: "too wide", data: encodePNG(t, 30, 10), rules: rules, wantStatus: http.StatusUnprocessableEntity, wantCode: helper.ImageCodeAspectRatio},
+		{name: "rotated jpeg checked upright", data: rotated, rules: helper.ImageRules{MaxAspectRatio: 1}},
+		{name: "jpeg too wide once upright", data: withJPEGSegment(photo, 0xe1, exifPayload(1)), rules: helper.ImageRules{MaxAspectRatio: 1}, wantStatus: http.StatusUnprocessableEntity, wantCode: helper.ImageCodeAspectRatio},
+		{name: "truncated header", data: encodePNG(t, 10, 10)[:20], rules: rules, wantStatus: http.StatusUnprocessableEntity, wantCode: helper.ImageCodeCorrupt},
+		{name: "truncated pixels", data: photo[:len(photo)/2], rules: rules, wantStatus: http.StatusUnprocessableEntity, wantCode: helper.ImageCodeCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := helper.ValidateImage(tt.data, tt.rules)
+			if tt.wantCode != "" {
+				var imgErr *helper.ImageError
+				if !errors.As(err, &imgErr) {
+					t.Fatalf("err = %v, want an ImageError", err)
+				}
+				if imgErr.Status != tt.wantStatus || imgErr.Code != tt.wantCode {
+					t.Errorf("error = %d %s, want %d %s", imgErr.Status, imgErr.Code, tt.wantStatus, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if _, _, err := image.Decode(bytes.NewReader(got)); err != nil {
+				t.Errorf("validated image does not decode: %v", err)
+			}
+		})
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	photo := encodeJPEG(t, 8, 8)
+	xmp := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), "<x:xmpmeta>secret</x:xmpmeta>"...)
+
+	tests := []struct {
+		name            string
+		data            []byte
+		format          string
+		gone            []string
+		wantOrientation bool
+	}{
+		{
+			name:   "png text",
+			data:   withPNGText(encodePNG(t, 4, 4), "Comment\x00secret"),
+			format: "png",
+			gone:   []string{"tEXt", "secret"},
+		},
+		{
+			name:   "jpeg exif and xmp",
+			data:   withJPEGSegment(withJPEGSegment(photo, 0xe1, exifPayload(1)), 0xe1, xmp),
+			format: "jpeg",
+			gone:   []string{"Exif", "Cam", "secret"},
+		},
+		{
+			name:            "jpeg orientation kept alone",
+			data:            withJPEGSegment(photo, 0xe1, exifPayload(6)),
+			format:          "jpeg",
+			gone:            []string{"Cam"},
+			wantOrientation: true,
+		},
+		{
+			name:   "unknown format untouched",
+			data:   []byte("GIF89a secret"),
+			format: "gif",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := helper.StripMetadata(tt.data, tt.format)
+			for _, s := range tt.gone {
+				if bytes.Contains(got, []byte(s)) {
+					t.Errorf("stripped image still contain %q", s)
+				}
+			}
+			if tt.format == "gif" {
+				if !bytes.Equal(got, tt.data) {
+					t.Errorf("unknown format was modified")
+				}
+				return
+			}
+			if _, _, err := helper.DecodeImage(got); err != nil {
+				t.Fatalf("stripped image does not decode: %v", err)
+			}
+			// orientation 6 is a quarter turn, the 8x8 photo stay square
+			// so the kept tag is checked on a wide one
+			if !tt.wantOrientation {
+				return
+			}
+			wide := withJPEGSegment(encodeJPEG(t, 8, 4), 0xe1, exifPayload(6))
+			img, _, err := helper.DecodeImage(helper.StripMetadata(wide, "jpeg"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 8 {
+				t.Errorf("stripped rotated photo bounds = %v, want 4x8", img.Bounds())
+			}
+		})
+	}
+}
+
+func TestPerceptualHash(t *testing.T) {
+	gradient := func(w, h int, reverse bool) image.Image {
+		img := image.NewGray(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				v := uint8((x*255/w + y*40/h) % 256)
+				if reverse {
+					v = 255 - v
+				}
+				img.SetGray(x, y, color.Gray{Y: v})
+			}
+		}
+		return img
+	}
+	distance := func(a, b uint64) int {
+		n := 0
+		for x := a ^ b; x != 0; x &= x - 1 {
+			n++
+		}
+		return n
+	}
+
+	base := helper.PerceptualHash(gradient(64, 48, false))
+	if got := helper.PerceptualHash(gradient(64, 48, false)); got != base {
+		t.Errorf("hash of the same image = %x, want %x", got, base)
+	}
+	if d := distance(base, helper.PerceptualHash(gradient(320, 240, false))); d > 4 {
+		t.Errorf("distance to a resized copy = %d, want at most 4", d)
+	}
+	if d := distance(base, helper.PerceptualHash(gradient(64, 48, true))); d < 32 {
+		t.Errorf("distance to the negative = %d, want at least 32", d)
+	}
+}
@@ -0,0 +1,156 @@
+package helper
+
+import (
+	"flukis/product/domain"
+	"fmt"
+)
+
+// productFilter is the predicate of ProductFilter, %[n]d is the position
+// of the n-th parameter of listFilterArgs.
+const productFilter = `(
+		COALESCE(cardinality($%[1]d::text[]), 0) = 0
+		OR (
+			SELECT COUNT(DISTINCT t.name)
+			FROM Product_Tag pt
+			JOIN Tag t ON pt.tag_id = t.tag_id
+			WHERE pt.product_id = p.product_id
+				AND pt.deleted_at IS NULL
+				AND t.name = ANY($%[1]d)
+		) >= CASE WHEN $%[2]d THEN cardinality($%[1]d::text[]) ELSE 1 END
+	)
+	AND ($%[3]d::text = '' OR p.name ILIKE $%[3]d)
+	AND ($%[4]d::timestamp IS NULL OR p.created_at >= $%[4]d)
+	AND ($%[5]d::timestamp IS NULL OR p.created_at <= $%[5]d)
+	AND ($%[6]d::timestamp IS NULL OR COALESCE(p.updated_at, p.created_at) >= $%[6]d)
+	AND ($%[7]d::timestamp IS NULL OR COALESCE(p.updated_at, p.created_at) <= $%[7]d)
+	AND ($%[8]d::float8 IS NULL OR COALESCE(p.price, 0) >= $%[8]d)
+	AND ($%[9]d::float8 IS NULL OR COALESCE(p.price, 0) <= $%[9]d)
+	AND (
+		COALESCE(cardinality($%[10]d::bytea[]), 0) = 0
+		OR EXISTS (
+			SELECT 1
+			FROM Product_Category pc
+			WHERE pc.product_id = p.product_id
+				AND pc.deleted_at IS NULL
+				AND pc.category_id = ANY($%[10]d)
+		)
+	)
+	AND (COALESCE(cardinality($%[11]d::bytea[]), 0) = 0 OR p.brand_id = ANY($%[11]d))
+	AND (
+		COALESCE(cardinality($%[12]d::bytea[]), 0) = 0
+		OR (
+			SELECT COUNT(DISTINCT pa.attribute_id)
+			FROM Product_Attribute pa
+			JOIN unnest($%[12]d::bytea[], $%[13]d::text[]) AS f(attribute_id, value)
+				ON f.attribute_id = pa.attribute_id AND f.value = pa.value
+			WHERE pa.product_id = p.product_id
+				AND pa.deleted_at IS NULL
+		) = (
+			SELECT COUNT(DISTINCT f.attribute_id)
+			FROM unnest($%[12]d::bytea[]) AS f(attribute_id)
+		)
+	)`
+
+// variantFilter is the predicate of VariantFilter, %[n]d is the position
+// of the n-th parameter of listFilterArgs.
+const variantFilter = `(
+		COALESCE(cardinality($%[1]d::text[]), 0) = 0
+		OR (
+			SELECT COUNT(DISTINCT t.name)
+			FROM Variant_Tag vt
+			JOIN Tag t ON vt.tag_id = t.tag_id
+			WHERE vt.variant_id = v.variant_id
+				AND vt.deleted_at IS NULL
+				AND t.name = ANY($%[1]d)
+		) >= CASE WHEN $%[2]d THEN cardinality($%[1]d::text[]) ELSE 1 END
+	)
+	AND ($%[3]d::text = '' OR v.name ILIKE $%[3]d)
+	AND ($%[4]d::timestamp IS NULL OR v.created_at >= $%[4]d)
+	AND ($%[5]d::timestamp IS NULL OR v.created_at <= $%[5]d)
+	AND ($%[6]d::timestamp IS NULL OR COALESCE(v.updated_at, v.created_at) >= $%[6]d)
+	AND ($%[7]d::timestamp IS NULL OR COALESCE(v.updated_at, v.created_at) <= $%[7]d)
+	AND ($%[8]d::float8 IS NULL OR COALESCE(v.price, 0) >= $%[8]d)
+	AND ($%[9]d::float8 IS NULL OR COALESCE(v.price, 0) <= $%[9]d)
+	AND (
+		COALESCE(cardinality($%[10]d::bytea[]), 0) = 0
+		OR EXISTS (
+			SELECT 1
+			FROM Product_Category pc
+			WHERE pc.product_id = p.product_id
+				AND pc.deleted_at IS NULL
+				AND pc.category_id = ANY($%[10]d)
+		)
+	)
+	AND (COALESCE(cardinality($%[11]d::bytea[]), 0) = 0 OR p.brand_id = ANY($%[11]d))
+	AND (
+		COALESCE(cardinality($%[12]d::bytea[]), 0) = 0
+		OR (
+			SELECT COUNT(DISTINCT a.attribute_id)
+			FROM (
+				SELECT va.attribute_id, va.value
+				FROM Variant_Attribute va
+				WHERE va.variant_id = v.variant_id
+					AND va.deleted_at IS NULL
+				UNION ALL
+				SELECT pa.attribute_id, pa.value
+				FROM Product_Attribute pa
+				WHERE pa.product_id = p.product_id
+					AND pa.deleted_at IS NULL
+					AND NOT EXISTS (
+						SELECT 1
+						FROM Variant_Attribute va
+						WHERE va.variant_id = v.variant_id
+							AND va.attribute_id = pa.attribute_id
+							AND va.deleted_at IS NULL
+					)
+			) AS a
+			JOIN unnest($%[12]d::bytea[], $%[13]d::text[]) AS f(attribute_id, value)
+				ON f.attribute_id = a.attribute_id AND f.value = a.value
+		) = (
+			SELECT COUNT(DISTINCT f.attribute_id)
+			FROM unnest($%[12]d::bytea[]) AS f(attribute_id)
+		)
+	)`
+
+// ProductFilter return the predicate of the products, aliased p, passing
+// filter. Its parameters are appended to args.
+func ProductFilter(filter domain.ListFilter, args []any) (string, []any) {
+	return listFilter(productFilter, filter, args)
+}
+
+// VariantFilter return the predicate of the variants, aliased v, passing
+// filter. Category, brand and the attribute a variant does not set come
+// from the main product, aliased p. Its parameters are appended to args.
+func VariantFilter(filter domain.ListFilter, args []any) (string, []any) {
+	return listFilter(variantFilter, filter, args)
+}
+
+func listFilter(predicate string, filter domain.ListFilter, args []any) (string, []any) {
+	filterArgs := listFilterArgs(filter)
+	positions := make([]any, len(filterArgs))
+	for i := range filterArgs {
+		positions[i] = len(args) + i + 1
+	}
+	return fmt.Sprintf(predicate, positions...), append(args, filterArgs...)
+}
+
+// listFilterArgs return the parameters of the filter predicates, in the
+// order of their position.
+func listFilterArgs(filter domain.ListFilter) []any {
+	attrIds, attrValues := filter.AttributePairs()
+	return []any{
+		filter.Tags.Tags,
+		filter.Tags.MatchAll,
+		filter.NamePattern(),
+		filter.CreatedFrom,
+		filter.CreatedTo,
+		filter.UpdatedFrom,
+		filter.UpdatedTo,
+		filter.MinPrice,
+		filter.MaxPrice,
+		filter.CategoryKeys(),
+		filter.BrandKeys(),
+		attrIds,
+		attrValues,
+	}
+}
@@ -0,0 +1,39 @@
+package helper_test
+
+import (
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestListFilterPositions(t *testing.T) {
+	placeholder := regexp.MustCompile(`\$(\d+)`)
+
+	tests := []struct {
+		name   string
+		filter func(domain.ListFilter, []any) (string, []any)
+	}{
+		{name: "product", filter: helper.ProductFilter},
+		{name: "variant", filter: helper.VariantFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, before := range []int{0, 3} {
+				where, args := tt.filter(domain.ListFilter{}, make([]any, before))
+				seen := make(map[int]bool)
+				for _, m := range placeholder.FindAllStringSubmatch(where, -1) {
+					n, _ := strconv.Atoi(m[1])
+					if n <= before || n > len(args) {
+						t.Errorf("after %d args: $%d outside %d..%d", before, n, before+1, len(args))
+					}
+					seen[n] = true
+				}
+				if len(seen) != len(args)-before {
+					t.Errorf("after %d args: %d parameters used, want %d", before, len(seen), len(args)-before)
+				}
+			}
+		})
+	}
+}
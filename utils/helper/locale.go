@@ -0,0 +1,67 @@
+package helper
+
+import (
+	"flukis/product/domain"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseLocales return the locales requested by client, most preferred
+// first. "?locale=" win over "Accept-Language", region tag like "en-us" is
+// followed by its language "en" as fallback.
+func ParseLocales(req *http.Request) []string {
+	var tags []string
+	if locale := req.URL.Query().Get("locale"); locale != "" {
+		tags = append(tags, locale)
+	}
+
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(req.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		accepted = append(accepted, weighted{tag: tag, q: q})
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].q > accepted[j].q
+	})
+	for _, a := range accepted {
+		tags = append(tags, a.tag)
+	}
+
+	var locales []string
+	seen := make(map[string]bool)
+	add := func(locale string) {
+		if locale == "" || seen[locale] {
+			return
+		}
+		seen[locale] = true
+		locales = append(locales, locale)
+	}
+	for _, tag := range tags {
+		tag = domain.NormalizeLocale(tag)
+		add(tag)
+		if base, _, ok := strings.Cut(tag, "-"); ok {
+			add(base)
+		}
+	}
+	return locales
+}
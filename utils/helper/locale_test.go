@@ -0,0 +1,39 @@
+package helper_test
+
+import (
+	"flukis/product/utils/helper"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseLocales(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		acceptLanguage string
+		want           []string
+	}{
+		{name: "none", want: nil},
+		{name: "query only", query: "?locale=fr", want: []string{"fr"}},
+		{name: "region followed by its language", query: "?locale=en-US", want: []string{"en-us", "en"}},
+		{name: "underscore normalized", query: "?locale=%20pt_BR%20", want: []string{"pt-br", "pt"}},
+		{name: "header by weight", acceptLanguage: "de;q=0.5, fr-CA, en;q=0.8", want: []string{"fr-ca", "fr", "en", "de"}},
+		{name: "query win over header", query: "?locale=id", acceptLanguage: "en", want: []string{"id", "en"}},
+		{name: "wildcard and zero weight skipped", acceptLanguage: "*, nl;q=0, es", want: []string{"es"}},
+		{name: "malformed weight skipped", acceptLanguage: "it;q=high, ja", want: []string{"ja"}},
+		{name: "duplicates kept once", query: "?locale=en", acceptLanguage: "en-GB, EN", want: []string{"en", "en-gb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/product"+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			got := helper.ParseLocales(req)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("locales = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,30 +1,129 @@
 package helper
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
-	"time"
+	"encoding/json"
+	"flukis/product/domain"
+	"fmt"
+	"slices"
+	"strings"
 )
 
-const (
-	timeFormat = "2006-01-02T15:04:05.999999Z07:00" // Use more precise format for encoding and decoding
-)
+// Cursors sign listing cursors with an HMAC key so client can not forge
+// them, and tie each cursor to the sort and filters it was issued for.
+type Cursors struct {
+	key []byte
+}
+
+func NewCursors(key []byte) *Cursors {
+	return &Cursors{
+		key: key,
+	}
+}
 
-// DecodeCursor will decode cursor from user for mysql
-func DecodeCursor(encodedTime string) (time.Time, error) {
-	byt, err := base64.StdEncoding.DecodeString(encodedTime)
+// Encode sign the cursor for scope, the filters of the listing. The
+// cursor is "<payload>.<signature>", both base64 url encoded.
+func (c *Cursors) Encode(lc domain.ListCursor, scope string) string {
+	lc.Scope = scopeDigest(scope)
+	payload, err := json.Marshal(lc)
 	if err != nil {
-		return time.Time{}, err
+		return ""
 	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
 
-	timeString := string(byt)
-	t, err := time.Parse(timeFormat, timeString)
+// Decode check the signature of encoded and that it was issued for sort
+// and scope. Empty cursor give the zero cursor.
+func (c *Cursors) Decode(encoded, sort, scope string) (domain.ListCursor, error) {
+	if encoded == "" {
+		return domain.ListCursor{}, nil
+	}
+	rawPayload, rawSignature, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return domain.ListCursor{}, fmt.Errorf("%w: cursor is malformed", domain.ErrInvalidCursor)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(rawPayload)
+	if err != nil {
+		return domain.ListCursor{}, fmt.Errorf("%w: %v", domain.ErrInvalidCursor, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(rawSignature)
+	if err != nil {
+		return domain.ListCursor{}, fmt.Errorf("%w: %v", domain.ErrInvalidCursor, err)
+	}
+	if !hmac.Equal(signature, c.sign(payload)) {
+		return domain.ListCursor{}, fmt.Errorf("%w: signature does not match", domain.ErrInvalidCursor)
+	}
+	var lc domain.ListCursor
+	if err := json.Unmarshal(payload, &lc); err != nil {
+		return domain.ListCursor{}, fmt.Errorf("%w: %v", domain.ErrInvalidCursor, err)
+	}
+	if lc.Sort != sort {
+		return domain.ListCursor{}, fmt.Errorf("%w: cursor was made for sort %s", domain.ErrInvalidCursor, lc.Sort)
+	}
+	if lc.Scope != scopeDigest(scope) {
+		return domain.ListCursor{}, fmt.Errorf("%w: cursor was made for other filters", domain.ErrInvalidCursor)
+	}
+	return lc, nil
+}
+
+// DecodeSort decode a cursor of a listing sorted by sort and return its
+// sort value typed for the query, nil when encoded is empty.
+func (c *Cursors) DecodeSort(encoded string, sort domain.ListSort, scope string) (domain.ListCursor, any, error) {
+	lc, err := c.Decode(encoded, sort.String(), scope)
+	if err != nil || encoded == "" {
+		return lc, nil, err
+	}
+	value, err := lc.Typed(sort)
+	if err != nil {
+		return domain.ListCursor{}, nil, err
+	}
+	return lc, value, nil
+}
 
-	return t, err
+func (c *Cursors) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
 }
 
-// EncodeCursor will encode cursor from mysql to user
-func EncodeCursor(t time.Time) string {
-	timeString := t.Format(timeFormat)
+// scopeDigest shorten scope, the cursor only need to tell it apart.
+func scopeDigest(scope string) string {
+	if scope == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(scope))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
 
-	return base64.StdEncoding.EncodeToString([]byte(timeString))
+// CursorPage trim the limit+1 items read for a page from cursor from, put
+// them in listing order and make the page cursors for scope. cursorOf
+// return the cursor after an item.
+func CursorPage[T any](cursors *Cursors, scope string, items []T, limit int, from domain.ListCursor, cursorOf func(T) domain.ListCursor) ([]T, domain.Page) {
+	extra := limit >= 0 && len(items) > limit
+	if extra {
+		items = items[:limit]
+	}
+	var page domain.Page
+	if from.Before {
+		// the cursor item follow a backward page, unless nothing precede
+		// it anymore and there is no item to point the next cursor at
+		slices.Reverse(items)
+		page.HasMore = len(items) > 0
+	} else {
+		page.HasMore = extra
+	}
+	if len(items) == 0 {
+		return items, page
+	}
+	if page.HasMore {
+		page.NextCursor = cursors.Encode(cursorOf(items[len(items)-1]), scope)
+	}
+	if (from.Before && extra) || (!from.Before && from.Sort != "") {
+		prev := cursorOf(items[0])
+		prev.Before = true
+		page.PrevCursor = cursors.Encode(prev, scope)
+	}
+	return items, page
 }
@@ -0,0 +1,149 @@
+package helper_test
+
+import (
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/helper"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestCursorsEncodeDecode(t *testing.T) {
+	cursors := helper.NewCursors([]byte("secret"))
+	lc := domain.ListCursor{
+		Sort:  "price:asc",
+		Value: "9.5",
+		ID:    ulid.MustParse("01J0000000000000000000000A"),
+	}
+	encoded := cursors.Encode(lc, "tag=red")
+	listed := cursors.Encode(lc, "category")
+
+	tampered := []byte(encoded)
+	tampered[2] ^= 1
+
+	tests := []struct {
+		name     string
+		cursors  *helper.Cursors
+		encoded  string
+		sort     string
+		scope    string
+		wantErr  bool
+		wantZero bool
+	}{
+		{name: "round trip", cursors: cursors, encoded: encoded, sort: "price:asc", scope: "tag=red"},
+		{name: "empty is the zero cursor", cursors: cursors, encoded: "", sort: "price:asc", wantZero: true},
+		{name: "other sort", cursors: cursors, encoded: encoded, sort: "price:desc", scope: "tag=red", wantErr: true},
+		{name: "other filters", cursors: cursors, encoded: encoded, sort: "price:asc", scope: "tag=blue", wantErr: true},
+		{name: "other listing", cursors: cursors, encoded: listed, sort: "price:asc", scope: "brand", wantErr: true},
+		{name: "unscoped listing", cursors: cursors, encoded: listed, sort: "price:asc", scope: "", wantErr: true},
+		{name: "other key", cursors: helper.NewCursors([]byte("other")), encoded: encoded, sort: "price:asc", scope: "tag=red", wantErr: true},
+		{name: "payload modified", cursors: cursors, encoded: string(tampered), sort: "price:asc", scope: "tag=red", wantErr: true},
+		{name: "signature missing", cursors: cursors, encoded: strings.Split(encoded, ".")[0], sort: "price:asc", scope: "tag=red", wantErr: true},
+		{name: "not base64", cursors: cursors, encoded: "!!.!!", sort: "price:asc", scope: "tag=red", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cursors.Decode(tt.encoded, tt.sort, tt.scope)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidCursor) {
+					t.Fatalf("err = %v, want %v", err, domain.ErrInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if tt.wantZero {
+				if got != (domain.ListCursor{}) {
+					t.Errorf("cursor = %+v, want zero", got)
+				}
+				return
+			}
+			if got.Sort != lc.Sort || got.Value != lc.Value || got.ID != lc.ID || got.Before {
+				t.Errorf("cursor = %+v, want %+v", got, lc)
+			}
+		})
+	}
+}
+
+func TestCursorPage(t *testing.T) {
+	cursors := helper.NewCursors([]byte("secret"))
+	const sort = "created:asc"
+	cursorOf := func(n int) domain.ListCursor {
+		return domain.ListCursor{Sort: sort, Value: strconv.Itoa(n)}
+	}
+	// decode return the item number a cursor point at and its direction
+	decode := func(t *testing.T, encoded string) (int, bool) {
+		t.Helper()
+		lc, err := cursors.Decode(encoded, sort, "")
+		if err != nil {
+			t.Fatalf("decode %q: %v", encoded, err)
+		}
+		n, err := strconv.Atoi(lc.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n, lc.Before
+	}
+	from := cursorOf(0)
+	before := cursorOf(10)
+	before.Before = true
+
+	tests := []struct {
+		name      string
+		items     []int
+		from      domain.ListCursor
+		want      []int
+		wantMore  bool
+		wantNext  int
+		wantPrev  int
+		wantNoPrv bool
+	}{
+		{name: "first page with more", items: []int{1, 2, 3}, want: []int{1, 2}, wantMore: true, wantNext: 2, wantNoPrv: true},
+		{name: "first and last page", items: []int{1, 2}, want: []int{1, 2}, wantNoPrv: true},
+		{name: "empty listing", items: nil, want: nil, wantNoPrv: true},
+		{name: "next page with more", items: []int{3, 4, 5}, from: from, want: []int{3, 4}, wantMore: true, wantNext: 4, wantPrev: 3},
+		{name: "last page", items: []int{5}, from: from, want: []int{5}, wantPrev: 5},
+		// a page before the cursor is read in reverse
+		{name: "previous page not first", items: []int{9, 8, 7}, from: before, want: []int{8, 9}, wantMore: true, wantNext: 9, wantPrev: 8},
+		{name: "previous page is the first", items: []int{2, 1}, from: before, want: []int{1, 2}, wantMore: true, wantNext: 2, wantNoPrv: true},
+		{name: "previous page empty", items: nil, from: before, want: nil, wantNoPrv: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := append([]int(nil), tt.items...)
+			got, page := helper.CursorPage(cursors, "", items, 2, tt.from, cursorOf)
+			if len(got) != len(tt.want) {
+				t.Fatalf("items = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("items = %v, want %v", got, tt.want)
+				}
+			}
+			if page.HasMore != tt.wantMore {
+				t.Errorf("has more = %v, want %v", page.HasMore, tt.wantMore)
+			}
+			if page.HasMore != (page.NextCursor != "") {
+				t.Errorf("has more = %v with next cursor %q", page.HasMore, page.NextCursor)
+			}
+			if tt.wantMore {
+				if n, back := decode(t, page.NextCursor); n != tt.wantNext || back {
+					t.Errorf("next cursor point at %d (before %v), want after %d", n, back, tt.wantNext)
+				}
+			}
+			if tt.wantNoPrv {
+				if page.PrevCursor != "" {
+					t.Errorf("prev cursor = %q, want empty", page.PrevCursor)
+				}
+				return
+			}
+			if n, back := decode(t, page.PrevCursor); n != tt.wantPrev || !back {
+				t.Errorf("prev cursor point at %d (before %v), want before %d", n, back, tt.wantPrev)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package helper
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// PerceptualHash return the 64 bit difference hash of img. The image is
+// shrunk to 9x8 gray pixels and each bit tell whether a pixel is darker
+// than its right neighbour, so resized or recompressed copies of the same
+// photo get hashes a few bits apart.
+func PerceptualHash(img image.Image) uint64 {
+	gray := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.CatmullRom.Scale(gray, gray.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray.GrayAt(x, y).Y < gray.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
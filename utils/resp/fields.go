@@ -0,0 +1,142 @@
+package resp
+
+import (
+	"flukis/product/domain"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SelectFields keep only the json fields named in fields of data, a struct
+// or a list of structs. Empty fields return data untouched, a name the
+// struct does not have is an ErrInvalidFields. The kept fields are
+// returned as maps of their value, encoded once with the response.
+func SelectFields(data any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+	value := reflect.ValueOf(data)
+	list := value.Kind() == reflect.Slice || value.Kind() == reflect.Array
+	itemType := value.Type()
+	if list {
+		itemType = itemType.Elem()
+	}
+	if itemType.Kind() == reflect.Pointer {
+		itemType = itemType.Elem()
+	}
+	if itemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot select fields of %s", value.Type())
+	}
+
+	known := jsonFieldsOf(itemType)
+	keep := make([]jsonField, 0, len(fields))
+	for _, name := range fields {
+		field, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", domain.ErrInvalidFields, name)
+		}
+		keep = append(keep, field)
+	}
+
+	if !list {
+		return pick(value, keep), nil
+	}
+	if value.Kind() == reflect.Slice && value.IsNil() {
+		return data, nil
+	}
+	items := make([]map[string]any, value.Len())
+	for i := range items {
+		items[i] = pick(value.Index(i), keep)
+	}
+	return items, nil
+}
+
+// jsonField is a struct field as encoding/json see it.
+type jsonField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// jsonFields cache the fields of each struct type, by name.
+var jsonFields sync.Map
+
+func jsonFieldsOf(t reflect.Type) map[string]jsonField {
+	if fields, ok := jsonFields.Load(t); ok {
+		return fields.(map[string]jsonField)
+	}
+	fields := make(map[string]jsonField)
+	collectFields(t, nil, fields)
+	jsonFields.Store(t, fields)
+	return fields
+}
+
+// collectFields add the exported fields of t, the fields of an untagged
+// embedded struct are promoted like encoding/json does. An outer field
+// win over a promoted one of the same name.
+func collectFields(t reflect.Type, index []int, fields map[string]jsonField) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, f)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := fields[name]; ok {
+			continue
+		}
+		fields[name] = jsonField{
+			name:      name,
+			index:     append(append([]int{}, index...), i),
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		}
+	}
+	for _, f := range embedded {
+		collectFields(f.Type, append(append([]int{}, index...), f.Index...), fields)
+	}
+}
+
+func pick(item reflect.Value, keep []jsonField) map[string]any {
+	for item.Kind() == reflect.Pointer {
+		if item.IsNil() {
+			return nil
+		}
+		item = item.Elem()
+	}
+	res := make(map[string]any, len(keep))
+	for _, field := range keep {
+		value := item.FieldByIndex(field.index)
+		if field.omitEmpty && isEmptyValue(value) {
+			continue
+		}
+		res[field.name] = value.Interface()
+	}
+	return res
+}
+
+// isEmptyValue report the values omitempty leave out.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
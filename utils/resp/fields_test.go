@@ -0,0 +1,64 @@
+package resp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"flukis/product/domain"
+	"flukis/product/utils/resp"
+	"testing"
+)
+
+type baseDTO struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type detailDTO struct {
+	baseDTO
+	Tags     []string `json:"tags,omitempty"`
+	Secret   string   `json:"-"`
+	Untagged string
+}
+
+func TestSelectFields(t *testing.T) {
+	item := detailDTO{baseDTO: baseDTO{ID: 1, Name: "shoe"}, Tags: []string{"red"}, Secret: "x", Untagged: "u"}
+	bare := detailDTO{baseDTO: baseDTO{ID: 2, Name: "hat"}}
+
+	tests := []struct {
+		name    string
+		data    any
+		fields  []string
+		want    string
+		wantErr error
+	}{
+		{name: "no fields keep everything", data: item, want: `{"id":1,"name":"shoe","tags":["red"],"Untagged":"u"}`},
+		{name: "embedded field", data: item, fields: []string{"name"}, want: `{"name":"shoe"}`},
+		{name: "several fields", data: item, fields: []string{"tags", "id"}, want: `{"id":1,"tags":["red"]}`},
+		{name: "untagged field", data: item, fields: []string{"Untagged"}, want: `{"Untagged":"u"}`},
+		{name: "omitempty still apply", data: bare, fields: []string{"id", "tags"}, want: `{"id":2}`},
+		{name: "pointer", data: &item, fields: []string{"id"}, want: `{"id":1}`},
+		{name: "list", data: []detailDTO{item, bare}, fields: []string{"name"}, want: `[{"name":"shoe"},{"name":"hat"}]`},
+		{name: "nil list", data: []detailDTO(nil), fields: []string{"name"}, want: `null`},
+		{name: "unknown field", data: item, fields: []string{"price"}, wantErr: domain.ErrInvalidFields},
+		{name: "ignored field", data: item, fields: []string{"Secret"}, wantErr: domain.ErrInvalidFields},
+		{name: "unknown field of empty list", data: []detailDTO{}, fields: []string{"price"}, wantErr: domain.ErrInvalidFields},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resp.SelectFields(tt.data, tt.fields)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			raw, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(raw) != tt.want {
+				t.Errorf("SelectFields = %s, want %s", raw, tt.want)
+			}
+		})
+	}
+}
@@ -36,3 +36,17 @@ func writeMessage(w http.ResponseWriter, status int, msg string) error {
 func WriteError(w http.ResponseWriter, status int, err error) error {
 	return writeMessage(w, status, err.Error())
 }
+
+// WriteErrorCode write the error together with a machine readable code.
+func WriteErrorCode(w http.ResponseWriter, status int, code string, err error) error {
+	var j struct {
+		Msg  string `json:"msg"`
+		Code string `json:"code"`
+	}
+
+	j.Msg = err.Error()
+	j.Code = code
+	w.Header().Add("Content-type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(j)
+}
@@ -0,0 +1,262 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flukis/product/utils/storage"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "minioadmin"
+	testSecretKey = "minioadmin-secret"
+	testRegion    = "eu-west-1"
+	testBucket    = "products"
+)
+
+// s3StandIn is an in memory S3 that check the signature of every request
+// the way S3 does before serving it.
+type s3StandIn struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newS3StandIn(t *testing.T) (*s3StandIn, *storage.S3) {
+	t.Helper()
+	standIn := &s3StandIn{
+		t:       t,
+		objects: make(map[string][]byte),
+		types:   make(map[string]string),
+	}
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+
+	client, err := storage.NewS3(srv.URL, testRegion, testBucket, testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	return standIn, client
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkSignature(req, body); err != nil {
+		s.t.Errorf("%s %s: %v", req.Method, req.URL.Path, err)
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(req.URL.Path, "/"+testBucket+"/")
+	if !ok {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch req.Method {
+	case http.MethodPut:
+		s.objects[key] = body
+		s.types[key] = req.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", s.types[key])
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		delete(s.types, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// checkSignature verify the x-amz headers and the signature version 4
+// Authorization header of req.
+func checkSignature(req *http.Request, body []byte) error {
+	amzDate := req.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return fmt.Errorf("x-amz-date %q: %v", amzDate, err)
+	}
+	if time.Since(signedAt).Abs() > 15*time.Minute {
+		return fmt.Errorf("x-amz-date %q is too far from now", amzDate)
+	}
+	payloadHash := hexSHA256(body)
+	if got := req.Header.Get("X-Amz-Content-Sha256"); got != payloadHash {
+		return fmt.Errorf("x-amz-content-sha256 is %q, want %q", got, payloadHash)
+	}
+
+	auth, ok := strings.CutPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return fmt.Errorf("authorization %q is not AWS4-HMAC-SHA256", req.Header.Get("Authorization"))
+	}
+	fields := make(map[string]string)
+	for _, part := range strings.Split(auth, ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		fields[name] = value
+	}
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	if want := testAccessKey + "/" + scope; fields["Credential"] != want {
+		return fmt.Errorf("credential is %q, want %q", fields["Credential"], want)
+	}
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !strings.Contains(";"+fields["SignedHeaders"]+";", ";"+required+";") {
+			return fmt.Errorf("signed headers %q miss %s", fields["SignedHeaders"], required)
+		}
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSum([]byte("AWS4"+testSecretKey), amzDate[:8])
+	key = hmacSum(key, testRegion)
+	key = hmacSum(key, "s3")
+	key = hmacSum(key, "aws4_request")
+	if want := hex.EncodeToString(hmacSum(key, stringToSign)); fields["Signature"] != want {
+		return fmt.Errorf("signature is %q, want %q", fields["Signature"], want)
+	}
+	return nil
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSum(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func TestS3RoundTrip(t *testing.T) {
+	standIn, client := newS3StandIn(t)
+	ctx := context.Background()
+	key := "images/ab/abcdef 01.jpg"
+	data := []byte("\xff\xd8\xff\xe0 not really a jpeg")
+
+	if err := client.Put(ctx, key, data, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := standIn.types[key]; got != "image/jpeg" {
+		t.Errorf("stored content type is %q, want image/jpeg", got)
+	}
+
+	got, err := storage.ReadAll(ctx, client, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("Get return %q, want %q", got, data)
+	}
+
+	if err := client.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := client.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get after Delete return %v, want ErrNotFound", err)
+	}
+	if err := client.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of missing key return %v, want nil", err)
+	}
+}
+
+func TestS3GetMissing(t *testing.T) {
+	_, client := newS3StandIn(t)
+	_, err := client.Get(context.Background(), "images/missing")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("Get return %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3InvalidKey(t *testing.T) {
+	_, client := newS3StandIn(t)
+	ctx := context.Background()
+	for _, key := range []string{"", "/images/a", "images/../../etc", "images/./a"} {
+		if err := client.Put(ctx, key, []byte("x"), "text/plain"); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Put(%q) return %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestS3Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	client, err := storage.NewS3(srv.URL, testRegion, testBucket, testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	err = client.Put(context.Background(), "images/a", []byte("x"), "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Put return %v, want the AccessDenied response", err)
+	}
+	if _, err := client.Get(context.Background(), "images/a"); err == nil || errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get return %v, want a non not found error", err)
+	}
+}
+
+func TestNewS3(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		bucket   string
+		wantErr  bool
+	}{
+		{name: "valid", endpoint: "http://localhost:9000", bucket: "products"},
+		{name: "relative endpoint", endpoint: "localhost:9000", bucket: "products", wantErr: true},
+		{name: "empty endpoint", endpoint: "", bucket: "products", wantErr: true},
+		{name: "empty bucket", endpoint: "http://localhost:9000", bucket: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := storage.NewS3(tt.endpoint, "", tt.bucket, testAccessKey, testSecretKey)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewS3 error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}